@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// marshalConfig serializes the config for writing to path. go-toml can't round-trip comments, so only the comment
+// block at the top of the existing file is preserved.
+func (m *model) marshalConfig(path string) ([]byte, error) {
+	data, err := toml.Marshal(m.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	header := leadingComments(existing)
+	if len(header) == 0 {
+		return data, nil
+	}
+	return append(append(header, '\n'), data...), nil
+}
+
+// leadingComments returns the comment lines at the top of a TOML document, up to the first line that isn't a
+// comment. Blank lines between comments are kept, but leading and trailing blank lines are dropped.
+func leadingComments(data []byte) []byte {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		if trimmed == "" && len(lines) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
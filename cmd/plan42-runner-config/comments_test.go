@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	runner_config "github.com/plan42-ai/cli/internal/cli/runnerconfig"
+	"github.com/stretchr/testify/require"
+)
+
+const commentedConfig = `
+# Plan42 runner config for my laptop.
+#
+# Managed by hand, edit with care.
+
+# the runner section
+[runner]
+url = 'https://api.plan42.ai'
+token = 'p42r_old'
+`
+
+func TestSavePreservesLeadingComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan42-runner.toml")
+	require.NoError(t, os.WriteFile(path, []byte(commentedConfig), 0o600))
+
+	m := initialModel(&runner_config.Options{ConfigFile: path}).(*model)
+	require.Equal(t, "p42r_old", m.cfg.Runner.RunnerToken)
+
+	m.cfg.Runner.RunnerToken = "p42r_new"
+	msg := m.save()
+	require.Equal(t, saveSuccessMsg{changed: true}, msg)
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(saved), "# Plan42 runner config for my laptop.\n#\n# Managed by hand, edit with care.\n")
+	require.Contains(t, string(saved), "# the runner section\n")
+	require.Contains(t, string(saved), "p42r_new")
+	require.NotContains(t, string(saved), "p42r_old")
+
+	// Saving again without changes is a no-op, so there's nothing to confirm.
+	m = initialModel(&runner_config.Options{ConfigFile: path}).(*model)
+	data, err := m.marshalConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, saved, data)
+	require.Equal(t, saveSuccessMsg{changed: false}, m.save())
+}
+
+func TestLeadingComments(t *testing.T) {
+	require.Nil(t, leadingComments(nil))
+	require.Nil(t, leadingComments([]byte("[runner]\n# not a header\n")))
+	require.Equal(t, "# a\n\n# b\n", string(leadingComments([]byte("\n# a\n\n# b\n\n[runner]\n"))))
+	require.Equal(t, "# only comments\n", string(leadingComments([]byte("# only comments"))))
+}
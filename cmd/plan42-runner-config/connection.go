@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/github"
+)
+
+const connectionTestTimeout = 10 * time.Second
+
+// connectionTestResultMsg reports the outcome of testing a github connection's token.
+type connectionTestResultMsg struct {
+	connectionID string
+	status       string
+	ok           bool
+}
+
+// testGithubConnection returns a command that resolves the current user with the given token and url.
+func testGithubConnection(connectionID string, serverURL string, token string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTestTimeout)
+		defer cancel()
+
+		client, err := github.NewClient(strings.TrimSpace(token), strings.TrimSpace(serverURL))
+		var user *ghapi.User
+		if err == nil {
+			user, _, err = client.GetCurrentUser(ctx)
+		}
+		status, ok := connectionTestStatus(user, err)
+		return connectionTestResultMsg{connectionID: connectionID, status: status, ok: ok}
+	}
+}
+
+// connectionTestStatus maps the result of GetCurrentUser to the status shown for a github connection.
+func connectionTestStatus(user *ghapi.User, err error) (string, bool) {
+	if err != nil {
+		var ghErr *ghapi.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil {
+			return fmt.Sprintf("connection failed: %d %s", ghErr.Response.StatusCode, ghErr.Message), false
+		}
+		return fmt.Sprintf("connection failed: %v", err), false
+	}
+	if user.GetLogin() == "" {
+		return "connection failed: no user returned", false
+	}
+	return "authenticated as " + user.GetLogin(), true
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionTestStatus(t *testing.T) {
+	status, ok := connectionTestStatus(&ghapi.User{Login: util.Pointer("octocat")}, nil)
+	require.True(t, ok)
+	require.Equal(t, "authenticated as octocat", status)
+
+	status, ok = connectionTestStatus(&ghapi.User{}, nil)
+	require.False(t, ok)
+	require.Equal(t, "connection failed: no user returned", status)
+
+	status, ok = connectionTestStatus(nil, &ghapi.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusUnauthorized},
+		Message:  "Bad credentials",
+	})
+	require.False(t, ok)
+	require.Equal(t, "connection failed: 401 Bad credentials", status)
+
+	status, ok = connectionTestStatus(nil, errors.New("missing github token"))
+	require.False(t, ok)
+	require.Equal(t, "connection failed: missing github token", status)
+}
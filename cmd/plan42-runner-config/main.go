@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -15,14 +14,15 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/google/renameio/v2"
 	"github.com/pelletier/go-toml/v2"
+	"github.com/plan42-ai/cli/internal/cli/runner"
 	runner_config "github.com/plan42-ai/cli/internal/cli/runnerconfig"
 	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/runnertoken"
 	"github.com/plan42-ai/cli/internal/tui"
 	"github.com/plan42-ai/cli/internal/tui/runtimeselector"
 	"github.com/plan42-ai/cli/internal/util"
-	"github.com/plan42-ai/openid/jwt"
+	"github.com/plan42-ai/cli/internal/version"
 	"github.com/plan42-ai/sdk-go/p42"
 )
 
@@ -36,6 +36,8 @@ const (
 	serverURLLabel          = "Server URL"
 	saveButton              = "[OK]"
 	cancelButton            = "[Cancel]"
+	confirmYesButton        = "[Yes]"
+	confirmNoButton         = "[No]"
 	validatingTokenSection  = "Validating Token"
 	connectionsSection      = "[github connections]"
 	maxConnectionFieldIndex = 1
@@ -43,6 +45,7 @@ const (
 	runnerTokenFieldIndex   = 0
 	runnerURLFieldIndex     = 1
 	runnerRuntimeFieldIndex = 2
+	minInputWidth           = 10
 )
 
 var commentStyle = lipgloss.NewStyle().
@@ -141,6 +144,7 @@ type model struct {
 	validateErr          error
 	saveErr              error
 	options              *runner_config.Options
+	secretsRevealed      bool
 }
 
 func (m model) Init() tea.Cmd {
@@ -150,12 +154,27 @@ func (m model) Init() tea.Cmd {
 func (m *model) triggerSave(cmds []tea.Cmd) []tea.Cmd {
 	m.commitChanges()
 	m.saveErr = nil
+	if fileData, err := m.marshalConfig(m.options.ConfigFile); err == nil && shouldConfirmOverwrite(m.options.ConfigFile, fileData) {
+		m.blurSelectedInput()
+		m.selectedSection = confirmNoButton
+		return cmds
+	}
 	return append(cmds, m.save)
 }
 
+// shouldConfirmOverwrite reports whether writing data to path would overwrite an existing file with different
+// content. If the file can't be read, it returns false and leaves any error to the save itself.
+func shouldConfirmOverwrite(path string, data []byte) bool {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return !bytes.Equal(existing, data)
+}
+
 func (m *model) triggerValidate(cmds []tea.Cmd) []tea.Cmd {
 	m.runnerToken.Blur()
-	m.cfg.Runner.RunnerToken = m.runnerToken.Value()
+	m.cfg.Runner.RunnerToken = strings.TrimSpace(m.runnerToken.Value())
 	m.selectedSection = validatingTokenSection
 	m.validateErr = nil
 	return append(cmds, m.validateToken, m.spinner.Tick)
@@ -186,7 +205,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = m.onKey(msg, cmds)
 	case model:
 		m = msg
+		m.applySecretEchoMode()
 		cmds = append(cmds, m.focusSelectedInput())
+	case connectionTestResultMsg:
+		m.onConnectionTestResult(msg)
 	case saveSuccessMsg:
 		m.configSaved = msg.changed
 		return m, tea.Quit
@@ -226,16 +248,18 @@ func (m *model) onError(msg error, cmds []tea.Cmd) []tea.Cmd {
 
 func (m model) View() string {
 	b := strings.Builder{}
-	b.WriteString(commentStyle.Render("# Plan42 Runner Config"))
+	b.WriteString(commentStyle.Render("# Plan42 Runner Config (ctrl+r to show/hide tokens, ctrl+t to test a github connection)"))
 
 	b.WriteString(m.getSectionStyle(runnerSection, 0).Render(runnerSection))
 	b.WriteRune('\n')
 	b.WriteString(m.getFieldLabelStyle(runnerSection, 0, 0).Render(runnerTokenLabel))
 	b.WriteString(m.runnerToken.View())
 	b.WriteRune('\n')
+	writeFieldHint(&b, validateRunnerToken(m.runnerToken.Value()))
 	b.WriteString(m.getFieldLabelStyle(runnerSection, 0, 1).Render(serverURLLabel))
 	b.WriteString(m.severURL.View())
 	b.WriteRune('\n')
+	writeFieldHint(&b, validateServerURL(m.severURL.Value()))
 	b.WriteString(m.getFieldLabelStyle(runnerSection, 0, 2).Render(runnerRuntimeLabel))
 	b.WriteString(m.runtime.View())
 	b.WriteRune('\n')
@@ -268,24 +292,24 @@ func (m model) View() string {
 		b.WriteString(m.getFieldLabelStyle(connectionsSection, i, 0).Render("Server URL"))
 		b.WriteString(m.githubConnections[i].serverURL.View())
 		b.WriteRune('\n')
+		writeFieldHint(&b, validateServerURL(m.githubConnections[i].serverURL.Value()))
 		b.WriteString(m.getFieldLabelStyle(connectionsSection, i, 1).Render("Github Token"))
 		b.WriteString(m.githubConnections[i].githubToken.View())
 		b.WriteRune('\n')
+		m.githubConnections[i].writeTestStatus(&b)
 	}
 
 	b.WriteRune('\n')
-	if m.selectedSection == saveButton {
-		b.WriteString(selectedButtonStyle.Render(saveButton))
-	} else {
-		b.WriteString(buttonStyle.Render(saveButton))
-	}
+	b.WriteString(buttonRowButton(saveButton, m.selectedSection))
+	b.WriteString(buttonRowButton(cancelButton, m.selectedSection))
+	b.WriteRune('\n')
 
-	if m.selectedSection == cancelButton {
-		b.WriteString(selectedButtonStyle.Render(cancelButton))
-	} else {
-		b.WriteString(buttonStyle.Render(cancelButton))
+	if m.selectedSection == confirmYesButton || m.selectedSection == confirmNoButton {
+		_, _ = fmt.Fprintf(&b, "\nOverwrite existing config file %s?\n", m.options.ConfigFile)
+		b.WriteString(buttonRowButton(confirmYesButton, m.selectedSection))
+		b.WriteString(buttonRowButton(confirmNoButton, m.selectedSection))
+		b.WriteRune('\n')
 	}
-	b.WriteRune('\n')
 
 	if m.saveErr != nil {
 		b.WriteString(errorStyle.Render(fmt.Sprintf("\nERROR: %v", m.saveErr)))
@@ -294,62 +318,50 @@ func (m model) View() string {
 	return b.String()
 }
 
+func buttonRowButton(button string, selectedSection string) string {
+	if selectedSection == button {
+		return selectedButtonStyle.Render(button)
+	}
+	return buttonStyle.Render(button)
+}
+
+// writeFieldHint writes an inline hint for a field that failed client-side validation.
+func writeFieldHint(b *strings.Builder, err error) {
+	if err == nil {
+		return
+	}
+	b.WriteString(fieldLabelStyle.Render(""))
+	b.WriteString(errorStyle.Render(err.Error()))
+	b.WriteRune('\n')
+}
+
 func (m model) validateToken() tea.Msg {
 	oldCfg := m.cfg.Github
 	m.githubConnections = nil
 	m.cfg.Github = make(map[string]*config.GithubInfo)
 	m.selectedSection = saveButton
 
-	if m.cfg.Runner.RunnerToken == "" {
-		return errors.New("missing runner token")
-	}
-
-	if m.cfg.Runner.URL == "" {
-		return errors.New("missing server url")
-	}
-
 	configByID := indexByID(oldCfg)
 
-	split := strings.SplitN(m.cfg.Runner.RunnerToken, "_", 2)
-	if len(split) != 2 || split[0] != "p42r" {
-		return errors.New("invalid runner token")
-	}
-
-	token, err := jwt.Parse(split[1])
+	client, tenantID, err := runner.NewServerClient(m.cfg.Runner)
 	if err != nil {
 		return err
 	}
 
-	options := []p42.Option{
-		p42.WithAPIToken(m.cfg.Runner.RunnerToken),
-	}
-
 	parsedURL, err := url.Parse(m.cfg.Runner.URL)
 	if err != nil || parsedURL.Scheme != "https" || parsedURL.Host == "" {
 		return errors.New("invalid server url")
 	}
 
-	if m.cfg.Runner.SkipSSLVerify {
-		options = append(options, p42.WithInsecureSkipVerify())
-	}
-
-	client := p42.NewClient(m.cfg.Runner.URL, options...)
-
 	req := &p42.ListGithubConnectionsRequest{
-		TenantID: token.Payload.Subject,
+		TenantID: tenantID,
 		Private:  util.Pointer(true),
 	}
 
 	for {
 		resp, err := client.ListGithubConnections(context.Background(), req)
-		var ehErr *p42.Error
-		if errors.As(err, &ehErr) {
-			if ehErr.ResponseCode == http.StatusForbidden {
-				return errors.New("token not authorized")
-			}
-		}
 		if err != nil {
-			return fmt.Errorf("unable to connect to server: %w", err)
+			return runner.ServerError(err)
 		}
 		for _, conn := range resp.Items {
 			cfg, ui := processConnection(conn, configByID)
@@ -399,12 +411,12 @@ func processConnection(
 }
 
 func (m *model) save() tea.Msg {
-	fileData, err := toml.Marshal(m.cfg)
+	fileData, err := m.marshalConfig(m.options.ConfigFile)
 	if err != nil {
 		return fmt.Errorf("unable to serialize config file: %w", err)
 	}
 
-	err = renameio.WriteFile(m.options.ConfigFile, fileData, os.FileMode(0600))
+	err = writeConfigFile(m.options.ConfigFile, fileData)
 	if err != nil {
 		return fmt.Errorf("unable to save config file: %w", err)
 	}
@@ -483,19 +495,27 @@ func (m *model) commitChanges() {
 	field := m.getTargetField()
 
 	if input != nil && field != nil {
-		if m.isRunnerURLSelected() && *field != input.Value() {
+		value := strings.TrimSpace(input.Value())
+		if value != input.Value() {
+			input.SetValue(value)
+		}
+		if m.isRunnerURLSelected() && *field != value {
 			m.cfg.Runner.SkipSSLVerify = false
 		}
-		*field = input.Value()
+		*field = value
 	}
 }
 
+// resize sizes the inputs to fill the space to the right of the field labels. Inputs never shrink below
+// minInputWidth, so on terminals narrower than the labels they wrap rather than collapse.
 func (m *model) resize(width int) {
-	inputWidth := max(width-(fieldLabelStyle.GetWidth()+3), 10)
+	inputWidth := max(width-(fieldLabelStyle.GetWidth()+3), minInputWidth)
 	m.runnerToken.Width = inputWidth
 	m.severURL.Width = inputWidth
 
 	for _, conn := range m.githubConnections {
+		conn.name.Width = inputWidth
+		conn.id.Width = inputWidth
 		conn.serverURL.Width = inputWidth
 		conn.githubToken.Width = inputWidth
 	}
@@ -507,9 +527,16 @@ func (m *model) onKey(msg tea.KeyMsg, cmds []tea.Cmd) []tea.Cmd {
 		cmds = append(cmds, tea.Quit)
 	case "ctrl+z":
 		cmds = append(cmds, tea.Suspend)
+	case "ctrl+t":
+		if m.selectedSection == connectionsSection {
+			cmds = m.triggerConnectionTest(cmds)
+		}
+	case "ctrl+r":
+		m.secretsRevealed = !m.secretsRevealed
+		m.applySecretEchoMode()
 	case "ctrl+s":
 		switch m.selectedSection {
-		case validatingTokenSection:
+		case validatingTokenSection, confirmYesButton, confirmNoButton:
 			// do nothing
 		default:
 			cmds = m.triggerSave(cmds)
@@ -520,27 +547,45 @@ func (m *model) onKey(msg tea.KeyMsg, cmds []tea.Cmd) []tea.Cmd {
 			cmds = m.triggerSave(cmds)
 		case cancelButton:
 			cmds = append(cmds, tea.Quit)
+		case confirmYesButton:
+			cmds = append(cmds, m.save)
+		case confirmNoButton:
+			m.selectedSection = saveButton
 		}
 	case "left":
-		if m.selectedSection == cancelButton {
+		switch m.selectedSection {
+		case cancelButton:
 			m.selectedSection = saveButton
+		case confirmNoButton:
+			m.selectedSection = confirmYesButton
 		}
 	case "right":
-		if m.selectedSection == saveButton {
+		switch m.selectedSection {
+		case saveButton:
 			m.selectedSection = cancelButton
+		case confirmYesButton:
+			m.selectedSection = confirmNoButton
 		}
 	case "shift+tab":
-		if m.selectedSection == cancelButton {
+		switch m.selectedSection {
+		case cancelButton:
 			m.selectedSection = saveButton
-			break
+			return cmds
+		case confirmNoButton:
+			m.selectedSection = confirmYesButton
+			return cmds
 		}
 		fallthrough // treat shift+tab as up arrow when not on the button row
 	case "up":
 		cmds = m.onUp(cmds)
 	case "tab":
-		if m.selectedSection == saveButton {
+		switch m.selectedSection {
+		case saveButton:
 			m.selectedSection = cancelButton
-			break
+			return cmds
+		case confirmYesButton:
+			m.selectedSection = confirmNoButton
+			return cmds
 		}
 		fallthrough // treat tab as down arrow when not on the button row
 	case "down":
@@ -625,21 +670,64 @@ func (m *model) onUp(cmds []tea.Cmd) []tea.Cmd {
 	return cmds
 }
 
+// triggerConnectionTest tests the selected github connection's token against its server url.
+func (m *model) triggerConnectionTest(cmds []tea.Cmd) []tea.Cmd {
+	m.commitChanges()
+	conn := m.githubConnections[m.selectedSectionIndex]
+	conn.testStatus = "testing connection..."
+	conn.testOK = true
+	return append(cmds, testGithubConnection(conn.id.Value(), conn.serverURL.Value(), conn.githubToken.Value()))
+}
+
+func (m *model) onConnectionTestResult(msg connectionTestResultMsg) {
+	for _, conn := range m.githubConnections {
+		if conn.id.Value() == msg.connectionID {
+			conn.testStatus = msg.status
+			conn.testOK = msg.ok
+		}
+	}
+}
+
+// applySecretEchoMode masks or reveals the token fields. Echo mode only affects rendering, so the stored values are
+// unaffected.
+func (m *model) applySecretEchoMode() {
+	mode := textinput.EchoPassword
+	if m.secretsRevealed {
+		mode = textinput.EchoNormal
+	}
+	m.runnerToken.EchoMode = mode
+	for _, conn := range m.githubConnections {
+		conn.githubToken.EchoMode = mode
+	}
+}
+
+// newSecretInput returns a text input that masks its value until revealed with ctrl+r.
+func newSecretInput() textinput.Model {
+	ret := textinput.New()
+	ret.EchoMode = textinput.EchoPassword
+	return ret
+}
+
 func (m *model) isRunnerURLSelected() bool {
 	return m.selectedSection == runnerSection && m.selectedFieldIndex == runnerURLFieldIndex
 }
 
+type Options struct {
+	runner_config.Options
+	Version kong.VersionFlag `help:"Print version and exit" name:"version" short:"v"`
+}
+
 func main() {
 	defer util.HandleExit()
-	var options runner_config.Options
-	kong.Parse(&options)
+	var options Options
+	kong.Parse(&options, kong.Vars{"version": version.String()})
 	err := options.Process()
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
 		panic(util.ExitCode(1))
 	}
 
-	p := tea.NewProgram(initialModel(&options), tea.WithAltScreen())
+	p := tea.NewProgram(initialModel(&options.Options), tea.WithAltScreen())
 	finalModel, err := p.Run()
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
@@ -655,27 +743,27 @@ func initialModel(options *runner_config.Options) tea.Model {
 		selectedSection:      runnerSection,
 		selectedSectionIndex: 0,
 		selectedFieldIndex:   0,
-		runnerToken:          textinput.New(),
+		runnerToken:          newSecretInput(),
 		severURL:             textinput.New(),
 		runtime:              runtimeselector.New(),
 		spinner:              spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(spinnerStyle)),
 		options:              options,
 	}
 	ret.runnerToken.Focus()
-	ret.runnerToken.Placeholder = "p42_01234abcdef..."
+	ret.runnerToken.Placeholder = runnertoken.Prefix + "01234abcdef..."
 	ret.cfg.Runner.URL = "https://api.dev.plan42.ai"
 	ret.severURL.SetValue(ret.cfg.Runner.URL)
 
 	f, err := os.Open(options.ConfigFile)
 	if err != nil {
-		ret.originalConfigData, _ = toml.Marshal(ret.cfg)
+		ret.originalConfigData, _ = ret.marshalConfig(options.ConfigFile)
 		return ret
 	}
 	defer f.Close()
 	err = toml.NewDecoder(f).Decode(&ret.cfg)
 
 	if err != nil {
-		ret.originalConfigData, _ = toml.Marshal(ret.cfg)
+		ret.originalConfigData, _ = ret.marshalConfig(options.ConfigFile)
 		return ret
 	}
 	for _, entry := range ret.cfg.Github {
@@ -688,7 +776,7 @@ func initialModel(options *runner_config.Options) tea.Model {
 		ret.runtime.SetValue(ret.cfg.Runner.Runtime)
 	}
 
-	ret.originalConfigData, _ = toml.Marshal(ret.cfg)
+	ret.originalConfigData, _ = ret.marshalConfig(options.ConfigFile)
 	return ret
 }
 
@@ -697,6 +785,21 @@ type githubConnectionModel struct {
 	id          textinput.Model
 	serverURL   textinput.Model
 	githubToken textinput.Model
+	testStatus  string
+	testOK      bool
+}
+
+func (g *githubConnectionModel) writeTestStatus(b *strings.Builder) {
+	if g.testStatus == "" {
+		return
+	}
+	b.WriteString(fieldLabelStyle.Render(""))
+	if g.testOK {
+		b.WriteString(commentStyle.Render(g.testStatus))
+	} else {
+		b.WriteString(errorStyle.Render(g.testStatus))
+	}
+	b.WriteRune('\n')
 }
 
 func (g *githubConnectionModel) getInput(index int) *textinput.Model {
@@ -715,7 +818,7 @@ func newGithubConnectionModel(entry *config.GithubInfo) githubConnectionModel {
 		name:        textinput.New(),
 		id:          textinput.New(),
 		serverURL:   textinput.New(),
-		githubToken: textinput.New(),
+		githubToken: newSecretInput(),
 	}
 	ret.name.SetValue(entry.Name)
 	ret.id.SetValue(entry.ConnectionID)
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func typeText(input *textinput.Model, text string) {
+	*input, _ = input.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+func TestSecretInputStoresTypedValue(t *testing.T) {
+	conn := newGithubConnectionModel(&config.GithubInfo{Name: "github", ConnectionID: "conn-1"})
+	m := model{
+		selectedSection:   runnerSection,
+		runnerToken:       newSecretInput(),
+		githubConnections: []*githubConnectionModel{&conn},
+		cfg: config.Config{
+			Github: map[string]*config.GithubInfo{"github": {Name: "github", ConnectionID: "conn-1"}},
+		},
+	}
+	m.runnerToken.Focus()
+	m.runnerToken.Width = 40
+	m.runnerToken.Placeholder = "placeholder"
+	require.Contains(t, m.runnerToken.View(), "placeholder")
+
+	typeText(&m.runnerToken, "p42r_abc")
+	require.NotContains(t, m.runnerToken.View(), "p42r_abc")
+
+	m.onKey(tea.KeyMsg{Type: tea.KeyCtrlR}, nil)
+	require.Equal(t, textinput.EchoNormal, m.runnerToken.EchoMode)
+	require.Equal(t, textinput.EchoNormal, conn.githubToken.EchoMode)
+	typeText(&m.runnerToken, ".def")
+
+	m.onKey(tea.KeyMsg{Type: tea.KeyCtrlR}, nil)
+	require.Equal(t, textinput.EchoPassword, m.runnerToken.EchoMode)
+	typeText(&m.runnerToken, ".ghi")
+
+	m.commitChanges()
+	require.Equal(t, "p42r_abc.def.ghi", m.cfg.Runner.RunnerToken)
+
+	m.selectedSection = connectionsSection
+	m.selectedFieldIndex = 1
+	conn.githubToken.Focus()
+	typeText(&conn.githubToken, "ghp_secret")
+	m.commitChanges()
+	require.Equal(t, "ghp_secret", m.cfg.Github["github"].Token)
+}
+
+func TestShouldConfirmOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan42-runner.toml")
+	require.False(t, shouldConfirmOverwrite(path, []byte("a = 1\n")), "missing file")
+
+	require.NoError(t, os.WriteFile(path, []byte("a = 1\n"), 0o600))
+	require.False(t, shouldConfirmOverwrite(path, []byte("a = 1\n")), "unchanged content")
+	require.True(t, shouldConfirmOverwrite(path, []byte("a = 2\n")), "changed content")
+	require.True(t, shouldConfirmOverwrite(path, nil), "cleared content")
+}
+
+func TestResizeClampsToMinimumWidth(t *testing.T) {
+	conn := newGithubConnectionModel(&config.GithubInfo{Name: "a-very-long-connection-name", ConnectionID: "conn-1"})
+	m := model{
+		runnerToken:       newSecretInput(),
+		severURL:          textinput.New(),
+		githubConnections: []*githubConnectionModel{&conn},
+	}
+
+	for _, width := range []int{0, 1, fieldLabelStyle.GetWidth()} {
+		m.resize(width)
+		for _, input := range []textinput.Model{m.runnerToken, m.severURL, conn.name, conn.id, conn.serverURL, conn.githubToken} {
+			require.Equal(t, minInputWidth, input.Width)
+		}
+		require.NotPanics(t, func() { _ = m.View() })
+	}
+
+	m.resize(100)
+	require.Equal(t, 100-fieldLabelStyle.GetWidth()-3, conn.name.Width)
+	require.Equal(t, 100-fieldLabelStyle.GetWidth()-3, conn.id.Width)
+}
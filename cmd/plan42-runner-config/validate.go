@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/plan42-ai/cli/internal/runnertoken"
+)
+
+// validateRunnerToken performs a lightweight client-side check of a runner token: its prefix, and, if it parses, that
+// it hasn't expired. It returns nil for an empty token, which is reported when the token is validated against the
+// server.
+func validateRunnerToken(token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil
+	}
+	if !strings.HasPrefix(token, runnertoken.Prefix) {
+		return errors.New("runner tokens start with " + runnertoken.Prefix)
+	}
+	_, err := runnertoken.Parse(token)
+	if errors.Is(err, runnertoken.ErrExpired) || errors.Is(err, runnertoken.ErrNotYetValid) {
+		return err
+	}
+	return nil
+}
+
+// validateServerURL performs a lightweight client-side check of a server URL. It returns nil for an empty URL,
+// which is reported when the token is validated against the server.
+func validateServerURL(serverURL string) error {
+	serverURL = strings.TrimSpace(serverURL)
+	if serverURL == "" {
+		return nil
+	}
+	if !strings.HasPrefix(serverURL, "https://") {
+		return errors.New("server url must start with https://")
+	}
+	parsed, err := url.Parse(serverURL)
+	if err != nil || parsed.Host == "" {
+		return errors.New("server url is not a valid url")
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRunnerToken(t *testing.T) {
+	require.NoError(t, validateRunnerToken(""))
+	require.NoError(t, validateRunnerToken("p42r_abc.def.ghi"))
+	require.NoError(t, validateRunnerToken("  p42r_abc.def.ghi\n"))
+	require.Error(t, validateRunnerToken("p42_abc.def.ghi"))
+	require.Error(t, validateRunnerToken("abc.def.ghi"))
+}
+
+func TestValidateServerURL(t *testing.T) {
+	require.NoError(t, validateServerURL(""))
+	require.NoError(t, validateServerURL("https://api.plan42.ai"))
+	require.NoError(t, validateServerURL(" https://api.plan42.ai/ "))
+	require.Error(t, validateServerURL("http://api.plan42.ai"))
+	require.Error(t, validateServerURL("api.plan42.ai"))
+	require.Error(t, validateServerURL("https://"))
+	require.Error(t, validateServerURL("https://%zz"))
+}
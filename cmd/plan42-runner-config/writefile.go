@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+
+	"github.com/google/renameio/v2"
+)
+
+// writeConfigFile atomically replaces the config file at path with data.
+func writeConfigFile(path string, data []byte) error {
+	return renameio.WriteFile(path, data, os.FileMode(0600))
+}
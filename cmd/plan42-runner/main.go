@@ -6,65 +6,85 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/signal"
-	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/plan42-ai/cli/internal/cli/runner"
 	"github.com/plan42-ai/cli/internal/poller"
+	"github.com/plan42-ai/cli/internal/runnertoken"
 	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/cli/internal/version"
 	"github.com/plan42-ai/log"
-	"github.com/plan42-ai/openid/jwt"
 )
 
+type Options struct {
+	runner.Options
+	Version kong.VersionFlag `help:"Print version and exit" name:"version" short:"v"`
+}
+
 func main() {
 	defer util.HandleExit()
 	log.SetupTextLogging()
-	var options runner.Options
-	kong.Parse(&options)
+	slog.SetDefault(slog.New(util.NewRedactingHandler(slog.Default().Handler())))
+	var options Options
+	kong.Parse(&options, kong.Vars{"version": version.String()})
 	err := options.Process()
 	if err != nil {
 		slog.Error("error processing options", "error", err)
-		panic(util.ExitCode(1))
+		panic(util.ExitCodeOf(err, util.ExitCodeConfig))
+	}
+	if options.PrintConfig {
+		err = options.WriteConfig(os.Stdout)
+		if err != nil {
+			slog.Error("error printing config", "error", err)
+			panic(util.ExitCodeConfig)
+		}
+		return
 	}
 	tokenID, runnerID, err := extractParamsFromToken(options.Config.Runner.RunnerToken)
 	if err != nil {
 		slog.Error("error extracting params from token", "error", err)
-		panic(util.ExitCode(2))
+		panic(util.ExitCodeToken)
 	}
+	options.LogStartupSummary(context.Background(), tokenID, runnerID)
+	// pulls still running at shutdown are cancelled, and waited for, after the poller has stopped.
+	prepullCtx, cancelPrepull := context.WithCancel(context.Background())
+	var prepull sync.WaitGroup
+	prepull.Go(func() { options.PrePullImages(prepullCtx, options.PrepullImages) })
+	defer prepull.Wait()
+	defer cancelPrepull()
+
 	p := poller.New(options.Client, tokenID, runnerID, options.PollerOptions()...)
 	defer util.Close(p)
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	sigCh := util.NotifyStopSignals()
 
-	sig := <-sigCh
+	select {
+	case sig := <-sigCh:
+		slog.Info("Received stop signal. Draining queues. This will take 30 seconds. Send another to stop immediately.", "signal", sig.String())
+	case <-p.Done():
+		slog.Info("processed one batch of messages, shutting down")
+	}
 
-	slog.Info("Received stop signal. Draining queues. This will take 30 seconds.", "signal", sig.String())
-	err = p.ShutdownTimeout(time.Minute * 5)
-	if err != nil {
+	err = util.GracefulShutdown(p, sigCh, time.Minute*5)
+	switch {
+	case errors.Is(err, util.ErrForcedShutdown):
+		slog.Warn("received second stop signal, forced shutdown")
+	case err != nil:
 		slog.ErrorContext(context.Background(), "draining queues timedoout, running force shutdown", "error", err)
-	} else {
+	default:
 		slog.Info("queues drained successfully, shutting down")
 	}
 }
 
 func extractParamsFromToken(token string) (tokenID string, runnerID string, err error) {
-	s := strings.SplitN(token, "_", 2)
-	if len(s) != 2 {
-		return "", "", errors.New("invalid api token")
-	}
-	if s[0] != "p42r" {
-		return "", "", errors.New("api token is not a runner token")
-	}
-	parsedToken, err := jwt.Parse(s[1])
+	claims, err := runnertoken.Parse(token)
 	if err != nil {
-		return "", "", fmt.Errorf("invalid api token: %w", err)
+		return "", "", err
 	}
-	if parsedToken.Payload.RunnerID == nil {
-		return "", "", errors.New("invalid api token")
+	if claims.RunnerID == "" {
+		return "", "", fmt.Errorf("%w: missing runner id", runnertoken.ErrInvalid)
 	}
-	return parsedToken.Payload.Subject, *parsedToken.Payload.RunnerID, nil
+	return claims.TenantID, claims.RunnerID, nil
 }
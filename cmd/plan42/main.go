@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -21,16 +23,17 @@ import (
 	runner_config "github.com/plan42-ai/cli/internal/cli/runnerconfig"
 	"github.com/plan42-ai/cli/internal/config"
 	"github.com/plan42-ai/cli/internal/launchctl"
+	"github.com/plan42-ai/cli/internal/p42client"
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/p42runtime/apple"
 	"github.com/plan42-ai/cli/internal/p42runtime/podman"
+	"github.com/plan42-ai/cli/internal/runnertoken"
 	"github.com/plan42-ai/cli/internal/util"
-	"github.com/plan42-ai/openid/jwt"
+	"github.com/plan42-ai/cli/internal/version"
 	"github.com/plan42-ai/sdk-go/p42"
 )
 
 var (
-	Version                = "dev"
 	ErrRunnerNotConfigured = errors.New("runner not configured. Run `plan42 runner configure` first, then re-run `plan42 runner enable`")
 )
 
@@ -48,24 +51,12 @@ const (
 	runnerAgentLabel = "ai.plan42.runner"
 )
 
-// jobLogDir returns the directory where job logs are stored.
-func jobLogDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to determine home directory: %w", err)
-	}
-	return filepath.Join(homeDir, "Library", "Logs", runnerAgentLabel), nil
-}
-
 // loadConfig loads the runner config from the given path.
-// If configPath is empty, it uses the default path (~/.config/plan42-runner.toml).
+// If configPath is empty, it uses the default path ($XDG_CONFIG_HOME or ~/.config, plus plan42-runner.toml).
 func loadConfig(configPath string) (*config.Config, error) {
-	if configPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to determine home directory: %w", err)
-		}
-		configPath = filepath.Join(homeDir, ".config", "plan42-runner.toml")
+	configPath, err := util.ResolveRunnerConfigFileName(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine home directory: %w", err)
 	}
 
 	f, err := os.Open(configPath)
@@ -79,24 +70,16 @@ func loadConfig(configPath string) (*config.Config, error) {
 
 	var cfg config.Config
 	if err := toml.NewDecoder(f).Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode runner config file: %w", err)
+		return nil, fmt.Errorf("failed to decode runner config file: %w", util.RedactError(err))
 	}
 
 	return &cfg, nil
 }
 
-func normalizeRuntime(runtimeName string) string {
-	runtimeName = strings.ToLower(strings.TrimSpace(runtimeName))
-	if runtimeName == "" {
-		return p42runtime.RuntimeApple
-	}
-	return runtimeName
-}
-
 // createProvider creates a runtime provider based on the config.
 // Returns an error if the configured runtime is not supported.
 func createProvider(cfg *config.Config, logDir string) (p42runtime.Provider, error) {
-	runtimeName := normalizeRuntime(cfg.Runner.Runtime)
+	runtimeName := p42runtime.NormalizeRuntime(cfg.Runner.Runtime)
 
 	switch runtimeName {
 	case p42runtime.RuntimeApple:
@@ -114,9 +97,13 @@ type RunnerOptions struct {
 	Exec    RunnerExecOptions    `cmd:"" help:"Execute the plan42 remote runner service."`
 	Stop    RunnerStopOptions    `cmd:"" help:"Stop the plan42 runner service."`
 	Status  RunnerStatusOptions  `cmd:"" help:"Show the status of the plan42 runner service."`
-	Logs    RunnerLogsOptions    `cmd:"" help:"Show the logs of the plan42 runner service."`
+	Check   RunnerCheckOptions   `cmd:"" help:"Check that the runner can connect to the server with its configured token."`
+	Doctor  RunnerDoctorOptions  `cmd:"" help:"Check the config, runner token, container runtime, server, and github connections."`
+	Logs    RunnerLogsOptions    `cmd:"" help:"Show the logs of the plan42 runner service, or of a task's agent job."`
 	Disable RunnerDisableOptions `cmd:"" help:"Disable the plan42 runner service."`
 	Job     RunnerJobOptions     `cmd:"" help:"Commands related to managing runner jobs."`
+	Kill    RunnerKillOptions    `cmd:"" help:"Kill the running jobs for a task."`
+	Clean   RunnerCleanOptions   `cmd:"" help:"Remove old logs for completed jobs."`
 }
 
 func forwardToSibling(execName string, commandDepth int) error {
@@ -145,7 +132,7 @@ func (r *RunnerExecOptions) Run() error {
 }
 
 type RunnerEnableOptions struct {
-	ConfigFile string `help:"Path to config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	ConfigFile string `help:"Path to config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (r *RunnerEnableOptions) Run() error {
@@ -167,13 +154,9 @@ func (r *RunnerEnableOptions) Run() error {
 }
 
 func (r *RunnerEnableOptions) resolveConfigPath() (string, error) {
-	configPath := r.ConfigFile
-	if configPath == "" {
-		var err error
-		configPath, err = util.DefaultRunnerConfigFileName()
-		if err != nil {
-			return "", fmt.Errorf("unable to determine default config file: %w", err)
-		}
+	configPath, err := util.ResolveRunnerConfigFileName(r.ConfigFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine default config file: %w", err)
 	}
 
 	absPath, err := filepath.Abs(configPath)
@@ -202,7 +185,7 @@ func validateRunnerConfig(configPath string) (*config.Config, error) {
 		return nil, ErrRunnerNotConfigured
 	}
 
-	cfg.Runner.Runtime = normalizeRuntime(cfg.Runner.Runtime)
+	cfg.Runner.Runtime = p42runtime.NormalizeRuntime(cfg.Runner.Runtime)
 	switch cfg.Runner.Runtime {
 	case p42runtime.RuntimeApple, p42runtime.RuntimePodman:
 	default:
@@ -242,17 +225,17 @@ func (r *RunnerEnableOptions) enableLaunchAgent(configPath string, cfg *config.C
 	}
 
 	agent := launchctl.Agent{
-		Name:        runnerAgentLabel,
-		Argv:        args,
-		ExitTimeout: util.Pointer(5 * time.Minute),
-		CreateLog:   true,
+		Name:             runnerAgentLabel,
+		Argv:             args,
+		ExitTimeout:      util.Pointer(5 * time.Minute),
+		ThrottleInterval: util.Pointer(launchctl.DefaultThrottleInterval),
+		CreateLog:        true,
 	}
 	err = agent.Create()
 	if err != nil {
 		return err
 	}
 
-	_ = agent.Shutdown()
 	_ = agent.Enable()
 	err = agent.Bootstrap()
 	if err != nil {
@@ -293,7 +276,9 @@ func (rs *RunnerStopOptions) Run() error {
 	return nil
 }
 
-type RunnerStatusOptions struct{}
+type RunnerStatusOptions struct {
+	ContainerPath string `help:"Path to the container executable, to report its version" default:"/opt/homebrew/bin/container"`
+}
 
 func (rs *RunnerStatusOptions) Run() error {
 	if runtime.GOOS != darwin {
@@ -308,14 +293,86 @@ func (rs *RunnerStatusOptions) Run() error {
 		return fmt.Errorf("failed to get runner status: %w", err)
 	}
 	fmt.Print(output)
+
+	containerProvider := apple.NewProvider(rs.ContainerPath, "")
+	if containerProvider.IsInstalled() {
+		v, err := containerProvider.CheckVersion(context.Background())
+		switch {
+		case v == apple.Version{}:
+			fmt.Printf("apple container version: unknown (%v)\n", err)
+		case err != nil:
+			fmt.Printf("apple container version: %s (%v)\n", v, err)
+		default:
+			fmt.Printf("apple container version: %s\n", v)
+		}
+	}
+	return nil
+}
+
+type RunnerCheckOptions struct {
+	ConfigFile string        `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
+	Timeout    time.Duration `help:"How long to wait for the server to respond." default:"30s"`
+}
+
+func (rc *RunnerCheckOptions) Run() error {
+	cfg, err := loadConfig(rc.ConfigFile)
+	if err != nil {
+		return util.WithExitCode(util.ExitCodeConfig, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rc.Timeout)
+	defer cancel()
+
+	runner.WarnIfInsecure(cfg.Runner)
+	err = runner.CheckServer(ctx, cfg.Runner)
+	if err != nil {
+		return fmt.Errorf("runner check failed for %s: %w", cfg.Runner.URL, err)
+	}
+	fmt.Printf("Successfully connected to %s with the configured runner token.\n", cfg.Runner.URL)
 	return nil
 }
 
+type RunnerDoctorOptions struct {
+	ConfigFile string        `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
+	Timeout    time.Duration `help:"How long to wait for the server and github to respond." default:"30s"`
+}
+
+func (rd *RunnerDoctorOptions) Run() error {
+	cfg, err := loadConfig(rd.ConfigFile)
+	var provider p42runtime.Provider
+	if err == nil {
+		provider, err = createProvider(cfg, "")
+	}
+	results := []runner.DoctorResult{
+		{
+			Name: "config",
+			Hint: "fix the config file, or create one with `plan42 runner config`",
+			Err:  util.WithExitCode(util.ExitCodeConfig, err),
+		},
+	}
+	// The remaining checks all need the config.
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), rd.Timeout)
+		defer cancel()
+		results = append(results, runner.RunDoctor(ctx, runner.DoctorChecks(*cfg, provider))...)
+	}
+	return runner.WriteDoctorReport(os.Stdout, results)
+}
+
 type RunnerLogsOptions struct {
-	Follow bool `name:"f" short:"f" help:"Follow log output."`
+	TaskID     string `arg:"" name:"task-id" optional:"" help:"Show the agent log of this task's job instead of the runner service log."`
+	Turn       *int   `help:"Show the job for this turn index when the task has more than one." short:"t"`
+	Follow     bool   `name:"f" short:"f" help:"Follow log output."`
+	Tail       int    `help:"Only show the last N lines." short:"n" placeholder:"N"`
+	ConfigFile string `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (rl *RunnerLogsOptions) Run() error {
+	if rl.TaskID != "" {
+		return rl.viewTaskLog()
+	}
+
+	// the runner service log is kept by launchd, so only job logs can be shown elsewhere.
 	if runtime.GOOS != darwin {
 		return fmt.Errorf("runner logs not supported on %s", runtime.GOOS)
 	}
@@ -326,15 +383,61 @@ func (rl *RunnerLogsOptions) Run() error {
 		return fmt.Errorf("failed to determine log path: %w", err)
 	}
 
-	return viewLogFile(logPath, rl.Follow)
+	return viewLogFile(logPath, rl.Follow, rl.Tail)
+}
+
+// viewTaskLog shows the log of the agent job for rl.TaskID.
+func (rl *RunnerLogsOptions) viewTaskLog() error {
+	cfg, err := loadConfig(rl.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	logDir, err := jobLogDir()
+	if err != nil {
+		return err
+	}
+
+	provider, err := createProvider(cfg, logDir)
+	if err != nil {
+		return err
+	}
+
+	jobID, err := p42runtime.FindTaskJob(context.Background(), provider, rl.TaskID, rl.Turn)
+	if err != nil {
+		return err
+	}
+
+	return viewLogFile(filepath.Join(logDir, jobID), rl.Follow, rl.Tail)
 }
 
-func viewLogFile(logPath string, follow bool) error {
+// viewLogFile shows the log at logPath, preceded by its rolled over files, oldest first, in a pager if stdout is a
+// terminal. If follow is set, new lines are printed as they are written, carrying on in the new file when the log is
+// rolled over. If tail is positive, only the last tail lines are shown.
+func viewLogFile(logPath string, follow bool, tail int) error {
 	var logCmd *exec.Cmd
-	if follow {
-		logCmd = exec.Command("tail", "-f", logPath)
-	} else {
-		logCmd = exec.Command("cat", logPath)
+	switch {
+	case tail > 0 && follow:
+		logCmd = exec.Command("tail", "-n", strconv.Itoa(tail), "-F", logPath)
+	case follow:
+		logCmd = exec.Command("tail", "-F", logPath)
+	default:
+		logFiles, err := p42runtime.JobLogFiles(logPath)
+		if err != nil {
+			return fmt.Errorf("failed to list log files: %w", err)
+		}
+		if tail <= 0 {
+			logCmd = exec.Command("cat", logFiles...)
+			break
+		}
+		// the last lines can span rolled over files, so they're taken from all of them.
+		logs, closeLogs, err := openLogFiles(logFiles)
+		if err != nil {
+			return err
+		}
+		defer closeLogs()
+		logCmd = exec.Command("tail", "-n", strconv.Itoa(tail))
+		logCmd.Stdin = logs
 	}
 
 	logCmd.Stderr = os.Stderr
@@ -399,6 +502,28 @@ func viewLogFile(logPath string, follow bool) error {
 	return nil
 }
 
+// openLogFiles opens paths and returns a reader of their contents, one after another, along with a function that
+// closes them.
+func openLogFiles(paths []string) (io.Reader, func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeFiles()
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+	return io.MultiReader(readers...), closeFiles, nil
+}
+
 type RunnerDisableOptions struct {
 }
 
@@ -434,14 +559,10 @@ type RunnerJobOptions struct {
 }
 
 type RunnerJobPruneOptions struct {
-	ConfigFile string `help:"Path to runner config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	ConfigFile string `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (r *RunnerJobPruneOptions) Run() error {
-	if runtime.GOOS != darwin {
-		return fmt.Errorf("runner job prune not supported on %s", runtime.GOOS)
-	}
-
 	cfg, err := loadConfig(r.ConfigFile)
 	if err != nil {
 		return err
@@ -481,12 +602,19 @@ func (r *RunnerJobPruneOptions) Run() error {
 }
 
 type ListRunnerJobOptions struct {
-	All        bool   `help:"When set, also list completed jobs." short:"a"`
-	Verbose    bool   `help:"Output verbose error logs."`
-	ConfigFile string `help:"Path to runner config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	All         bool   `help:"When set, also list completed jobs." short:"a"`
+	Verbose     bool   `help:"Output verbose error logs."`
+	Output      string `help:"Output format (table or json)." short:"o" enum:"table,json" default:"table"`
+	Task        string `help:"Only list jobs whose task ID starts with this prefix."`
+	Concurrency int    `help:"Maximum number of concurrent API calls used to fetch job details." default:"10"`
+	ConfigFile  string `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (l *ListRunnerJobOptions) Run() error {
+	if l.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", l.Concurrency)
+	}
+
 	cfg, err := loadConfig(l.ConfigFile)
 	if err != nil {
 		return err
@@ -496,22 +624,16 @@ func (l *ListRunnerJobOptions) Run() error {
 		return fmt.Errorf("runner token not set in config. Run `plan42 runner config` to configure the runner")
 	}
 
-	s := strings.SplitN(cfg.Runner.RunnerToken, "_", 2)
-	if len(s) != 2 || s[0] != "p42r" {
-		return fmt.Errorf("invalid runner token in config. Run `plan42 runner config` to configure the runner")
-	}
-
-	token, err := jwt.Parse(s[1])
+	claims, err := runnertoken.Parse(cfg.Runner.RunnerToken)
 	if err != nil {
-		return fmt.Errorf("invalid runner token in config. Run `plan42 runner config` to configure the runner")
+		return fmt.Errorf("%w. Run `plan42 runner config` to configure the runner", err)
 	}
 
-	tenantID := token.Payload.Subject
-	options := []p42.Option{
-		p42.WithAPIToken(cfg.Runner.RunnerToken),
-	}
-	if cfg.Runner.SkipSSLVerify {
-		options = append(options, p42.WithInsecureSkipVerify())
+	tenantID := claims.TenantID
+	runner.WarnIfInsecure(cfg.Runner)
+	options, err := p42client.Options(cfg.Runner)
+	if err != nil {
+		return err
 	}
 	client := p42.NewClient(cfg.Runner.URL, options...)
 
@@ -525,11 +647,31 @@ func (l *ListRunnerJobOptions) Run() error {
 		return err
 	}
 
-	jobs, err := p42runtime.GetJobs(context.Background(), provider, client, tenantID, l.Verbose, l.All)
+	jobs, err := p42runtime.GetJobs(
+		context.Background(),
+		provider,
+		client,
+		tenantID,
+		p42runtime.GetJobsOptions{
+			Verbose:          l.Verbose,
+			IncludeCompleted: l.All,
+			Filter:           p42runtime.GetJobsFilter{TaskIDPrefix: l.Task},
+			FetchConcurrency: l.Concurrency,
+		},
+	)
 	if err != nil {
 		return fmt.Errorf("failed to list jobs: %w", err)
 	}
 
+	if l.Output == "json" {
+		data, err := p42runtime.MarshalJobsJSON(jobs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal jobs: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	widths := getJobWidths(jobs)
 	fmt.Printf(
 		"%-*s     %-*s     %-*s     %-*s     %-*s\n",
@@ -554,7 +696,7 @@ func (l *ListRunnerJobOptions) Run() error {
 			widths.ID,
 			fmt.Sprintf("plan42-%v-%d", job.TaskID, job.TurnIndex),
 			widths.Title,
-			job.TaskTitle,
+			job.DisplayTitle(),
 			widths.TurnIndex,
 			job.TurnIndex,
 			widths.Running,
@@ -563,6 +705,11 @@ func (l *ListRunnerJobOptions) Run() error {
 			createdDate,
 		)
 	}
+	for _, job := range jobs {
+		if job.FetchErr != nil {
+			fmt.Fprintf(os.Stderr, "plan42-%v-%d: %v\n", job.TaskID, job.TurnIndex, job.FetchErr)
+		}
+	}
 	return nil
 }
 
@@ -583,7 +730,7 @@ func getJobWidths(jobs []*p42runtime.Job) JobWidths {
 			len(fmt.Sprintf("plan42-%v-%d", job.TaskID, job.TurnIndex)),
 			len(jobIDColumn),
 		)
-		ret.Title = max(ret.Title, len(job.TaskTitle), len(titleColumn))
+		ret.Title = max(ret.Title, len(job.DisplayTitle()), len(titleColumn))
 		ret.TurnIndex = max(ret.TurnIndex, len(fmt.Sprintf("%d", job.TurnIndex)), len(turnIndexColumn))
 		ret.Created = max(ret.Created, len(job.CreatedDate.Format(time.DateTime)), len(createdColumn))
 	}
@@ -596,16 +743,12 @@ type RunnerJobLogsOptions struct {
 }
 
 func (rl *RunnerJobLogsOptions) Run() error {
-	if runtime.GOOS != darwin {
-		return fmt.Errorf("runner job logs not supported on %s", runtime.GOOS)
-	}
-
 	logPath, err := runnerJobLogPath(rl.JobID)
 	if err != nil {
 		return err
 	}
 
-	return viewLogFile(logPath, rl.Follow)
+	return viewLogFile(logPath, rl.Follow, 0)
 }
 
 func runnerJobLogPath(jobID string) (string, error) {
@@ -613,24 +756,20 @@ func runnerJobLogPath(jobID string) (string, error) {
 		return "", fmt.Errorf("jobid is required")
 	}
 
-	homeDir, err := os.UserHomeDir()
+	logDir, err := jobLogDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to determine user home directory: %w", err)
+		return "", err
 	}
 
-	return filepath.Join(homeDir, "Library", "Logs", runnerAgentLabel, jobID), nil
+	return filepath.Join(logDir, jobID), nil
 }
 
 type KillRunnerJobOptions struct {
 	JobID      string `arg:"" help:"The job id to kill."`
-	ConfigFile string `help:"Path to runner config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	ConfigFile string `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (k *KillRunnerJobOptions) Run() error {
-	if runtime.GOOS != darwin {
-		return fmt.Errorf("runner job kill not supported on %s", runtime.GOOS)
-	}
-
 	cfg, err := loadConfig(k.ConfigFile)
 	if err != nil {
 		return err
@@ -653,9 +792,83 @@ func (k *KillRunnerJobOptions) Run() error {
 	return provider.KillJob(context.Background(), k.JobID)
 }
 
+type RunnerKillOptions struct {
+	TaskID     string `arg:"" name:"task-id" help:"The task ID whose jobs should be killed."`
+	Turn       *int   `help:"Only kill the job for this turn index." short:"t"`
+	All        bool   `help:"Kill every running turn of the task when more than one is running." short:"a"`
+	ConfigFile string `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
+}
+
+func (k *RunnerKillOptions) Run() error {
+	cfg, err := loadConfig(k.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	logDir, err := jobLogDir()
+	if err != nil {
+		return err
+	}
+
+	provider, err := createProvider(cfg, logDir)
+	if err != nil {
+		return err
+	}
+
+	killed, err := p42runtime.KillTaskJobs(context.Background(), provider, k.TaskID, k.Turn, k.All)
+	for _, jobID := range killed {
+		fmt.Printf("killed %s\n", jobID)
+	}
+	return err
+}
+
+type RunnerCleanOptions struct {
+	OlderThan  time.Duration `help:"Only remove logs last modified longer ago than this." default:"168h"`
+	DryRun     bool          `help:"Print the logs that would be removed without removing them."`
+	ConfigFile string        `help:"Path to runner config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
+}
+
+func (c *RunnerCleanOptions) Run() error {
+	cfg, err := loadConfig(c.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	logDir, err := jobLogDir()
+	if err != nil {
+		return err
+	}
+
+	provider, err := createProvider(cfg, logDir)
+	if err != nil {
+		return err
+	}
+
+	pruned, err := p42runtime.PruneJobLogs(context.Background(), provider, c.OlderThan, c.DryRun)
+	for _, jobID := range pruned {
+		if c.DryRun {
+			fmt.Printf("would remove %s\n", jobID)
+		} else {
+			fmt.Printf("removed %s\n", jobID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clean job logs: %w", err)
+	}
+	return nil
+}
+
+type VersionOptions struct{}
+
+func (v *VersionOptions) Run() error {
+	fmt.Println(version.String())
+	return nil
+}
+
 type Options struct {
-	Version kong.VersionFlag `help:"Print version and exit" name:"version" short:"v"`
-	Runner  RunnerOptions    `cmd:""`
+	Version    kong.VersionFlag `help:"Print version and exit" name:"version" short:"v"`
+	Runner     RunnerOptions    `cmd:""`
+	VersionCmd VersionOptions   `cmd:"" name:"version" help:"Print version information."`
 }
 
 func main() {
@@ -663,11 +876,13 @@ func main() {
 	var options Options
 	kongCtx := kong.Parse(
 		&options,
-		kong.Vars{"version": Version},
+		kong.Vars{"version": version.String()},
 	)
 
 	var err error
 	switch kongCtx.Command() {
+	case "version":
+		err = options.VersionCmd.Run()
 	case "runner exec":
 		err = options.Runner.Exec.Run()
 	case "runner enable":
@@ -678,7 +893,11 @@ func main() {
 		err = options.Runner.Stop.Run()
 	case "runner status":
 		err = options.Runner.Status.Run()
-	case "runner logs":
+	case "runner check":
+		err = options.Runner.Check.Run()
+	case "runner doctor":
+		err = options.Runner.Doctor.Run()
+	case "runner logs", "runner logs <task-id>":
 		err = options.Runner.Logs.Run()
 	case "runner disable":
 		err = options.Runner.Disable.Run()
@@ -690,12 +909,16 @@ func main() {
 		err = options.Runner.Job.Kill.Run()
 	case "runner job logs <jobid>":
 		err = options.Runner.Job.Logs.Run()
+	case "runner kill <task-id>":
+		err = options.Runner.Kill.Run()
+	case "runner clean":
+		err = options.Runner.Clean.Run()
 	default:
 		err = fmt.Errorf("unknown command: %s", kongCtx.Command())
 	}
 
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
-		panic(util.ExitCode(-1))
+		panic(util.ExitCodeOf(err, -1))
 	}
 }
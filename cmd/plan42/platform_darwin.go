@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// jobLogDir returns the directory where job logs are stored.
+func jobLogDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "Library", "Logs", runnerAgentLabel), nil
+}
@@ -0,0 +1,22 @@
+//go:build !darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// jobLogDir returns the directory job logs are kept in: ai.plan42.runner in $XDG_STATE_HOME, or ~/.local/state if it
+// isn't set.
+func jobLogDir() (string, error) {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(stateHome) {
+		return filepath.Join(stateHome, runnerAgentLabel), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".local", "state", runnerAgentLabel), nil
+}
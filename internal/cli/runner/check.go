@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/p42client"
+	"github.com/plan42-ai/cli/internal/runnertoken"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42"
+)
+
+var (
+	// ErrTokenNotAuthorized is returned when the server rejects the runner token.
+	ErrTokenNotAuthorized = errors.New("token not authorized")
+	// ErrServerUnreachable is returned when a call to the server fails for any other reason.
+	ErrServerUnreachable = errors.New("unable to connect to server")
+)
+
+// NewServerClient returns a client for the server configured in cfg, authenticated with the runner token, along with
+// the ID of the tenant the token belongs to.
+func NewServerClient(cfg config.Runner) (*p42.Client, string, error) {
+	if cfg.RunnerToken == "" {
+		return nil, "", errors.New("missing runner token")
+	}
+	if cfg.URL == "" {
+		return nil, "", errors.New("missing server url")
+	}
+
+	claims, err := runnertoken.Parse(cfg.RunnerToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	options, err := p42client.Options(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return p42.NewClient(cfg.URL, options...), claims.TenantID, nil
+}
+
+// WarnIfInsecure logs a warning if TLS verification of the server is disabled, so a runner left with skip_ssl_verify
+// set doesn't go unnoticed.
+func WarnIfInsecure(cfg config.Runner) {
+	if !cfg.SkipSSLVerify {
+		return
+	}
+	slog.Warn(
+		"TLS certificate verification is disabled (skip_ssl_verify); connections to the server are not secure",
+		"endpoint", cfg.URL,
+	)
+}
+
+// ServerError wraps an error returned by the server in ErrTokenNotAuthorized if the server rejected the runner token,
+// or ErrServerUnreachable otherwise.
+func ServerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var httpErr p42.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.Code() == http.StatusUnauthorized || httpErr.Code() == http.StatusForbidden) {
+		return fmt.Errorf("%w: %w", ErrTokenNotAuthorized, err)
+	}
+	return fmt.Errorf("%w: %w", ErrServerUnreachable, err)
+}
+
+// CheckServer verifies that the runner token and server URL in cfg work by making a lightweight authenticated call to
+// the server. The returned error carries an exit code that tells an invalid token, a rejected token, and an
+// unreachable server apart.
+func CheckServer(ctx context.Context, cfg config.Runner) error {
+	client, tenantID, err := NewServerClient(cfg)
+	if err != nil {
+		return util.WithExitCode(util.ExitCodeToken, err)
+	}
+
+	_, err = client.ListGithubConnections(
+		ctx,
+		&p42.ListGithubConnectionsRequest{
+			TenantID:   tenantID,
+			MaxResults: util.Pointer(1),
+			Private:    util.Pointer(true),
+		},
+	)
+	err = ServerError(err)
+	switch {
+	case errors.Is(err, ErrTokenNotAuthorized):
+		return util.WithExitCode(util.ExitCodeUnauthorized, err)
+	case err != nil:
+		return util.WithExitCode(util.ExitCodeUnreachable, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckServer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		wantErr  error
+		wantCode util.ExitCode
+	}{
+		{name: "authorized", status: http.StatusOK},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: ErrTokenNotAuthorized, wantCode: util.ExitCodeUnauthorized},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: ErrServerUnreachable, wantCode: util.ExitCodeUnreachable},
+	}
+
+	token := testRunnerToken(t, map[string]any{"sub": "tenant-123"})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/tenants/tenant-123/github-connections", r.URL.Path)
+				assert.Equal(t, "APIToken "+token, r.Header.Get("Authorization"))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				if tc.status == http.StatusOK {
+					_ = json.NewEncoder(w).Encode(p42.List[*p42.GithubConnection]{})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(p42.Error{ResponseCode: tc.status, Message: http.StatusText(tc.status)})
+			}))
+			defer server.Close()
+
+			err := CheckServer(t.Context(), config.Runner{URL: server.URL, RunnerToken: token})
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCode, util.ExitCodeOf(err, -1))
+		})
+	}
+}
+
+func TestCheckServerUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverURL := server.URL
+	server.Close()
+
+	token := testRunnerToken(t, map[string]any{"sub": "tenant-123"})
+	err := CheckServer(t.Context(), config.Runner{URL: serverURL, RunnerToken: token})
+	require.ErrorIs(t, err, ErrServerUnreachable)
+	require.Equal(t, util.ExitCodeUnreachable, util.ExitCodeOf(err, -1))
+}
+
+func TestCheckServerInvalidToken(t *testing.T) {
+	err := CheckServer(t.Context(), config.Runner{URL: "https://api.plan42.ai", RunnerToken: "not-a-runner-token"})
+	require.Error(t, err)
+	require.Equal(t, util.ExitCodeToken, util.ExitCodeOf(err, -1))
+}
+
+func TestWarnIfInsecure(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cfg := config.Runner{URL: "https://api.plan42.ai", RunnerToken: testRunnerToken(t, map[string]any{"sub": "tenant-123"})}
+	WarnIfInsecure(cfg)
+	require.Empty(t, buf.String())
+
+	// building a client doesn't log, since the config TUI builds one while it owns the terminal.
+	cfg.SkipSSLVerify = true
+	_, _, err := NewServerClient(cfg)
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+
+	WarnIfInsecure(cfg)
+	require.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	require.Contains(t, buf.String(), "level=WARN")
+	require.Contains(t, buf.String(), "TLS certificate verification is disabled")
+	require.Contains(t, buf.String(), "endpoint=https://api.plan42.ai")
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/github"
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/p42runtime/apple"
+	"github.com/plan42-ai/cli/internal/runnertoken"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+// DoctorCheck is one of the checks run by `plan42 runner doctor`.
+type DoctorCheck struct {
+	Name string
+	// Hint tells the user how to fix the problem when the check fails.
+	Hint string
+	Run  func(ctx context.Context) error
+}
+
+// DoctorResult is the outcome of a DoctorCheck. Err is nil if the check passed.
+type DoctorResult struct {
+	Name string
+	Hint string
+	Err  error
+}
+
+// RunDoctor runs checks in order and returns their results. A failed check doesn't stop the remaining ones from
+// running, so every problem is reported at once.
+func RunDoctor(ctx context.Context, checks []DoctorCheck) []DoctorResult {
+	results := make([]DoctorResult, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, DoctorResult{Name: check.Name, Hint: check.Hint, Err: check.Run(ctx)})
+	}
+	return results
+}
+
+// DoctorChecks returns the checks for a runner configured with cfg that uses provider to run jobs: the runner
+// token, the container runtime, the server, and each github connection.
+func DoctorChecks(cfg config.Config, provider p42runtime.Provider) []DoctorCheck {
+	checks := []DoctorCheck{
+		{
+			Name: "runner token",
+			Hint: "set a valid runner token with `plan42 runner config`",
+			Run: func(_ context.Context) error {
+				if cfg.Runner.RunnerToken == "" {
+					return util.WithExitCode(util.ExitCodeToken, errors.New("missing runner token"))
+				}
+				_, err := runnertoken.Parse(cfg.Runner.RunnerToken)
+				return util.WithExitCode(util.ExitCodeToken, err)
+			},
+		},
+		{
+			Name: fmt.Sprintf("%s runtime", provider.Name()),
+			Hint: "install the container runtime, or select another one with the runtime setting",
+			Run: func(ctx context.Context) error {
+				return checkRuntime(ctx, provider)
+			},
+		},
+		{
+			Name: "server",
+			Hint: "check the url and runner token in the config, and that the server is reachable from this machine",
+			Run: func(ctx context.Context) error {
+				return CheckServer(ctx, cfg.Runner)
+			},
+		},
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Github)) {
+		cnn := cfg.Github[name]
+		checks = append(checks, DoctorCheck{
+			Name: fmt.Sprintf("github connection %s", name),
+			Hint: "the github token may have expired or been revoked; update it with `plan42 runner config`",
+			Run: func(ctx context.Context) error {
+				return checkGithubConnection(ctx, cnn)
+			},
+		})
+	}
+	return checks
+}
+
+// WriteDoctorReport writes results to w as a checklist, with a hint below each failed check, and returns an error
+// if any check failed. The error carries the exit code of the first failed check that has one.
+func WriteDoctorReport(w io.Writer, results []DoctorResult) error {
+	var failed int
+	code := util.ExitCodeConfig
+	for _, result := range results {
+		if result.Err == nil {
+			_, _ = fmt.Fprintf(w, "[PASS] %s\n", result.Name)
+			continue
+		}
+		if failed == 0 {
+			code = util.ExitCodeOf(result.Err, code)
+		}
+		failed++
+		_, _ = fmt.Fprintf(w, "[FAIL] %s: %v\n", result.Name, result.Err)
+		if result.Hint != "" {
+			_, _ = fmt.Fprintf(w, "       hint: %s\n", result.Hint)
+		}
+	}
+	if failed > 0 {
+		return util.WithExitCode(code, fmt.Errorf("%d of %d checks failed", failed, len(results)))
+	}
+	return nil
+}
+
+// checkRuntime verifies that provider's runtime is installed and, for apple container, that it's a supported release.
+func checkRuntime(ctx context.Context, provider p42runtime.Provider) error {
+	if err := p42runtime.CheckAvailable(provider); err != nil {
+		return util.WithExitCode(util.ExitCodeRuntimeNotInstalled, err)
+	}
+	if containerProvider, ok := provider.(*apple.Provider); ok {
+		if _, err := containerProvider.CheckVersion(ctx); err != nil {
+			return util.WithExitCode(util.ExitCodeRuntimeNotInstalled, err)
+		}
+	}
+	return nil
+}
+
+// checkGithubConnection verifies that the token for cnn authenticates with github.
+func checkGithubConnection(ctx context.Context, cnn *config.GithubInfo) error {
+	if cnn == nil {
+		return errors.New("empty connection config")
+	}
+	client, err := github.NewClient(cnn.Token, cnn.URL, github.WithGraphQLURL(cnn.GraphQLURL))
+	if err != nil {
+		return err
+	}
+	user, _, err := client.GetCurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("github authentication failed: %w", err)
+	}
+	if user.GetLogin() == "" {
+		return errors.New("github did not return the authenticated user")
+	}
+	return nil
+}
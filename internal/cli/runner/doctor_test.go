@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDoctorReport(t *testing.T) {
+	testCases := []struct {
+		name     string
+		results  []DoctorResult
+		wantErr  string
+		wantCode util.ExitCode
+	}{
+		{
+			name:    "all pass",
+			results: []DoctorResult{{Name: "runner token"}, {Name: "server"}},
+		},
+		{
+			name: "mixed",
+			results: []DoctorResult{
+				{Name: "runner token"},
+				{Name: "podman runtime", Err: util.WithExitCode(util.ExitCodeRuntimeNotInstalled, errors.New("not installed"))},
+				{Name: "server", Err: util.WithExitCode(util.ExitCodeUnreachable, errors.New("connection refused"))},
+				{Name: "github connection work"},
+			},
+			wantErr:  "2 of 4 checks failed",
+			wantCode: util.ExitCodeRuntimeNotInstalled,
+		},
+		{
+			name: "no exit code",
+			results: []DoctorResult{
+				{Name: "github connection work", Err: errors.New("bad credentials")},
+				{Name: "server", Err: util.WithExitCode(util.ExitCodeUnreachable, errors.New("connection refused"))},
+			},
+			wantErr:  "2 of 2 checks failed",
+			wantCode: util.ExitCodeConfig,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := WriteDoctorReport(&out, tc.results)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				require.NotContains(t, out.String(), "[FAIL]")
+				return
+			}
+			require.EqualError(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCode, util.ExitCodeOf(err, -1))
+		})
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	var ran []string
+	check := func(name string, err error) DoctorCheck {
+		return DoctorCheck{
+			Name: name,
+			Hint: "fix " + name,
+			Run: func(_ context.Context) error {
+				ran = append(ran, name)
+				return err
+			},
+		}
+	}
+	results := RunDoctor(t.Context(), []DoctorCheck{
+		check("runner token", errors.New("invalid token")),
+		check("server", nil),
+	})
+	require.Equal(t, []string{"runner token", "server"}, ran)
+
+	var out bytes.Buffer
+	require.Error(t, WriteDoctorReport(&out, results))
+	require.Equal(
+		t,
+		"[FAIL] runner token: invalid token\n       hint: fix runner token\n[PASS] server\n",
+		out.String(),
+	)
+}
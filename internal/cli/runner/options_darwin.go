@@ -2,26 +2,31 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/p42runtime/apple"
 	"github.com/plan42-ai/cli/internal/p42runtime/podman"
 	"github.com/plan42-ai/cli/internal/poller"
+	"github.com/plan42-ai/cli/internal/util"
 )
 
 const runnerAgentLabel = "ai.plan42.runner"
 
 type PlatformOptions struct {
-	ContainerPath string              `help:"Path to the container executable" default:"/opt/homebrew/bin/container"`
-	PodmanPath    string              `help:"Path to the podman executable" default:"podman"`
-	Provider      p42runtime.Provider `kong:"-"`
-	runtime       string
+	ContainerPath string `help:"Path to the container executable" default:"/opt/homebrew/bin/container"`
+	PodmanPath    string `help:"Path to the podman executable" default:"podman"`
+	// ContainerStartTimeout bounds `container system start`, which hangs if the container service does.
+	ContainerStartTimeout time.Duration       `help:"How long to wait for 'container system start' to finish." default:"60s"`
+	Provider              p42runtime.Provider `kong:"-"`
+	runtime               string
 }
 
 func (p *PlatformOptions) PollerOptions(options []poller.Option) []poller.Option {
@@ -33,7 +38,7 @@ func (p *PlatformOptions) PollerOptions(options []poller.Option) []poller.Option
 	return options
 }
 
-func (p *PlatformOptions) SetupRuntime(runtimeName string) error {
+func (p *PlatformOptions) SetupRuntime(runtimeName string, registryAuth p42runtime.RegistryAuth) error {
 	logDir, err := runnerLogDir()
 	if err != nil {
 		return fmt.Errorf("failed to determine log directory: %w", err)
@@ -42,9 +47,9 @@ func (p *PlatformOptions) SetupRuntime(runtimeName string) error {
 	p.runtime = runtimeName
 	switch runtimeName {
 	case p42runtime.RuntimeApple:
-		p.Provider = apple.NewProvider(p.ContainerPath, logDir)
+		p.Provider = apple.NewProvider(p.ContainerPath, logDir, apple.WithRegistryAuth(registryAuth))
 	case p42runtime.RuntimePodman:
-		p.Provider = podman.NewProvider(p.PodmanPath, logDir)
+		p.Provider = podman.NewProvider(p.PodmanPath, logDir, podman.WithRegistryAuth(registryAuth))
 	default:
 		return fmt.Errorf("unsupported runtime: %s", runtimeName)
 	}
@@ -60,22 +65,56 @@ func (p *PlatformOptions) Init(ctx context.Context) error {
 	switch p.runtime {
 	case p42runtime.RuntimePodman:
 		if !p.Provider.IsInstalled() {
-			return fmt.Errorf("podman is not installed on the local runner; update the [runner] runtime in the config or install podman")
+			return util.WithExitCode(
+				util.ExitCodeRuntimeNotInstalled,
+				fmt.Errorf("podman is not installed on the local runner; update the [runner] runtime in the config or install podman"),
+			)
 		}
 		return nil
 	default:
 		if !p.Provider.IsInstalled() {
-			return fmt.Errorf("apple container runtime is not installed on the local runner; update the [runner] runtime or install the Apple runtime")
+			return util.WithExitCode(
+				util.ExitCodeRuntimeNotInstalled,
+				fmt.Errorf("apple container runtime is not installed on the local runner; update the [runner] runtime or install the Apple runtime"),
+			)
 		}
-		slog.InfoContext(ctx, "running `container system start`", "container_path", p.ContainerPath)
-		// #nosec G204: ContainerPath is user-configurable and validated separately.
-		cmd := exec.CommandContext(ctx, p.ContainerPath, "system", "start")
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Errorf("container system start failed: %w: %s", err, strings.TrimSpace(string(output)))
+		if appleProvider, ok := p.Provider.(*apple.Provider); ok {
+			v, err := appleProvider.CheckVersion(ctx)
+			if err != nil {
+				return util.WithExitCode(util.ExitCodeRuntimeNotInstalled, err)
+			}
+			slog.InfoContext(ctx, "detected apple container runtime", "version", v.String())
 		}
-		return nil
+		return util.WithExitCode(util.ExitCodeStartup, startContainerSystem(ctx, p.ContainerPath, p.ContainerStartTimeout))
+	}
+}
+
+// startContainerSystem runs `container system start`, giving up after timeout. A timeout of zero waits indefinitely.
+func startContainerSystem(ctx context.Context, containerPath string, timeout time.Duration) error {
+	slog.InfoContext(ctx, "running `container system start`", "container_path", containerPath, "timeout", timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// #nosec G204: ContainerPath is user-configurable and validated separately.
+	cmd := exec.CommandContext(ctx, containerPath, "system", "start")
+	// don't wait on the output of children that outlive a killed `container`.
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("container system start timed out after %s; check that the container service is healthy", timeout)
 	}
+	if err != nil {
+		return fmt.Errorf("container system start failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// PrePullImages pulls images with the configured runtime, so they're cached before jobs arrive.
+func (p *PlatformOptions) PrePullImages(ctx context.Context, images []string) {
+	p42runtime.PrePullImages(ctx, p.Provider, images)
 }
 
 func runnerLogDir() (string, error) {
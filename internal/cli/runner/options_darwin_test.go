@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeContainerBinary writes a stand-in for the container cli that runs script for every command.
+func fakeContainerBinary(t *testing.T, script string) string {
+	t.Helper()
+	binPath := filepath.Join(t.TempDir(), "container")
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte("#!/bin/sh\n"+script+"\n"), 0o755))
+	return binPath
+}
+
+func TestStartContainerSystem(t *testing.T) {
+	require.NoError(t, startContainerSystem(t.Context(), fakeContainerBinary(t, "exit 0"), time.Second))
+
+	err := startContainerSystem(t.Context(), fakeContainerBinary(t, "echo boom >&2; exit 1"), time.Second)
+	require.ErrorContains(t, err, "container system start failed")
+	require.ErrorContains(t, err, "boom")
+}
+
+func TestStartContainerSystemTimeout(t *testing.T) {
+	start := time.Now()
+	err := startContainerSystem(t.Context(), fakeContainerBinary(t, "sleep 10"), 100*time.Millisecond)
+	require.ErrorContains(t, err, "container system start timed out after 100ms")
+	require.Less(t, time.Since(start), 5*time.Second)
+}
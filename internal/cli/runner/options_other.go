@@ -5,6 +5,7 @@ package runner
 import (
 	"context"
 
+	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/poller"
 )
 
@@ -19,7 +20,12 @@ func (p *PlatformOptions) Init(_ context.Context) error {
 	return nil
 }
 
-func (p *PlatformOptions) SetupRuntime(runtimeName string) error {
+func (p *PlatformOptions) PrePullImages(_ context.Context, images []string) {
+	_ = images
+}
+
+func (p *PlatformOptions) SetupRuntime(runtimeName string, registryAuth p42runtime.RegistryAuth) error {
 	_ = runtimeName
+	_ = registryAuth
 	return nil
 }
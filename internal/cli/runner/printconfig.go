@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"maps"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+// redacted replaces secrets that don't look like tokens, such as registry passwords, in the printed config.
+const redacted = "****"
+
+// WriteConfig writes the effective config, after the token file and endpoint derivation have been applied, to w as
+// TOML. Tokens and passwords are redacted.
+func (o *Options) WriteConfig(w io.Writer) error {
+	cfg := redactConfig(o.Config)
+	_, _ = fmt.Fprintf(w, "# effective config loaded from %s\n", o.ConfigFile)
+	err := toml.NewEncoder(w).Encode(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return nil
+}
+
+// redactConfig returns a copy of cfg with its secrets redacted. cfg is not modified.
+func redactConfig(cfg config.Config) config.Config {
+	cfg.Runner.RunnerToken = redactSecret(cfg.Runner.RunnerToken)
+
+	registries := maps.Clone(cfg.Runner.Registries)
+	for host, auth := range registries {
+		if auth == nil {
+			continue
+		}
+		registries[host] = &config.RegistryAuth{Username: auth.Username, Password: redactSecret(auth.Password)}
+	}
+	cfg.Runner.Registries = registries
+
+	github := maps.Clone(cfg.Github)
+	for name, cnn := range github {
+		if cnn == nil {
+			continue
+		}
+		c := *cnn
+		c.Token = redactSecret(c.Token)
+		github[name] = &c
+	}
+	cfg.Github = github
+	return cfg
+}
+
+// redactSecret masks secret. A secret that is a single recognizable token keeps its last 4 characters visible, like
+// tokens in the logs, so it can be told apart from others. Anything else is masked entirely.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	masked := util.RedactToken(secret)
+	if masked == redacted || (len(secret) > 4 && masked == redacted+secret[len(secret)-4:]) {
+		return masked
+	}
+	return redacted
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintConfig(t *testing.T) {
+	token := testRunnerToken(t, map[string]any{"iss": "https://api.plan42.ai", "sub": "tenant-123"})
+	o := Options{
+		ConfigFile: "/home/user/.config/plan42-runner.toml",
+		Config: config.Config{
+			Runner: config.Runner{
+				URL:         "https://api.plan42.ai",
+				RunnerToken: token,
+				Runtime:     "podman",
+				Registries: map[string]*config.RegistryAuth{
+					"ghcr.io": {Username: "robot", Password: "hunter2-password"},
+				},
+			},
+			Github: map[string]*config.GithubInfo{
+				"github": {Name: "github", URL: "https://github.com", ConnectionID: "connection-123", Token: "ghp_abcdefghijklmnop1234"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, o.WriteConfig(&buf))
+	out := buf.String()
+	require.NotContains(t, out, token)
+	require.NotContains(t, out, "hunter2-password")
+	require.NotContains(t, out, "ghp_abcdefghijklmnop1234")
+
+	var printed config.Config
+	require.NoError(t, toml.Unmarshal(buf.Bytes(), &printed))
+	require.Equal(t, "https://api.plan42.ai", printed.Runner.URL)
+	require.Equal(t, "podman", printed.Runner.Runtime)
+	require.Equal(t, "****"+token[len(token)-4:], printed.Runner.RunnerToken)
+	require.Equal(t, "****", printed.Runner.Registries["ghcr.io"].Password)
+	require.Equal(t, "robot", printed.Runner.Registries["ghcr.io"].Username)
+	require.Equal(t, "****1234", printed.Github["github"].Token)
+	require.Equal(t, "connection-123", printed.Github["github"].ConnectionID)
+
+	// the options' config is left untouched.
+	require.Equal(t, token, o.Config.Runner.RunnerToken)
+	require.Equal(t, "hunter2-password", o.Config.Runner.Registries["ghcr.io"].Password)
+	require.Equal(t, "ghp_abcdefghijklmnop1234", o.Config.Github["github"].Token)
+}
@@ -4,13 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
 	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/docker"
+	"github.com/plan42-ai/cli/internal/p42client"
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/poller"
+	"github.com/plan42-ai/cli/internal/runnertoken"
 	"github.com/plan42-ai/cli/internal/util"
 	"github.com/plan42-ai/sdk-go/p42"
 )
@@ -20,25 +29,64 @@ type Options struct {
 	Ctx           context.Context               `kong:"-"`
 	Client        *p42.Client                   `kong:"-"`
 	Config        config.Config                 `kong:"-"`
-	ConfigFile    string                        `help:"Path to config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	ConfigFile    string                        `help:"Path to config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 	ConnectionIdx map[string]*config.GithubInfo `kong:"-"` // indexes github config based on connection id.
+
+	EndpointFromToken bool   `help:"Derive the endpoint URL from the runner token issuer when the config does not specify one."`
+	StateFile         string `help:"Path to the runner state file. Defaults to the config file path with a .state.json extension." optional:""`
+	TokenFile         string `help:"Read the runner token from this file instead of the config file. Use - to read it from stdin." optional:""`
+	Once              bool   `help:"Process a single batch of messages and exit. Useful for CI and testing."`
+
+	RequireConnections bool `help:"Refuse to start if the config has no github connections."`
+	PrintConfig        bool `help:"Print the effective config, with secrets redacted, and exit." name:"print-config"`
+	ForegroundLogs     bool `help:"Also write agent container output to stderr, each line prefixed with the job ID. For debugging." name:"foreground-logs"`
+
+	AgentTimeout        time.Duration `kong:"-"` // parsed from Config.Runner.AgentTimeout.
+	KeyRotationInterval time.Duration `kong:"-"` // parsed from Config.Runner.KeyRotationInterval.
+	PrepullImages       []string      `kong:"-"` // Config.Runner.PrepullImages, checked against the allowlist and fully qualified.
 }
 
 func (o *Options) PollerOptions() []poller.Option {
 	ret := []poller.Option{
 		poller.WithConnectionIdx(o.ConnectionIdx),
+		poller.WithAgentTimeout(o.AgentTimeout),
+		poller.WithKeyRotationInterval(o.KeyRotationInterval),
+		poller.WithKeepContainers(o.Config.Runner.KeepContainers),
+		poller.WithMinFreeDiskBytes(uint64(o.Config.Runner.MinFreeDiskMB) * p42runtime.BytesPerMB),
+		poller.WithLogRotation(o.Config.Runner.MaxLogSize, o.Config.Runner.MaxLogFiles),
+		poller.WithGithubRateLimit(o.githubRequestsPerSecond(), o.Config.Runner.GithubBurst),
+		poller.WithMaxConcurrentMessages(o.Config.Runner.MaxConcurrentMessages),
+		poller.WithAllowedImages(o.Config.Runner.AllowedImages),
+		poller.WithDefaultRegistry(o.Config.Runner.DefaultRegistry),
+		poller.WithStateFile(o.StateFile),
+	}
+	if o.Once {
+		ret = append(ret, poller.WithOnce())
+	}
+	if o.ForegroundLogs {
+		ret = append(ret, poller.WithForegroundLogs(os.Stderr))
 	}
 	ret = o.PlatformOptions.PollerOptions(ret)
 	return ret
 }
 
+// githubRequestsPerSecond returns the rate GitHub API calls are limited to for each github connection, which is the
+// poller's default unless the config sets one.
+func (o *Options) githubRequestsPerSecond() float64 {
+	if o.Config.Runner.GithubRequestsPerSecond == nil {
+		return poller.DefaultGithubRequestsPerSecond
+	}
+	return *o.Config.Runner.GithubRequestsPerSecond
+}
+
 func (o *Options) Process() error {
 	var err error
-	if o.ConfigFile == "" {
-		o.ConfigFile, err = util.DefaultRunnerConfigFileName()
-		if err != nil {
-			return fmt.Errorf("failed to determine default config file path: %w", err)
-		}
+	o.ConfigFile, err = util.ResolveRunnerConfigFileName(o.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to determine default config file path: %w", err)
+	}
+	if o.StateFile == "" {
+		o.StateFile = strings.TrimSuffix(o.ConfigFile, filepath.Ext(o.ConfigFile)) + ".state.json"
 	}
 
 	f, err := os.Open(o.ConfigFile)
@@ -50,28 +98,92 @@ func (o *Options) Process() error {
 	decoder := toml.NewDecoder(f)
 	err = decoder.Decode(&o.Config)
 	if err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+		return fmt.Errorf("failed to parse config file: %w", util.RedactError(err))
+	}
+
+	err = o.loadTokenFile(os.Stdin)
+	if err != nil {
+		return err
 	}
 
 	if o.Config.Runner.RunnerToken == "" {
 		return errors.New("runner token not specified")
 	}
 
-	if o.Config.Runner.URL == "" {
-		return errors.New("endpoint URL not specified")
+	_, err = runnertoken.Parse(o.Config.Runner.RunnerToken)
+	if err != nil {
+		return util.WithExitCode(util.ExitCodeToken, err)
 	}
 
-	runtimeName := normalizeRuntime(o.Config.Runner.Runtime)
-	if err := o.SetupRuntime(runtimeName); err != nil {
-		return fmt.Errorf("failed to configure runtime: %w", err)
+	err = o.resolveEndpoint()
+	if err != nil {
+		return err
+	}
+
+	err = o.checkConnections()
+	if err != nil {
+		return err
+	}
+	WarnIfInsecure(o.Config.Runner)
+
+	o.AgentTimeout, err = parseDurationSetting("agent_timeout", o.Config.Runner.AgentTimeout)
+	if err != nil {
+		return err
+	}
+
+	o.KeyRotationInterval, err = parseDurationSetting("key_rotation_interval", o.Config.Runner.KeyRotationInterval)
+	if err != nil {
+		return err
 	}
 
-	clientOptions := []p42.Option{
-		p42.WithAPIToken(o.Config.Runner.RunnerToken),
+	if o.Config.Runner.MinFreeDiskMB < 0 {
+		return fmt.Errorf("invalid min_free_disk_mb %d: must not be negative", o.Config.Runner.MinFreeDiskMB)
+	}
+	if o.Config.Runner.MaxLogSize < 0 {
+		return fmt.Errorf("invalid max_log_size %d: must not be negative", o.Config.Runner.MaxLogSize)
+	}
+	if o.Config.Runner.MaxLogFiles < 0 {
+		return fmt.Errorf("invalid max_log_files %d: must not be negative", o.Config.Runner.MaxLogFiles)
+	}
+	if o.Config.Runner.MaxConcurrentMessages < 0 {
+		return fmt.Errorf("invalid max_concurrent_messages %d: must not be negative", o.Config.Runner.MaxConcurrentMessages)
+	}
+	if rps := o.Config.Runner.GithubRequestsPerSecond; rps != nil && *rps < 0 {
+		return fmt.Errorf("invalid github_requests_per_second %v: must not be negative", *rps)
+	}
+	if o.Config.Runner.GithubBurst < 0 {
+		return fmt.Errorf("invalid github_burst %d: must not be negative", o.Config.Runner.GithubBurst)
+	}
+
+	if o.Config.Runner.DefaultRegistry != "" {
+		if _, err := p42runtime.ParseRegistryHost(o.Config.Runner.DefaultRegistry); err != nil {
+			return fmt.Errorf("invalid default_registry: %w", err)
+		}
+	}
+
+	o.PrepullImages, err = resolvePrepullImages(o.Config.Runner)
+	if err != nil {
+		return err
+	}
+
+	registryAuth, err := registryAuth(o.Config.Runner.Registries)
+	if err != nil {
+		return err
 	}
 
-	if o.Config.Runner.SkipSSLVerify {
-		clientOptions = append(clientOptions, p42.WithInsecureSkipVerify())
+	// The config is only printed, so there's no need to set up the runtime.
+	if o.PrintConfig {
+		return nil
+	}
+
+	runtimeName := p42runtime.NormalizeRuntime(o.Config.Runner.Runtime)
+	if err := o.SetupRuntime(runtimeName, registryAuth); err != nil {
+		return fmt.Errorf("failed to configure runtime: %w", err)
+	}
+
+	clientOptions, err := p42client.Options(o.Config.Runner)
+	if err != nil {
+		return err
 	}
 
 	o.Ctx = context.Background()
@@ -90,10 +202,192 @@ func (o *Options) Process() error {
 	return nil
 }
 
-func normalizeRuntime(runtimeName string) string {
-	runtimeName = strings.ToLower(strings.TrimSpace(runtimeName))
-	if runtimeName == "" {
-		return p42runtime.RuntimeApple
+// loadTokenFile sets the runner token from TokenFile, if it's set. A path of "-" reads the token from stdin. The
+// token may be set in the config file or with TokenFile, but not both.
+func (o *Options) loadTokenFile(stdin io.Reader) error {
+	if o.TokenFile == "" {
+		return nil
+	}
+	if o.Config.Runner.RunnerToken != "" {
+		return errors.New("runner token is set in both the config file and --token-file")
+	}
+
+	var data []byte
+	var err error
+	if o.TokenFile == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		warnIfWorldReadable(o.TokenFile)
+		data, err = os.ReadFile(o.TokenFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	o.Config.Runner.RunnerToken = strings.TrimSpace(string(data))
+	if o.Config.Runner.RunnerToken == "" {
+		return fmt.Errorf("token file %s is empty", o.TokenFile)
+	}
+	return nil
+}
+
+// warnIfWorldReadable logs a warning if other users can read the file at path. Windows doesn't report POSIX
+// permissions, so the check is skipped there.
+func warnIfWorldReadable(path string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.Mode().Perm()&0o004 != 0 {
+		slog.Warn("token file is world readable, consider restricting it with chmod 600", "path", path)
+	}
+}
+
+// LogStartupSummary logs the effective configuration of the runner in a single line, so it's easy to tell which
+// environment and runtime it's using. Secrets, such as the runner and github tokens, are never logged.
+func (o *Options) LogStartupSummary(ctx context.Context, tenantID string, runnerID string) {
+	slog.InfoContext(
+		ctx,
+		"runner starting",
+		"endpoint", o.Config.Runner.URL,
+		"tenantID", tenantID,
+		"runnerID", runnerID,
+		"runtime", p42runtime.NormalizeRuntime(o.Config.Runner.Runtime),
+		"githubConnections", len(o.Config.Github),
+		"initialQueues", poller.InitialQueueCount,
+		"logLevel", logLevel(ctx),
+		"skipSSLVerify", o.Config.Runner.SkipSSLVerify,
+		"agentTimeout", o.AgentTimeout,
+		"keyRotationInterval", o.KeyRotationInterval,
+		"keepContainers", o.Config.Runner.KeepContainers,
+		"allowedImages", len(o.Config.Runner.AllowedImages),
+		"once", o.Once,
+	)
+}
+
+// logLevel returns the lowest level enabled by the default logger.
+func logLevel(ctx context.Context) slog.Level {
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+		if slog.Default().Enabled(ctx, level) {
+			return level
+		}
+	}
+	return slog.LevelError
+}
+
+// registryAuth converts the registry credentials in the config to the form used by runtime providers.
+func registryAuth(registries map[string]*config.RegistryAuth) (p42runtime.RegistryAuth, error) {
+	ret := make(p42runtime.RegistryAuth)
+	for host, auth := range registries {
+		if auth == nil || auth.Username == "" || auth.Password == "" {
+			return nil, fmt.Errorf("registry %q must specify a username and password", host)
+		}
+		ret[host] = p42runtime.RegistryCredentials{Username: auth.Username, Password: auth.Password}
+	}
+	err := ret.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// resolvePrepullImages checks the images in prepull_images against allowed_images and returns them fully qualified,
+// as agents run them, so the pulled images are the ones jobs use.
+func resolvePrepullImages(cfg config.Runner) ([]string, error) {
+	ret := make([]string, 0, len(cfg.PrepullImages))
+	for _, image := range cfg.PrepullImages {
+		if _, err := docker.ParseImageURI(image); err != nil {
+			return nil, fmt.Errorf("invalid image %q in prepull_images: %w", image, err)
+		}
+		resolved, err := poller.ResolveAgentImage(image, cfg.AllowedImages, cfg.DefaultRegistry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prepull_images: %w", err)
+		}
+		ret = append(ret, resolved)
+	}
+	return ret, nil
+}
+
+// parseDurationSetting parses a duration config value, such as agent_timeout. An empty value parses as 0, which
+// means the setting is disabled.
+func parseDurationSetting(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", name, value)
+	}
+	return d, nil
+}
+
+// checkConnections warns if the config has no github connections, since every github backed operation will fail
+// with an unknown connection id. If RequireConnections is set, it returns an error instead.
+func (o *Options) checkConnections() error {
+	if len(o.Config.Github) > 0 {
+		return nil
+	}
+	if o.RequireConnections {
+		return errors.New("no github connections configured. Run `plan42 runner config` to add one")
+	}
+	slog.Warn("no github connections configured; github operations will fail until one is added with `plan42 runner config`")
+	return nil
+}
+
+// resolveEndpoint makes sure an endpoint URL is configured. If the config does not specify one and EndpointFromToken
+// is set, the endpoint is derived from the issuer claim of the runner token. If the config does specify one, a warning
+// is logged when its host does not match the issuer host, since that usually means the token belongs to a different
+// environment.
+func (o *Options) resolveEndpoint() error {
+	issuer, issuerErr := tokenIssuer(o.Config.Runner.RunnerToken)
+
+	if o.Config.Runner.URL == "" {
+		if !o.EndpointFromToken {
+			return errors.New("endpoint URL not specified")
+		}
+		if issuerErr != nil {
+			return fmt.Errorf("unable to derive endpoint URL from runner token: %w", issuerErr)
+		}
+		o.Config.Runner.URL = issuer.String()
+		slog.Info("derived endpoint URL from runner token", "url", o.Config.Runner.URL)
+		return nil
+	}
+
+	endpoint, err := url.Parse(o.Config.Runner.URL)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint URL: %w", err)
+	}
+
+	// Malformed tokens are reported when the token params are extracted, so there is nothing to compare against here.
+	if issuerErr == nil && !strings.EqualFold(endpoint.Hostname(), issuer.Hostname()) {
+		slog.Warn(
+			"endpoint URL host does not match runner token issuer",
+			"url", o.Config.Runner.URL,
+			"issuer", issuer.String(),
+		)
+	}
+	return nil
+}
+
+// tokenIssuer returns the scheme and host of the issuer claim in a runner token.
+func tokenIssuer(token string) (*url.URL, error) {
+	claims, err := runnertoken.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	issuer := claims.Issuer
+	if issuer == nil || issuer.Host == "" {
+		return nil, errors.New("runner token does not specify an issuer")
+	}
+	scheme := issuer.Scheme
+	if scheme == "" {
+		scheme = "https"
 	}
-	return runtimeName
+	return &url.URL{Scheme: scheme, Host: issuer.Host}, nil
 }
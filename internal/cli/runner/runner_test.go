@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/poller"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func testRunnerToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{"alg": "ES256", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	enc := base64.RawURLEncoding
+	return "p42r_" + enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + "." + enc.EncodeToString([]byte("sig"))
+}
+
+func TestResolveEndpointFromTokenIssuer(t *testing.T) {
+	token := testRunnerToken(t, map[string]any{
+		"iss":       "https://api.dev.plan42.ai/some/path",
+		"sub":       "tenant-123",
+		"runner_id": "runner-123",
+	})
+
+	o := Options{
+		Config:            config.Config{Runner: config.Runner{RunnerToken: token}},
+		EndpointFromToken: true,
+	}
+	require.NoError(t, o.resolveEndpoint())
+	require.Equal(t, "https://api.dev.plan42.ai", o.Config.Runner.URL)
+}
+
+func TestResolveEndpointRequiresURLWithoutFlag(t *testing.T) {
+	token := testRunnerToken(t, map[string]any{"iss": "https://api.dev.plan42.ai", "sub": "tenant-123"})
+
+	o := Options{Config: config.Config{Runner: config.Runner{RunnerToken: token}}}
+	require.EqualError(t, o.resolveEndpoint(), "endpoint URL not specified")
+}
+
+func TestResolveEndpointFromTokenWithoutIssuer(t *testing.T) {
+	token := testRunnerToken(t, map[string]any{"sub": "tenant-123"})
+
+	o := Options{
+		Config:            config.Config{Runner: config.Runner{RunnerToken: token}},
+		EndpointFromToken: true,
+	}
+	require.Error(t, o.resolveEndpoint())
+	require.Empty(t, o.Config.Runner.URL)
+}
+
+func TestResolveEndpointKeepsExplicitURL(t *testing.T) {
+	token := testRunnerToken(t, map[string]any{"iss": "https://api.dev.plan42.ai", "sub": "tenant-123"})
+
+	o := Options{
+		Config: config.Config{Runner: config.Runner{
+			RunnerToken: token,
+			URL:         "https://api.plan42.ai",
+		}},
+		EndpointFromToken: true,
+	}
+	require.NoError(t, o.resolveEndpoint())
+	require.Equal(t, "https://api.plan42.ai", o.Config.Runner.URL)
+}
+
+func TestProcessMissingConfigIsConfigError(t *testing.T) {
+	o := Options{ConfigFile: filepath.Join(t.TempDir(), "missing.toml")}
+	err := o.Process()
+	require.Error(t, err)
+	require.Equal(t, util.ExitCodeConfig, util.ExitCodeOf(err, util.ExitCodeConfig))
+}
+
+func TestParseDurationSetting(t *testing.T) {
+	d, err := parseDurationSetting("agent_timeout", "")
+	require.NoError(t, err)
+	require.Zero(t, d)
+
+	d, err = parseDurationSetting("agent_timeout", "90m")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, d)
+
+	_, err = parseDurationSetting("agent_timeout", "soon")
+	require.ErrorContains(t, err, "agent_timeout")
+
+	_, err = parseDurationSetting("agent_timeout", "-1h")
+	require.Error(t, err)
+}
+
+func TestGithubRequestsPerSecond(t *testing.T) {
+	testCases := map[string]float64{
+		"":                                 poller.DefaultGithubRequestsPerSecond,
+		"github_requests_per_second = 0":   0,
+		"github_requests_per_second = 2.5": 2.5,
+	}
+	for setting, expected := range testCases {
+		var o Options
+		require.NoError(t, toml.Unmarshal([]byte("[runner]\n"+setting+"\n"), &o.Config))
+		require.InDelta(t, expected, o.githubRequestsPerSecond(), 0, setting)
+	}
+}
+
+func TestResolvePrepullImages(t *testing.T) {
+	cfg := config.Runner{
+		PrepullImages:   []string{"plan42-ai/agent:1.2.3", "ghcr.io/plan42-ai/agent"},
+		DefaultRegistry: "registry.example.com",
+	}
+	images, err := resolvePrepullImages(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"registry.example.com/plan42-ai/agent:1.2.3", "ghcr.io/plan42-ai/agent:latest"}, images)
+
+	cfg.AllowedImages = []string{"registry.example.com/plan42-ai/agent:1.2.3"}
+	_, err = resolvePrepullImages(cfg)
+	require.ErrorContains(t, err, "ghcr.io/plan42-ai/agent is not in the runner's allowed_images")
+
+	cfg.PrepullImages = []string{"not a valid image"}
+	_, err = resolvePrepullImages(cfg)
+	require.ErrorContains(t, err, "prepull_images")
+}
+
+func TestCheckConnections(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	o := Options{Config: config.Config{Github: map[string]*config.GithubInfo{}}}
+	require.NoError(t, o.checkConnections())
+	require.Contains(t, buf.String(), "level=WARN")
+	require.Contains(t, buf.String(), "no github connections configured")
+
+	buf.Reset()
+	o.Config.Github["github"] = &config.GithubInfo{Name: "github", ConnectionID: "connection-123"}
+	require.NoError(t, o.checkConnections())
+	require.Empty(t, buf.String())
+
+	o = Options{RequireConnections: true}
+	require.Error(t, o.checkConnections())
+}
+
+func TestLoadTokenFile(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	token := testRunnerToken(t, map[string]any{"iss": "https://api.plan42.ai", "sub": "tenant-123"})
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte(token+"\n"), 0o600))
+
+	o := Options{TokenFile: path}
+	require.NoError(t, o.loadTokenFile(strings.NewReader("")))
+	require.Equal(t, token, o.Config.Runner.RunnerToken)
+	require.Empty(t, buf.String())
+
+	require.NoError(t, os.Chmod(path, 0o644))
+	o = Options{TokenFile: path}
+	require.NoError(t, o.loadTokenFile(strings.NewReader("")))
+	require.Equal(t, token, o.Config.Runner.RunnerToken)
+	if runtime.GOOS != "windows" {
+		require.Contains(t, buf.String(), "token file is world readable")
+	}
+
+	o = Options{TokenFile: "-"}
+	require.NoError(t, o.loadTokenFile(strings.NewReader(token)))
+	require.Equal(t, token, o.Config.Runner.RunnerToken)
+
+	o = Options{TokenFile: path, Config: config.Config{Runner: config.Runner{RunnerToken: token}}}
+	require.Error(t, o.loadTokenFile(strings.NewReader("")))
+
+	o = Options{TokenFile: "-"}
+	require.Error(t, o.loadTokenFile(strings.NewReader(" \n")))
+}
+
+func TestLogStartupSummary(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	token := testRunnerToken(t, map[string]any{"iss": "https://api.plan42.ai", "sub": "tenant-123"})
+	o := Options{
+		Config: config.Config{
+			Runner: config.Runner{URL: "https://api.plan42.ai", RunnerToken: token, Runtime: "Podman"},
+			Github: map[string]*config.GithubInfo{
+				"github": {Name: "github", ConnectionID: "connection-123", Token: "ghp_secretgithubtoken"},
+			},
+		},
+		AgentTimeout: 2 * time.Hour,
+	}
+	o.LogStartupSummary(t.Context(), "tenant-123", "runner-123")
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="runner starting"`,
+		"endpoint=https://api.plan42.ai",
+		"tenantID=tenant-123",
+		"runnerID=runner-123",
+		"runtime=podman",
+		"githubConnections=1",
+		"initialQueues=1",
+		"logLevel=DEBUG",
+		"agentTimeout=2h0m0s",
+	} {
+		require.Contains(t, out, want)
+	}
+	require.NotContains(t, out, token)
+	require.NotContains(t, out, "p42r_")
+	require.NotContains(t, out, "ghp_secretgithubtoken")
+}
@@ -7,16 +7,14 @@ import (
 )
 
 type Options struct {
-	ConfigFile string `help:"Path to config file. Defaults to ~/.config/plan42-runner.toml" short:"c" optional:""`
+	ConfigFile string `help:"Path to config file. Defaults to $PLAN42_RUNNER_CONFIG or plan42-runner.toml in $XDG_CONFIG_HOME (~/.config)" short:"c" optional:""`
 }
 
 func (o *Options) Process() error {
 	var err error
-	if o.ConfigFile == "" {
-		o.ConfigFile, err = util.DefaultRunnerConfigFileName()
-		if err != nil {
-			return fmt.Errorf("failed to determine default config file path: %w", err)
-		}
+	o.ConfigFile, err = util.ResolveRunnerConfigFileName(o.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to determine default config file path: %w", err)
 	}
 	return nil
 }
@@ -5,6 +5,58 @@ type Runner struct {
 	RunnerToken   string `toml:"token"`
 	SkipSSLVerify bool   `toml:"skip_ssl_verify,omitempty"`
 	Runtime       string `toml:"runtime"`
+
+	// AgentTimeout is the longest an agent container may run, e.g. "2h". Empty means no limit.
+	AgentTimeout string `toml:"agent_timeout,omitempty"`
+
+	// KeyRotationInterval is how often queue keys are rotated, e.g. "24h". Empty disables rotation.
+	KeyRotationInterval string `toml:"key_rotation_interval,omitempty"`
+
+	// KeepContainers keeps agent containers after they exit so they can be inspected. They must be removed manually.
+	KeepContainers bool `toml:"keep_containers,omitempty"`
+
+	// MinFreeDiskMB is the disk space, in megabytes, that must be free where job logs are written for a job to start.
+	// 0 disables the check.
+	MinFreeDiskMB int `toml:"min_free_disk_mb,omitempty"`
+
+	// MaxLogSize is the size, in bytes, at which an agent job's log is rolled over to {jobID}.1, {jobID}.2, and so on,
+	// keeping MaxLogFiles (default 5) of them. 0 disables rotation.
+	MaxLogSize  int64 `toml:"max_log_size,omitempty"`
+	MaxLogFiles int   `toml:"max_log_files,omitempty"`
+
+	// MaxConcurrentMessages bounds the number of messages processed at once across all queues. 0 uses the default.
+	MaxConcurrentMessages int `toml:"max_concurrent_messages,omitempty"`
+
+	// GithubRequestsPerSecond limits the GitHub API calls made for each github connection, allowing bursts of up to
+	// GithubBurst calls. Unset uses the default limit, and 0 disables it. A GithubBurst of 0 uses the default burst.
+	GithubRequestsPerSecond *float64 `toml:"github_requests_per_second,omitempty"`
+	GithubBurst             int      `toml:"github_burst,omitempty"`
+
+	// CACertFile is a PEM bundle of additional CAs to trust when connecting to the server.
+	CACertFile string `toml:"ca_cert_file,omitempty"`
+
+	// ClientCertFile and ClientKeyFile are a PEM encoded certificate and key presented to the server for mutual TLS.
+	ClientCertFile string `toml:"client_cert_file,omitempty"`
+	ClientKeyFile  string `toml:"client_key_file,omitempty"`
+
+	// AllowedImages restricts the images agents may run to these image references or digests ("sha256:..."). Empty
+	// allows every image.
+	AllowedImages []string `toml:"allowed_images,omitempty"`
+
+	// DefaultRegistry is the registry host, with an optional ":port", that agent images without a registry are
+	// qualified with. Empty uses docker.io.
+	DefaultRegistry string `toml:"default_registry,omitempty"`
+
+	// PrepullImages are pulled in the background when the runner starts, so the first job doesn't wait for them.
+	PrepullImages []string `toml:"prepull_images,omitempty"`
+
+	// Registries holds credentials for private image registries, keyed by registry host (with an optional ":port").
+	Registries map[string]*RegistryAuth `toml:"registries,omitempty"`
+}
+
+type RegistryAuth struct {
+	Username string `toml:"username"`
+	Password string `toml:"password"`
 }
 
 type GithubInfo struct {
@@ -12,6 +64,9 @@ type GithubInfo struct {
 	URL          string `toml:"url"`
 	ConnectionID string `toml:"connection_id"`
 	Token        string `toml:"token"`
+
+	// GraphQLURL overrides the GraphQL endpoint derived from URL, for proxied GitHub Enterprise setups.
+	GraphQLURL string `toml:"graphql_url,omitempty"`
 }
 
 type Config struct {
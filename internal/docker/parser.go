@@ -13,6 +13,8 @@ var (
 	validRepositoryNameRegex = regexp.MustCompile(`^([a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
 	validPortRegex           = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
 	validTagRegex            = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]*$`)
+	validDigestRegex         = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+	validSHA256DigestRegex   = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
 )
 
 type ImageURI struct {
@@ -20,6 +22,8 @@ type ImageURI struct {
 	RegistryPort *string
 	Repository   string
 	Tag          *string
+	// Digest pins the image to a content digest, e.g. "sha256:...".
+	Digest *string
 }
 
 func (i *ImageURI) MarshalText() (text []byte, err error) {
@@ -37,6 +41,10 @@ func (i *ImageURI) MarshalText() (text []byte, err error) {
 		buf.WriteByte(':')
 		buf.WriteString(*i.Tag)
 	}
+	if i.Digest != nil {
+		buf.WriteByte('@')
+		buf.WriteString(*i.Digest)
+	}
 	return buf.Bytes(), nil
 }
 
@@ -70,8 +78,29 @@ func (i *ImageURI) WithDefaultRegistry(registry *string) *ImageURI {
 	return i
 }
 
+func (i *ImageURI) WithTag(tag *string) *ImageURI {
+	ret := *i
+	ret.Tag = tag
+	return &ret
+}
+
+// WithDefaultTag returns the image with tag if it has neither a tag nor a digest. A digest already identifies the
+// image, so it isn't given a tag.
+func (i *ImageURI) WithDefaultTag(tag *string) *ImageURI {
+	if i != nil && i.Tag == nil && i.Digest == nil && tag != nil {
+		return i.WithTag(tag)
+	}
+	return i
+}
+
 func ParseImageURI(uri string) (*ImageURI, error) {
 	var ret ImageURI
+	// Split off a digest, if any.
+	if name, digest, ok := strings.Cut(uri, "@"); ok {
+		uri = name
+		ret.Digest = &digest
+	}
+
 	// Split the uri by /
 	components := strings.Split(uri, "/")
 	// If the first component contains a . or : then it is a registry name
@@ -97,6 +126,9 @@ func ParseImageURI(uri string) (*ImageURI, error) {
 	if ret.Tag != nil && !validTag(*ret.Tag) {
 		return nil, fmt.Errorf("invalid tag: '%v'", *ret.Tag)
 	}
+	if ret.Digest != nil && !validDigest(*ret.Digest) {
+		return nil, fmt.Errorf("invalid digest: '%v'", *ret.Digest)
+	}
 
 	return &ret, nil
 }
@@ -147,6 +179,15 @@ func combineRepo(elemes []string, lastElem string) string {
 func validTag(s string) bool {
 	return validTagRegex.MatchString(s)
 }
+
+// validDigest reports whether s is an OCI content digest. sha256 digests must be 64 hex characters.
+func validDigest(s string) bool {
+	if !validDigestRegex.MatchString(s) {
+		return false
+	}
+	return !strings.HasPrefix(s, "sha256:") || validSHA256DigestRegex.MatchString(s)
+}
+
 func validPort(s string) bool {
 	if !validPortRegex.MatchString(s) {
 		return false
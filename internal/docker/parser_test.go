@@ -80,6 +80,34 @@ func TestSuccess(t *testing.T) {
 				Tag:        util.Pointer("latest"),
 			},
 		},
+		{
+			name:  "digest",
+			value: "ghcr.io/plan42-ai/agent@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+			expected: docker.ImageURI{
+				Registry:   util.Pointer("ghcr.io"),
+				Repository: "plan42-ai/agent",
+				Digest:     util.Pointer("sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"),
+			},
+		},
+		{
+			name:  "tag and digest",
+			value: "registry.local:5000/agent:1.2.3@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+			expected: docker.ImageURI{
+				Registry:     util.Pointer("registry.local"),
+				RegistryPort: util.Pointer("5000"),
+				Repository:   "agent",
+				Tag:          util.Pointer("1.2.3"),
+				Digest:       util.Pointer("sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"),
+			},
+		},
+		{
+			name:  "digest without registry",
+			value: "ubuntu@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+			expected: docker.ImageURI{
+				Repository: "ubuntu",
+				Digest:     util.Pointer("sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"),
+			},
+		},
 		{
 			name:  "repository with dot",
 			value: "docker.io",
@@ -132,6 +160,16 @@ func TestErrors(t *testing.T) {
 			value:         "ubuntu+=5:latest",
 			expectedError: "invalid repository: 'ubuntu+=5'",
 		},
+		{
+			name:          "short sha256 digest",
+			value:         "ubuntu@sha256:4f53cda1",
+			expectedError: "invalid digest: 'sha256:4f53cda1'",
+		},
+		{
+			name:          "digest without algorithm",
+			value:         "ubuntu@4f53cda18c2baa0c",
+			expectedError: "invalid digest: '4f53cda18c2baa0c'",
+		},
 		{
 			name:          "bad port 1",
 			value:         "docker.io:443a/ubuntu",
@@ -156,3 +194,33 @@ func TestErrors(t *testing.T) {
 		)
 	}
 }
+
+func TestWithDefaultTag(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		value    string
+		tag      *string
+		expected string
+	}{
+		{name: "no tag", value: "docker.io/ubuntu", tag: util.Pointer("latest"), expected: "docker.io/ubuntu:latest"},
+		{name: "tag present", value: "docker.io/ubuntu:24.04", tag: util.Pointer("latest"), expected: "docker.io/ubuntu:24.04"},
+		{name: "registry port", value: "registry.local:5000/ubuntu", tag: util.Pointer("latest"), expected: "registry.local:5000/ubuntu:latest"},
+		{name: "nil default", value: "docker.io/ubuntu", expected: "docker.io/ubuntu"},
+		{name: "digest", value: "docker.io/ubuntu@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945", tag: util.Pointer("latest"), expected: "docker.io/ubuntu@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				t.Parallel()
+				uri, err := docker.ParseImageURI(tc.value)
+				require.NoError(t, err)
+				actual := uri.WithDefaultTag(tc.tag)
+				require.Equal(t, tc.expected, actual.String())
+				// the original is left unchanged.
+				require.Equal(t, tc.value, uri.String())
+			},
+		)
+	}
+}
@@ -15,21 +15,62 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/cli/internal/version"
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
 
 const (
 	DefaultGithubURL        = "https://github.com"
 	defaultGithubGraphqlURL = "https://api.github.com/graphql"
+
+	// DefaultPageSize is the number of items requested per page of a GraphQL query. It is also the maximum GitHub
+	// allows.
+	DefaultPageSize = 100
 )
 
 type Client struct {
 	restClient *ghapi.Client
 	httpClient *http.Client
 	graphqlURL string
+	pageSize   int
+	userAgent  string
+}
+
+type ClientOption func(c *Client)
+
+// WithPageSize sets the number of items requested per page of a GraphQL query. Values above DefaultPageSize are
+// clamped to it, and values less than 1 are ignored.
+func WithPageSize(pageSize int) ClientOption {
+	return func(c *Client) {
+		if pageSize < 1 {
+			return
+		}
+		c.pageSize = min(pageSize, DefaultPageSize)
+	}
+}
+
+// WithGraphQLURL sets the GraphQL endpoint used by the client, for GitHub Enterprise setups that don't serve it at the
+// standard /api/graphql path. An empty endpoint keeps the endpoint derived from the base URL. NewClient fails if
+// endpoint isn't an absolute URL.
+func WithGraphQLURL(endpoint string) ClientOption {
+	return func(c *Client) {
+		if endpoint != "" {
+			c.graphqlURL = endpoint
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent sent on REST and GraphQL requests. It defaults to version.UserAgent(). An empty
+// userAgent keeps the default.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
 }
 
-func NewClient(token string, baseURL string) (*Client, error) {
+func NewClient(token string, baseURL string, options ...ClientOption) (*Client, error) {
 	if token == "" {
 		return nil, fmt.Errorf("missing github token")
 	}
@@ -45,11 +86,34 @@ func NewClient(token string, baseURL string) (*Client, error) {
 		rest = configured
 	}
 
-	return &Client{
+	ret := &Client{
 		restClient: rest,
 		httpClient: httpClient,
-		graphqlURL: graphqlURL(baseURL),
-	}, nil
+		pageSize:   DefaultPageSize,
+		userAgent:  version.UserAgent(),
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	rest.UserAgent = ret.userAgent
+
+	if ret.graphqlURL == "" {
+		ret.graphqlURL = graphqlURL(baseURL)
+	} else if err := validateGraphQLURL(ret.graphqlURL); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func validateGraphQLURL(graphqlURL string) error {
+	parsed, err := url.Parse(graphqlURL)
+	if err != nil {
+		return fmt.Errorf("invalid graphql url %q: %w", graphqlURL, err)
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("invalid graphql url %q: must be an absolute url", graphqlURL)
+	}
+	return nil
 }
 
 func graphqlURL(baseURL string) string {
@@ -82,35 +146,120 @@ func (c *Client) ListBranches(ctx context.Context, owner string, repo string, op
 	return c.restClient.Repositories.ListBranches(ctx, owner, repo, opts)
 }
 
-func (c *Client) GetPRFeedBack(ctx context.Context, org string, repo string, prNum int) ([]messages.PRFeedback, error) {
-	var err error
-	var ret []messages.PRFeedback
+func (c *Client) ListPullRequests(ctx context.Context, owner string, repo string, opts *ghapi.PullRequestListOptions) ([]*ghapi.PullRequest, *ghapi.Response, error) {
+	return c.restClient.PullRequests.List(ctx, owner, repo, opts)
+}
 
-	ret, err = c.getReviewThreadFeedback(ctx, org, repo, prNum, ret)
-	if err != nil {
-		return nil, err
+// ListCollaborators lists the users with access to a repository, for picking reviewers.
+func (c *Client) ListCollaborators(ctx context.Context, owner string, repo string, opts *ghapi.ListCollaboratorsOptions) ([]*ghapi.User, *ghapi.Response, error) {
+	return c.restClient.Repositories.ListCollaborators(ctx, owner, repo, opts)
+}
+
+// PRFeedback is the feedback left on a pull request, along with the head of the PR at the time it was fetched.
+type PRFeedback struct {
+	// HeadRef is the name of the PR's head branch.
+	HeadRef string
+	// HeadSHA is the commit hash of the PR's head branch.
+	HeadSHA string
+	// Feedback is the list of review threads, issue comments, and review comments on the PR.
+	Feedback []messages.PRFeedback
+	// Cursors records how far collection of each feedback source got.
+	Cursors FeedbackCursors
+}
+
+// FeedbackCursors records the position reached in each of the paginated feedback sources of a PR.
+type FeedbackCursors struct {
+	ReviewThreads FeedbackCursor
+	IssueComments FeedbackCursor
+	Reviews       FeedbackCursor
+}
+
+// FeedbackCursor is the position reached in a paginated feedback source.
+type FeedbackCursor struct {
+	// After is the end cursor of the last page that was fully collected. It is empty if no pages have been collected.
+	After string
+	// Done is set once every page has been collected.
+	Done bool
+}
+
+// advance records that a page has been fully collected, and reports whether there are more pages to fetch.
+func (fc *FeedbackCursor) advance(hasNextPage bool, endCursor string) bool {
+	if !hasNextPage {
+		fc.Done = true
+		return false
 	}
+	fc.After = endCursor
+	return true
+}
 
-	ret, err = c.getIssueCommentFeedback(ctx, org, repo, prNum, ret)
-	if err != nil {
-		return nil, err
+type feedbackOptions struct {
+	includeMinimized bool
+}
+
+// FeedbackOption configures how PR feedback is collected.
+type FeedbackOption func(o *feedbackOptions)
+
+// WithIncludeMinimized sets whether minimized (e.g. outdated or resolved) comments are included in the feedback.
+// Minimized comments are included by default.
+func WithIncludeMinimized(include bool) FeedbackOption {
+	return func(o *feedbackOptions) {
+		o.includeMinimized = include
 	}
+}
 
-	ret, err = c.getReviewCommentFeedback(ctx, org, repo, prNum, ret)
-	if err != nil {
-		return nil, err
+func newFeedbackOptions(options []FeedbackOption) feedbackOptions {
+	ret := feedbackOptions{includeMinimized: true}
+	for _, opt := range options {
+		opt(&ret)
 	}
+	return ret
+}
 
-	return ret, nil
+// skip reports whether a comment with the given minimized state should be dropped.
+func (o feedbackOptions) skip(isMinimized bool) bool {
+	return isMinimized && !o.includeMinimized
 }
 
-func (c *Client) getReviewThreadFeedback(ctx context.Context, org string, repo string, prNum int, ret []messages.PRFeedback) ([]messages.PRFeedback, error) {
+// GetPRFeedBack returns the feedback left on a PR. If collection fails part way through, the feedback collected so far
+// is returned along with the error, and can be passed to ResumePRFeedBack to continue from where it left off.
+func (c *Client) GetPRFeedBack(ctx context.Context, org string, repo string, prNum int, options ...FeedbackOption) (*PRFeedback, error) {
+	return c.ResumePRFeedBack(ctx, org, repo, prNum, &PRFeedback{}, options...)
+}
+
+// ResumePRFeedBack continues collecting the feedback on a PR from the cursors recorded in pr, appending to the
+// feedback it already holds. Pages that were fully collected before are not fetched again.
+func (c *Client) ResumePRFeedBack(ctx context.Context, org string, repo string, prNum int, pr *PRFeedback, options ...FeedbackOption) (*PRFeedback, error) {
+	opts := newFeedbackOptions(options)
+
+	sources := []func(context.Context, string, string, int, feedbackOptions, *PRFeedback) error{
+		c.getReviewThreadFeedback,
+		c.getIssueCommentFeedback,
+		c.getReviewCommentFeedback,
+	}
+	for _, source := range sources {
+		if err := source(ctx, org, repo, prNum, opts, pr); err != nil {
+			return pr, err
+		}
+	}
+	return pr, nil
+}
+
+// getReviewThreadFeedback collects the review threads on the PR. The head of the PR is fetched by the same query and
+// recorded on pr.
+func (c *Client) getReviewThreadFeedback(ctx context.Context, org string, repo string, prNum int, opts feedbackOptions, pr *PRFeedback) error {
+	cursor := &pr.Cursors.ReviewThreads
+	if cursor.Done {
+		return nil
+	}
+
 	req := request(
 		reviewThreadQuery,
 		reviewThreadVariables{
-			Owner: org,
-			Name:  repo,
-			PRNum: prNum,
+			Owner:  org,
+			Name:   repo,
+			PRNum:  prNum,
+			First:  c.pageSize,
+			Cursor: cursor.After,
 		},
 	)
 
@@ -119,42 +268,53 @@ func (c *Client) getReviewThreadFeedback(ctx context.Context, org string, repo s
 
 		err := c.queryGraphQL(ctx, &req, &resp)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if req.Variables.Cursor == "" {
+			pr.HeadRef = resp.Data.Repository.PullRequest.HeadRefName
+			pr.HeadSHA = resp.Data.Repository.PullRequest.HeadRefOid
 		}
 
+		var page []messages.PRFeedback
 		for _, thread := range resp.Data.Repository.PullRequest.ReviewThreads.Nodes {
-			comments, err := c.GetThreadComments(ctx, thread.ID)
+			comments, err := c.getThreadComments(ctx, thread.ID, opts)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if len(comments) == 0 {
 				continue
 			}
-			ret = append(ret, messages.PRFeedback{
+			page = append(page, messages.PRFeedback{
 				ID:         thread.ID,
 				IsResolved: thread.IsResolved,
 				Comments:   comments,
 			})
 		}
 
-		if !resp.Data.Repository.PullRequest.ReviewThreads.PageInfo.HasNextPage {
-			break
+		pageInfo := resp.Data.Repository.PullRequest.ReviewThreads.PageInfo
+		pr.Feedback = append(pr.Feedback, page...)
+		if !cursor.advance(pageInfo.HasNextPage, pageInfo.EndCursor) {
+			return nil
 		}
-		req.Variables.Cursor = resp.Data.Repository.PullRequest.ReviewThreads.PageInfo.EndCursor
+		req.Variables.Cursor = cursor.After
 	}
-	return ret, nil
 }
 
-func (c *Client) getIssueCommentFeedback(ctx context.Context, org string, repo string, prNum int, ret []messages.PRFeedback) ([]messages.PRFeedback, error) {
+func (c *Client) getIssueCommentFeedback(ctx context.Context, org string, repo string, prNum int, opts feedbackOptions, pr *PRFeedback) error {
+	cursor := &pr.Cursors.IssueComments
+	if cursor.Done {
+		return nil
+	}
+
 	req := request(
 		issueCommentsQuery,
-		issueCommentVariables{Owner: org, Name: repo, PRNum: prNum},
+		issueCommentVariables{Owner: org, Name: repo, PRNum: prNum, First: c.pageSize, Cursor: cursor.After},
 	)
 
 	for {
 		var resp issueCommentsResponse
 		if err := c.queryGraphQL(ctx, &req, &resp); err != nil {
-			return nil, err
+			return err
 		}
 
 		comments := resp.Data.Repository.PullRequest.Comments
@@ -163,10 +323,10 @@ func (c *Client) getIssueCommentFeedback(ctx context.Context, org string, repo s
 			if comment.Author != nil {
 				user = comment.Author.Login
 			}
-			if isPlan42Comment(user, comment.Body) {
+			if isPlan42Comment(user, comment.Body) || opts.skip(comment.IsMinimized) {
 				continue
 			}
-			ret = append(ret, messages.PRFeedback{
+			pr.Feedback = append(pr.Feedback, messages.PRFeedback{
 				ID: comment.ID,
 				Comments: []messages.Comment{{
 					User:            user,
@@ -178,25 +338,28 @@ func (c *Client) getIssueCommentFeedback(ctx context.Context, org string, repo s
 			})
 		}
 
-		if !comments.PageInfo.HasNextPage {
-			break
+		if !cursor.advance(comments.PageInfo.HasNextPage, comments.PageInfo.EndCursor) {
+			return nil
 		}
-		req.Variables.Cursor = comments.PageInfo.EndCursor
+		req.Variables.Cursor = cursor.After
 	}
-
-	return ret, nil
 }
 
-func (c *Client) getReviewCommentFeedback(ctx context.Context, org string, repo string, prNum int, ret []messages.PRFeedback) ([]messages.PRFeedback, error) {
+func (c *Client) getReviewCommentFeedback(ctx context.Context, org string, repo string, prNum int, opts feedbackOptions, pr *PRFeedback) error {
+	cursor := &pr.Cursors.Reviews
+	if cursor.Done {
+		return nil
+	}
+
 	req := request(
 		reviewCommentsQuery,
-		reviewCommentVariables{Owner: org, Name: repo, PRNum: prNum},
+		reviewCommentVariables{Owner: org, Name: repo, PRNum: prNum, First: c.pageSize, Cursor: cursor.After},
 	)
 
 	for {
 		var resp reviewCommentsResponse
 		if err := c.queryGraphQL(ctx, &req, &resp); err != nil {
-			return nil, err
+			return err
 		}
 
 		reviews := resp.Data.Repository.PullRequest.Reviews
@@ -208,31 +371,31 @@ func (c *Client) getReviewCommentFeedback(ctx context.Context, org string, repo
 			if review.Author != nil {
 				user = review.Author.Login
 			}
-			if isPlan42Comment(user, review.Body) {
+			if isPlan42Comment(user, review.Body) || opts.skip(review.IsMinimized) {
 				continue
 			}
 			commitHash := ""
 			if review.Commit != nil {
 				commitHash = review.Commit.Oid
 			}
-			ret = append(ret, messages.PRFeedback{
+			pr.Feedback = append(pr.Feedback, messages.PRFeedback{
 				ID: review.ID,
 				Comments: []messages.Comment{{
-					User:       user,
-					Body:       review.Body,
-					Date:       review.CreatedAt,
-					CommitHash: commitHash,
+					User:            user,
+					Body:            review.Body,
+					Date:            review.CreatedAt,
+					CommitHash:      commitHash,
+					IsMinimized:     review.IsMinimized,
+					MinimizedReason: review.MinimizedReason,
 				}},
 			})
 		}
 
-		if !reviews.PageInfo.HasNextPage {
-			break
+		if !cursor.advance(reviews.PageInfo.HasNextPage, reviews.PageInfo.EndCursor) {
+			return nil
 		}
-		req.Variables.Cursor = reviews.PageInfo.EndCursor
+		req.Variables.Cursor = cursor.After
 	}
-
-	return ret, nil
 }
 
 func isPlan42Comment(user string, body string) bool {
@@ -250,11 +413,16 @@ func request[T any](query string, variables T) graphQLRequest[T] {
 	}
 }
 
-func (c *Client) GetThreadComments(ctx context.Context, threadID string) ([]messages.Comment, error) {
+func (c *Client) GetThreadComments(ctx context.Context, threadID string, options ...FeedbackOption) ([]messages.Comment, error) {
+	return c.getThreadComments(ctx, threadID, newFeedbackOptions(options))
+}
+
+func (c *Client) getThreadComments(ctx context.Context, threadID string, opts feedbackOptions) ([]messages.Comment, error) {
 	req := request(
 		commentQuery,
 		commentVariables{
 			ThreadID: threadID,
+			First:    c.pageSize,
 		},
 	)
 
@@ -268,9 +436,13 @@ func (c *Client) GetThreadComments(ctx context.Context, threadID string) ([]mess
 		}
 		for _, c := range resp.Data.Node.Comments.Nodes {
 			user := c.Author.Login
-			if isPlan42Comment(user, c.Body) {
+			if isPlan42Comment(user, c.Body) || opts.skip(c.IsMinimized) {
 				continue
 			}
+			commitHash := ""
+			if c.Commit != nil {
+				commitHash = c.Commit.Oid
+			}
 			ret = append(
 				ret,
 				messages.Comment{
@@ -281,7 +453,7 @@ func (c *Client) GetThreadComments(ctx context.Context, threadID string) ([]mess
 					Path:            c.Path,
 					StartLine:       c.StartLine,
 					OrigStartLine:   c.OriginalStartLine,
-					CommitHash:      c.Commit.Oid,
+					CommitHash:      commitHash,
 					IsMinimized:     c.IsMinimized,
 					MinimizedReason: c.MinimizedReason,
 				},
@@ -299,6 +471,7 @@ type reviewThreadVariables struct {
 	Owner  string `json:"owner"`
 	Name   string `json:"name"`
 	PRNum  int    `json:"prNum"`
+	First  int    `json:"first"`
 	Cursor string `json:"cursor"`
 }
 
@@ -311,6 +484,8 @@ type reviewThreadResponse struct {
 	Data struct {
 		Repository struct {
 			PullRequest struct {
+				HeadRefName   string `json:"headRefName"`
+				HeadRefOid    string `json:"headRefOid"`
 				ReviewThreads struct {
 					PageInfo struct {
 						HasNextPage bool   `json:"hasNextPage"`
@@ -327,10 +502,12 @@ type reviewThreadResponse struct {
 }
 
 const reviewThreadQuery = `
-query($owner:String!, $name:String!, $prNum:Int!, $cursor:String) {
+query($owner:String!, $name:String!, $prNum:Int!, $first:Int!, $cursor:String) {
   repository(owner: $owner, name: $name) {
     pullRequest(number: $prNum) {
-      reviewThreads(first: 100 , after: $cursor) {
+      headRefName
+      headRefOid
+      reviewThreads(first: $first, after: $cursor) {
         pageInfo { hasNextPage endCursor } 
         nodes {
           id
@@ -360,7 +537,7 @@ type commentQueryResult struct {
 					MinimizedReason string    `json:"minimizedReason"`
 					DiffHunk        string    `json:"diffHunk"`
 					Path            string    `json:"path"`
-					Commit          struct {
+					Commit          *struct {
 						Oid string `json:"oid"`
 					} `json:"commit"`
 					StartLine         int `json:"startLine"`
@@ -373,14 +550,15 @@ type commentQueryResult struct {
 
 type commentVariables struct {
 	ThreadID string `json:"threadID"`
+	First    int    `json:"first"`
 	Cursor   string `json:"cursor"`
 }
 
 const commentQuery = `
-query($threadID:ID!, $cursor:String) {
+query($threadID:ID!, $first:Int!, $cursor:String) {
   node(id: $threadID) {
     ... on PullRequestReviewThread {
-      comments(first: 100, after: $cursor) {
+      comments(first: $first, after: $cursor) {
         pageInfo { hasNextPage endCursor }
         nodes {
           author { login }
@@ -404,6 +582,7 @@ type issueCommentVariables struct {
 	Owner  string `json:"owner"`
 	Name   string `json:"name"`
 	PRNum  int    `json:"prNum"`
+	First  int    `json:"first"`
 	Cursor string `json:"cursor"`
 }
 
@@ -433,10 +612,10 @@ type issueCommentsResponse struct {
 }
 
 const issueCommentsQuery = `
-query($owner:String!, $name:String!, $prNum:Int!, $cursor:String) {
+query($owner:String!, $name:String!, $prNum:Int!, $first:Int!, $cursor:String) {
   repository(owner: $owner, name: $name) {
     pullRequest(number: $prNum) {
-      comments(first: 100, after: $cursor) {
+      comments(first: $first, after: $cursor) {
         pageInfo { hasNextPage endCursor }
         nodes {
           id
@@ -456,6 +635,7 @@ type reviewCommentVariables struct {
 	Owner  string `json:"owner"`
 	Name   string `json:"name"`
 	PRNum  int    `json:"prNum"`
+	First  int    `json:"first"`
 	Cursor string `json:"cursor"`
 }
 
@@ -473,9 +653,11 @@ type reviewCommentsResponse struct {
 						Author *struct {
 							Login string `json:"login"`
 						} `json:"author"`
-						Body      string    `json:"body"`
-						CreatedAt time.Time `json:"createdAt"`
-						Commit    *struct {
+						Body            string    `json:"body"`
+						CreatedAt       time.Time `json:"createdAt"`
+						IsMinimized     bool      `json:"isMinimized"`
+						MinimizedReason string    `json:"minimizedReason"`
+						Commit          *struct {
 							Oid string `json:"oid"`
 						} `json:"commit"`
 					} `json:"nodes"`
@@ -486,16 +668,18 @@ type reviewCommentsResponse struct {
 }
 
 const reviewCommentsQuery = `
-query($owner:String!, $name:String!, $prNum:Int!, $cursor:String) {
+query($owner:String!, $name:String!, $prNum:Int!, $first:Int!, $cursor:String) {
   repository(owner: $owner, name: $name) {
     pullRequest(number: $prNum) {
-      reviews(first: 100, after: $cursor) {
+      reviews(first: $first, after: $cursor) {
         pageInfo { hasNextPage endCursor }
         nodes {
           id
           author { login }
           body
           createdAt
+          isMinimized
+          minimizedReason
           commit { oid }
         }
       }
@@ -517,6 +701,7 @@ func (c *Client) queryGraphQL(ctx context.Context, req any, resp any) error {
 	httpReq.Header.Set("Authorization", "Bearer "+c.token())
 	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
 	httpReq.Header.Set("Accept", "application/vnd.github+json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
 
 	httpResp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -0,0 +1,362 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPullRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/repos/plan42-ai/cli/pulls", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "open", r.URL.Query().Get("state"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Equal(t, "1", r.URL.Query().Get("per_page"))
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=3&per_page=1>; rel="next"`, "http://"+r.Host, r.URL.Path))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"number": 42, "title": "Add a thing", "head": {"ref": "feature"}, "base": {"ref": "main"}}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-token", server.URL)
+	require.NoError(t, err)
+
+	pulls, resp, err := client.ListPullRequests(
+		t.Context(),
+		"plan42-ai",
+		"cli",
+		&ghapi.PullRequestListOptions{State: "open", ListOptions: ghapi.ListOptions{Page: 2, PerPage: 1}},
+	)
+	require.NoError(t, err)
+	require.Len(t, pulls, 1)
+	require.Equal(t, 42, pulls[0].GetNumber())
+	require.Equal(t, "Add a thing", pulls[0].GetTitle())
+	require.Equal(t, "feature", pulls[0].GetHead().GetRef())
+	require.Equal(t, 3, resp.NextPage)
+}
+
+func TestListCollaborators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/repos/plan42-ai/cli/collaborators", r.URL.Path)
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "direct", r.URL.Query().Get("affiliation"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Equal(t, "1", r.URL.Query().Get("per_page"))
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=3&per_page=1>; rel="next"`, "http://"+r.Host, r.URL.Path))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"login": "octocat"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("test-token", server.URL)
+	require.NoError(t, err)
+
+	users, resp, err := client.ListCollaborators(
+		t.Context(),
+		"plan42-ai",
+		"cli",
+		&ghapi.ListCollaboratorsOptions{Affiliation: "direct", ListOptions: ghapi.ListOptions{Page: 2, PerPage: 1}},
+	)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, "octocat", users[0].GetLogin())
+	require.Equal(t, 3, resp.NextPage)
+}
+
+type graphQLTestRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// newGraphQLServer returns a client backed by a fake GraphQL endpoint that answers each request with respond.
+func newGraphQLServer(t *testing.T, respond func(req graphQLTestRequest) string, options ...ClientOption) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/graphql", r.URL.Path)
+
+		var req graphQLTestRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(respond(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("test-token", server.URL, options...)
+	require.NoError(t, err)
+	return client
+}
+
+func TestGetPRFeedBackIncludesHead(t *testing.T) {
+	client := newGraphQLServer(t, func(req graphQLTestRequest) string {
+		query := req.Query
+		switch {
+		case strings.Contains(query, "reviewThreads"):
+			return `{"data": {"repository": {"pullRequest": {
+				"headRefName": "feature",
+				"headRefOid": "abc123",
+				"reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": [{"id": "thread-1"}]}
+			}}}}`
+		case strings.Contains(query, "PullRequestReviewThread"):
+			return `{"data": {"node": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"author": {"login": "reviewer"}, "body": "fix this", "commit": {"oid": "def456"}},
+				{"author": {"login": "reviewer"}, "body": "and this", "commit": null}
+			]}}}}`
+		default:
+			return `{"data": {"repository": {"pullRequest": {}}}}`
+		}
+	})
+
+	fb, err := client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+	require.NoError(t, err)
+	require.Equal(t, "feature", fb.HeadRef)
+	require.Equal(t, "abc123", fb.HeadSHA)
+	require.Len(t, fb.Feedback, 1)
+	require.Len(t, fb.Feedback[0].Comments, 2)
+	require.Equal(t, "def456", fb.Feedback[0].Comments[0].CommitHash)
+	require.Empty(t, fb.Feedback[0].Comments[1].CommitHash)
+}
+
+func TestGetPRFeedBackMinimizedFilter(t *testing.T) {
+	client := newGraphQLServer(t, func(req graphQLTestRequest) string {
+		query := req.Query
+		switch {
+		case strings.Contains(query, "reviewThreads"):
+			return `{"data": {"repository": {"pullRequest": {
+				"reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": [{"id": "thread-1"}]}
+			}}}}`
+		case strings.Contains(query, "PullRequestReviewThread"):
+			return `{"data": {"node": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"author": {"login": "reviewer"}, "body": "active thread comment"},
+				{"author": {"login": "reviewer"}, "body": "outdated thread comment", "isMinimized": true, "minimizedReason": "outdated"}
+			]}}}}`
+		case strings.Contains(query, "reviews("):
+			return `{"data": {"repository": {"pullRequest": {"reviews": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"id": "review-1", "author": {"login": "reviewer"}, "body": "active review"},
+				{"id": "review-2", "author": {"login": "reviewer"}, "body": "resolved review", "isMinimized": true, "minimizedReason": "resolved"}
+			]}}}}}`
+		default:
+			return `{"data": {"repository": {"pullRequest": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"id": "comment-1", "author": {"login": "reviewer"}, "body": "active comment"},
+				{"id": "comment-2", "author": {"login": "reviewer"}, "body": "outdated comment", "isMinimized": true, "minimizedReason": "outdated"}
+			]}}}}}`
+		}
+	})
+
+	bodies := func(fb *PRFeedback) []string {
+		var ret []string
+		for _, f := range fb.Feedback {
+			for _, c := range f.Comments {
+				ret = append(ret, c.Body)
+			}
+		}
+		return ret
+	}
+
+	fb, err := client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"active thread comment",
+			"outdated thread comment",
+			"active comment",
+			"outdated comment",
+			"active review",
+			"resolved review",
+		},
+		bodies(fb),
+	)
+
+	fb, err = client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42, WithIncludeMinimized(false))
+	require.NoError(t, err)
+	require.Equal(t, []string{"active thread comment", "active comment", "active review"}, bodies(fb))
+}
+
+func TestGraphQLPageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ClientOption
+		expected float64
+	}{
+		{name: "default", expected: DefaultPageSize},
+		{name: "configured", options: []ClientOption{WithPageSize(25)}, expected: 25},
+		{name: "clamped", options: []ClientOption{WithPageSize(500)}, expected: DefaultPageSize},
+		{name: "invalid", options: []ClientOption{WithPageSize(0)}, expected: DefaultPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var pageSizes []any
+			client := newGraphQLServer(
+				t,
+				func(req graphQLTestRequest) string {
+					mu.Lock()
+					pageSizes = append(pageSizes, req.Variables["first"])
+					mu.Unlock()
+					if strings.Contains(req.Query, "reviewThreads") {
+						return `{"data": {"repository": {"pullRequest": {
+							"reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": [{"id": "thread-1"}]}
+						}}}}`
+					}
+					return `{"data": {}}`
+				},
+				tt.options...,
+			)
+
+			_, err := client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			// review threads, thread comments, issue comments, and reviews.
+			require.Len(t, pageSizes, 4)
+			for _, pageSize := range pageSizes {
+				require.InDelta(t, tt.expected, pageSize, 0)
+			}
+		})
+	}
+}
+
+func TestResumePRFeedBack(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	failed := false
+
+	client := newGraphQLServer(t, func(req graphQLTestRequest) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		cursor, _ := req.Variables["cursor"].(string)
+		switch {
+		case strings.Contains(req.Query, "reviewThreads"):
+			requests["threads"]++
+			return `{"data": {"repository": {"pullRequest": {"headRefName": "feature"}}}}`
+		case strings.Contains(req.Query, "reviews("):
+			requests["reviews"]++
+			return `{"data": {}}`
+		}
+
+		requests["comments:"+cursor]++
+		switch cursor {
+		case "":
+			return `{"data": {"repository": {"pullRequest": {"comments": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "page-1"},
+				"nodes": [{"id": "comment-1", "author": {"login": "reviewer"}, "body": "first"}]
+			}}}}}`
+		default:
+			if !failed {
+				failed = true
+				return `not json`
+			}
+			return `{"data": {"repository": {"pullRequest": {"comments": {
+				"pageInfo": {"hasNextPage": false},
+				"nodes": [{"id": "comment-2", "author": {"login": "reviewer"}, "body": "second"}]
+			}}}}}`
+		}
+	})
+
+	fb, err := client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+	require.Error(t, err)
+	require.NotNil(t, fb)
+	require.Len(t, fb.Feedback, 1)
+	require.True(t, fb.Cursors.ReviewThreads.Done)
+	require.Equal(t, FeedbackCursor{After: "page-1"}, fb.Cursors.IssueComments)
+
+	fb, err = client.ResumePRFeedBack(t.Context(), "plan42-ai", "cli", 42, fb)
+	require.NoError(t, err)
+	require.Equal(t, "feature", fb.HeadRef)
+	require.Len(t, fb.Feedback, 2)
+	require.Equal(t, "first", fb.Feedback[0].Comments[0].Body)
+	require.Equal(t, "second", fb.Feedback[1].Comments[0].Body)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(
+		t,
+		map[string]int{
+			"threads":         1,
+			"comments:":       1,
+			"comments:page-1": 2,
+			"reviews":         1,
+		},
+		requests,
+	)
+}
+
+func TestGraphQLURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baseURL  string
+		override string
+		want     string
+	}{
+		{name: "github.com", want: "https://api.github.com/graphql"},
+		{name: "enterprise", baseURL: "https://github.example.com/", want: "https://github.example.com/api/graphql"},
+		{
+			name:     "override",
+			baseURL:  "https://github.example.com/",
+			override: "https://proxy.example.com/github/graphql?x=1",
+			want:     "https://proxy.example.com/github/graphql?x=1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := NewClient("test-token", tc.baseURL, WithGraphQLURL(tc.override))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, client.graphqlURL)
+		})
+	}
+
+	for _, invalid := range []string{"/api/graphql", "github.example.com/api/graphql", "https://%zz"} {
+		_, err := NewClient("test-token", "https://github.example.com/", WithGraphQLURL(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var mu sync.Mutex
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("test-token", server.URL)
+	require.NoError(t, err)
+	_, err = client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+	require.NoError(t, err)
+
+	client, err = NewClient("test-token", server.URL, WithUserAgent("custom-agent/1.0"))
+	require.NoError(t, err)
+	_, err = client.GetPRFeedBack(t.Context(), "plan42-ai", "cli", 42)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// review threads, issue comments, and reviews for each client.
+	require.Len(t, userAgents, 6)
+	for _, userAgent := range userAgents[:3] {
+		require.Equal(t, version.UserAgent(), userAgent)
+	}
+	for _, userAgent := range userAgents[3:] {
+		require.Equal(t, "custom-agent/1.0", userAgent)
+	}
+}
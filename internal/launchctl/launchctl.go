@@ -1,24 +1,62 @@
 package launchctl
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
-	"io"
+	"maps"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/plan42-ai/xml"
 )
 
+// DefaultThrottleInterval is a respawn delay that keeps an agent that crashes on startup from restarting in a tight
+// loop.
+const DefaultThrottleInterval = 10 * time.Second
+
+// DefaultLogFileName is the name of the agent's log file when Agent.LogFileName isn't set.
+const DefaultLogFileName = "log.txt"
+
 type Agent struct {
 	Name        string
 	Argv        []string
 	ExitTimeout *time.Duration
-	CreateLog   bool
+	// ThrottleInterval is the minimum time launchd waits between starts of the agent. nil uses the launchd default.
+	ThrottleInterval *time.Duration
+	// CreateLog sends the agent's stderr and stdout to files in ~/Library/Logs/<Name>.
+	CreateLog bool
+	// LogFileName is the name of the file stderr is written to. It defaults to DefaultLogFileName.
+	LogFileName string
+	// StdoutFileName is the name of the file stdout is written to. It defaults to the stderr log file, so both
+	// streams are interleaved in one log.
+	StdoutFileName string
+	// Environment sets environment variables for the agent's process.
+	Environment map[string]string
+	// KeepAlive sets when launchd restarts the agent after it exits. nil restarts it unconditionally.
+	KeepAlive *KeepAlive
+}
+
+// KeepAlive is the dictionary form of the launchd KeepAlive policy.
+type KeepAlive struct {
+	// SuccessfulExit restarts the agent only if it exits with a zero status when true, or only if it exits with a
+	// non-zero status (or is killed by a signal) when false.
+	SuccessfulExit bool
+}
+
+func (k *KeepAlive) element() any {
+	if k == nil {
+		return boolElement(true)
+	}
+	return dictElement{
+		Entries: []any{
+			keyElement{Value: "SuccessfulExit"},
+			boolElement(k.SuccessfulExit),
+		},
+	}
 }
 
 type plistDocument struct {
@@ -31,6 +69,11 @@ type plistDict struct {
 	Entries []any `xml:",any"`
 }
 
+type dictElement struct {
+	XMLName xml.Name `xml:"dict"`
+	Entries []any    `xml:",any"`
+}
+
 type keyElement struct {
 	XMLName xml.Name `xml:"key"`
 	Value   string   `xml:",chardata"`
@@ -94,7 +137,7 @@ func (a *Agent) ToXML() (string, error) {
 				keyElement{Value: "RunAtLoad"},
 				boolElement(true),
 				keyElement{Value: "KeepAlive"},
-				boolElement(true),
+				a.KeepAlive.element(),
 			},
 		},
 	}
@@ -107,15 +150,37 @@ func (a *Agent) ToXML() (string, error) {
 		)
 	}
 
+	if a.ThrottleInterval != nil {
+		doc.Dict.Entries = append(
+			doc.Dict.Entries,
+			keyElement{Value: "ThrottleInterval"},
+			intElement{Value: int(a.ThrottleInterval.Seconds())},
+		)
+	}
+
+	if len(a.Environment) > 0 {
+		env := dictElement{}
+		for _, name := range slices.Sorted(maps.Keys(a.Environment)) {
+			env.Entries = append(env.Entries, keyElement{Value: name}, stringElement{Value: a.Environment[name]})
+		}
+		doc.Dict.Entries = append(doc.Dict.Entries, keyElement{Value: "EnvironmentVariables"}, env)
+	}
+
 	if a.CreateLog {
 		logPath, err := a.LogPath()
 		if err != nil {
 			return "", err
 		}
+		stdoutPath, err := a.StdoutPath()
+		if err != nil {
+			return "", err
+		}
 		doc.Dict.Entries = append(
 			doc.Dict.Entries,
 			keyElement{Value: "StandardErrorPath"},
 			stringElement{Value: logPath},
+			keyElement{Value: "StandardOutPath"},
+			stringElement{Value: stdoutPath},
 		)
 	}
 
@@ -172,71 +237,20 @@ func (a *Agent) FullLabel() string {
 	return fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
 }
 
-func (a *Agent) Shutdown() error {
-	label := fmt.Sprintf("gui/%d", os.Getuid())
-	plistPath, err := a.PlistPath()
-	if err != nil {
-		return err
-	}
-	cmd := exec.Command("launchctl", "bootout", label, plistPath)
-	return cmd.Run()
-}
-
-func (a *Agent) Status() (string, error) {
-	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
-	cmd := exec.Command("launchctl", "print", fullLabel)
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
-	var exitErr *exec.ExitError
-	if errors.As(err, &exitErr) && strings.Contains(outputStr, "Could not find service ") {
-		return "Not Running", nil
-	}
-	return outputStr, err
-}
-
-func (a *Agent) Enable() error {
-	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
-	cmd := exec.Command("launchctl", "enable", fullLabel)
-	cmd.Stdout = io.Discard
-	cmd.Stderr = io.Discard
-	return cmd.Run()
-}
-
-func (a *Agent) Bootstrap() error {
-	label := fmt.Sprintf("gui/%d", os.Getuid())
-	plistPath, err := a.PlistPath()
-	if err != nil {
-		return err
-	}
-	cmd := exec.Command("launchctl", "bootstrap", label, plistPath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// LogPath returns the path of the file the agent's stderr is written to.
+func (a *Agent) LogPath() (string, error) {
+	return a.logFilePath(cmp.Or(a.LogFileName, DefaultLogFileName))
 }
 
-func (a *Agent) Kickstart() error {
-	// #nosec: G204 - Subprocess launched with a potential tainted input or cmd arguments
-	//    This is ok. The "tainted" arg is gui/uid, where we get the UID from the OS via a system call.
-	cmd := exec.Command("launchctl", "kickstart", "-kp", a.FullLabel())
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+// StdoutPath returns the path of the file the agent's stdout is written to.
+func (a *Agent) StdoutPath() (string, error) {
+	return a.logFilePath(cmp.Or(a.StdoutFileName, a.LogFileName, DefaultLogFileName))
 }
 
-func (a *Agent) LogPath() (string, error) {
+func (a *Agent) logFilePath(name string) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to determine user home directory: %w", err)
 	}
-	return path.Join(homeDir, "Library", "Logs", a.Name, "log.txt"), nil
-}
-
-func (a *Agent) Disable() error {
-	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
-	cmd := exec.Command("launchctl", "disable", fullLabel)
-	cmd.Stdout = io.Discard
-	cmd.Stderr = io.Discard
-	return cmd.Run()
+	return path.Join(homeDir, "Library", "Logs", a.Name, name), nil
 }
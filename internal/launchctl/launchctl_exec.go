@@ -0,0 +1,95 @@
+//go:build !windows
+
+package launchctl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func (a *Agent) Shutdown() error {
+	label := fmt.Sprintf("gui/%d", os.Getuid())
+	plistPath, err := a.PlistPath()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("launchctl", "bootout", label, plistPath)
+	return cmd.Run()
+}
+
+func (a *Agent) Status() (string, error) {
+	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
+	cmd := exec.Command("launchctl", "print", fullLabel)
+	output, err := cmd.CombinedOutput()
+	outputStr := string(output)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && strings.Contains(outputStr, "Could not find service ") {
+		return "Not Running", nil
+	}
+	return outputStr, err
+}
+
+func (a *Agent) Enable() error {
+	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
+	cmd := exec.Command("launchctl", "enable", fullLabel)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	return cmd.Run()
+}
+
+// Bootstrap loads the agent from its plist. If the agent is already loaded, e.g. by an earlier install, it is unloaded
+// and loaded again, so changes to the plist take effect.
+func (a *Agent) Bootstrap() error {
+	err := a.bootstrap()
+	if err == nil || !a.isLoaded() {
+		return err
+	}
+	err = a.Shutdown()
+	if err != nil {
+		return fmt.Errorf("failed to unload the already loaded agent: %w", err)
+	}
+	return a.bootstrap()
+}
+
+func (a *Agent) bootstrap() error {
+	label := fmt.Sprintf("gui/%d", os.Getuid())
+	plistPath, err := a.PlistPath()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("launchctl", "bootstrap", label, plistPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// isLoaded reports whether launchd has the agent loaded.
+func (a *Agent) isLoaded() bool {
+	// #nosec: G204 - Subprocess launched with a potential tainted input or cmd arguments
+	//    This is ok. The "tainted" arg is gui/uid, where we get the UID from the OS via a system call.
+	return exec.Command("launchctl", "print", a.FullLabel()).Run() == nil
+}
+
+func (a *Agent) Kickstart() error {
+	// #nosec: G204 - Subprocess launched with a potential tainted input or cmd arguments
+	//    This is ok. The "tainted" arg is gui/uid, where we get the UID from the OS via a system call.
+	cmd := exec.Command("launchctl", "kickstart", "-kp", a.FullLabel())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (a *Agent) Disable() error {
+	fullLabel := fmt.Sprintf("gui/%d/%s", os.Getuid(), a.Name)
+	cmd := exec.Command("launchctl", "disable", fullLabel)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	return cmd.Run()
+}
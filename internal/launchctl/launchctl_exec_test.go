@@ -0,0 +1,74 @@
+//go:build !windows
+
+package launchctl_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/launchctl"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLaunchctl puts a stand-in for launchctl on PATH that tracks whether the agent is loaded and records the
+// subcommands it runs. It returns a function that reads the recorded subcommands.
+func fakeLaunchctl(t *testing.T, loaded bool) func() []string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	callsPath := filepath.Join(dir, "calls")
+	statePath := filepath.Join(dir, "loaded")
+	if loaded {
+		require.NoError(t, os.WriteFile(statePath, nil, 0o600))
+	}
+	script := `#!/bin/sh
+echo "$1" >> "` + callsPath + `"
+case "$1" in
+bootstrap)
+	if [ -f "` + statePath + `" ]; then
+		echo "Bootstrap failed: 5: Input/output error" >&2
+		exit 5
+	fi
+	touch "` + statePath + `"
+	;;
+bootout)
+	rm -f "` + statePath + `"
+	;;
+print)
+	if [ ! -f "` + statePath + `" ]; then
+		echo "Could not find service" >&2
+		exit 113
+	fi
+	;;
+esac
+exit 0
+`
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "launchctl"), []byte(script), 0o755))
+
+	return func() []string {
+		data, err := os.ReadFile(callsPath)
+		require.NoError(t, err)
+		return strings.Fields(string(data))
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	calls := fakeLaunchctl(t, false)
+	agent := launchctl.Agent{Name: "ai.plan42.runner"}
+
+	require.NoError(t, agent.Bootstrap())
+	require.Equal(t, []string{"bootstrap"}, calls())
+}
+
+func TestBootstrapReloadsLoadedAgent(t *testing.T) {
+	calls := fakeLaunchctl(t, true)
+	agent := launchctl.Agent{Name: "ai.plan42.runner"}
+
+	require.NoError(t, agent.Bootstrap())
+	require.Equal(t, []string{"bootstrap", "print", "bootout", "bootstrap"}, calls())
+}
@@ -47,3 +47,112 @@ func TestBuildLaunchAgentPlist(t *testing.T) {
 
 	require.Equal(t, expected, actual)
 }
+
+func TestBuildLaunchAgentPlistKeepAliveDict(t *testing.T) {
+	agent := launchctl.Agent{
+		Name:      "ai.plan42.runner",
+		Argv:      []string{"/opt/homebrew/bin/plan42-runner"},
+		KeepAlive: &launchctl.KeepAlive{SuccessfulExit: false},
+	}
+
+	actual, err := agent.ToXML()
+	require.NoError(t, err)
+
+	const expected = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+  <dict>
+    <key>Label</key>
+    <string>ai.plan42.runner</string>
+    <key>ProgramArguments</key>
+    <array>
+      <string>/opt/homebrew/bin/plan42-runner</string>
+    </array>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>KeepAlive</key>
+    <dict>
+      <key>SuccessfulExit</key>
+      <false/>
+    </dict>
+  </dict>
+</plist>
+`
+
+	require.Equal(t, expected, actual)
+}
+
+func TestBuildLaunchAgentPlistThrottleInterval(t *testing.T) {
+	agent := launchctl.Agent{
+		Name: "ai.plan42.runner",
+		Argv: []string{"/opt/homebrew/bin/plan42-runner"},
+	}
+
+	actual, err := agent.ToXML()
+	require.NoError(t, err)
+	require.NotContains(t, actual, "ThrottleInterval")
+
+	agent.ThrottleInterval = util.Pointer(30 * time.Second)
+	actual, err = agent.ToXML()
+	require.NoError(t, err)
+	require.Contains(t, actual, "    <key>ThrottleInterval</key>\n    <integer>30</integer>\n")
+}
+
+func TestBuildLaunchAgentPlistEnvironment(t *testing.T) {
+	agent := launchctl.Agent{
+		Name: "ai.plan42.runner",
+		Argv: []string{"/opt/homebrew/bin/plan42-runner"},
+		Environment: map[string]string{
+			"PLAN42_LOG_LEVEL": "debug",
+			"HTTPS_PROXY":      "http://proxy.example.com:8080",
+			"NO_PROXY":         "localhost",
+		},
+	}
+
+	actual, err := agent.ToXML()
+	require.NoError(t, err)
+
+	const expected = `    <key>EnvironmentVariables</key>
+    <dict>
+      <key>HTTPS_PROXY</key>
+      <string>http://proxy.example.com:8080</string>
+      <key>NO_PROXY</key>
+      <string>localhost</string>
+      <key>PLAN42_LOG_LEVEL</key>
+      <string>debug</string>
+    </dict>
+`
+	require.Contains(t, actual, expected)
+
+	agent.Environment = nil
+	actual, err = agent.ToXML()
+	require.NoError(t, err)
+	require.NotContains(t, actual, "EnvironmentVariables")
+}
+
+func TestBuildLaunchAgentPlistLogPaths(t *testing.T) {
+	t.Setenv("HOME", "/Users/example")
+	agent := launchctl.Agent{
+		Name:      "ai.plan42.runner",
+		Argv:      []string{"/opt/homebrew/bin/plan42-runner"},
+		CreateLog: true,
+	}
+
+	actual, err := agent.ToXML()
+	require.NoError(t, err)
+	require.Contains(t, actual, `    <key>StandardErrorPath</key>
+    <string>/Users/example/Library/Logs/ai.plan42.runner/log.txt</string>
+    <key>StandardOutPath</key>
+    <string>/Users/example/Library/Logs/ai.plan42.runner/log.txt</string>
+`)
+
+	agent.LogFileName = "stderr.txt"
+	agent.StdoutFileName = "stdout.txt"
+	actual, err = agent.ToXML()
+	require.NoError(t, err)
+	require.Contains(t, actual, `    <key>StandardErrorPath</key>
+    <string>/Users/example/Library/Logs/ai.plan42.runner/stderr.txt</string>
+    <key>StandardOutPath</key>
+    <string>/Users/example/Library/Logs/ai.plan42.runner/stdout.txt</string>
+`)
+}
@@ -0,0 +1,30 @@
+package launchctl
+
+import "errors"
+
+// ErrUnsupported is returned by agent operations that require launchctl, which doesn't exist on Windows.
+var ErrUnsupported = errors.New("launchctl is not supported on windows")
+
+func (a *Agent) Shutdown() error {
+	return ErrUnsupported
+}
+
+func (a *Agent) Status() (string, error) {
+	return "", ErrUnsupported
+}
+
+func (a *Agent) Enable() error {
+	return ErrUnsupported
+}
+
+func (a *Agent) Bootstrap() error {
+	return ErrUnsupported
+}
+
+func (a *Agent) Kickstart() error {
+	return ErrUnsupported
+}
+
+func (a *Agent) Disable() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,133 @@
+// Package p42client builds Plan42 API clients from the runner config, including the TLS settings the SDK doesn't
+// provide options for.
+package p42client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/version"
+	"github.com/plan42-ai/sdk-go/p42"
+)
+
+// Options returns the client options for the server configured in cfg: the runner token, any TLS settings, and the
+// runner's User-Agent. It fails if a configured CA bundle or client certificate can't be loaded.
+func Options(cfg config.Runner) ([]p42.Option, error) {
+	ret := []p42.Option{
+		p42.WithAPIToken(cfg.RunnerToken),
+	}
+	if cfg.SkipSSLVerify {
+		ret = append(ret, p42.WithInsecureSkipVerify())
+	}
+	if cfg.CACertFile != "" {
+		pool, err := LoadRootCAs(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, WithRootCAs(pool))
+	}
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := LoadClientCert(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, WithClientCert(cert))
+	}
+	// The User-Agent wraps the transport, so it has to come after the options that configure it.
+	ret = append(ret, WithUserAgent(version.UserAgent()))
+	return ret, nil
+}
+
+// LoadRootCAs returns the system cert pool with the PEM encoded certificates in path added to it.
+func LoadRootCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA cert file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA cert file %s does not contain any PEM encoded certificates", path)
+	}
+	return pool, nil
+}
+
+// WithRootCAs makes the client verify the server's certificate against pool instead of the system cert pool.
+func WithRootCAs(pool *x509.CertPool) p42.Option {
+	return func(c *p42.Client) {
+		tlsConfig(c).RootCAs = pool
+	}
+}
+
+// LoadClientCert loads the client certificate in certFile and its private key in keyFile. Both files must be set.
+func LoadClientCert(certFile string, keyFile string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, errors.New("client_cert_file and client_key_file must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate %s with key %s: %w", certFile, keyFile, err)
+	}
+	return cert, nil
+}
+
+// WithClientCert makes the client present cert to the server when the server requests a client certificate.
+func WithClientCert(cert tls.Certificate) p42.Option {
+	return func(c *p42.Client) {
+		tlsConfig(c).Certificates = []tls.Certificate{cert}
+	}
+}
+
+// WithUserAgent sets the User-Agent header on every request made by the client. It wraps the client's transport, so it
+// must be applied after any options that configure the transport, such as WithRootCAs or p42.WithInsecureSkipVerify.
+func WithUserAgent(userAgent string) p42.Option {
+	return func(c *p42.Client) {
+		if c.HTTPClient == nil {
+			c.HTTPClient = &http.Client{}
+		}
+		base := c.HTTPClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.HTTPClient.Transport = &userAgentTransport{base: base, userAgent: userAgent}
+	}
+}
+
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given.
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
+
+// tlsConfig returns the TLS config of the client's transport, creating the client, transport, and config as needed.
+// It mirrors how p42.WithInsecureSkipVerify sets up the transport, so the options can be combined.
+func tlsConfig(c *p42.Client) *tls.Config {
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{}
+	}
+
+	transport, _ := c.HTTPClient.Transport.(*http.Transport)
+	if transport == nil {
+		transport = &http.Transport{}
+		c.HTTPClient.Transport = transport
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return transport.TLSClientConfig
+}
@@ -0,0 +1,164 @@
+package p42client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/version"
+	"github.com/plan42-ai/sdk-go/p42"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, data []byte) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "*.pem")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	path := f.Name()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// httpTransport returns the *http.Transport underneath the User-Agent transport added by Options.
+func httpTransport(t *testing.T, client *p42.Client) *http.Transport {
+	t.Helper()
+	ua, ok := client.HTTPClient.Transport.(*userAgentTransport)
+	require.True(t, ok)
+	transport, ok := ua.base.(*http.Transport)
+	require.True(t, ok)
+	return transport
+}
+
+func TestOptionsTrustsCACertFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	caFile := writeFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	options, err := Options(config.Runner{RunnerToken: "p42r_token", CACertFile: caFile})
+	require.NoError(t, err)
+	client := p42.NewClient(server.URL, options...)
+
+	transport := httpTransport(t, client)
+	require.NotNil(t, transport.TLSClientConfig)
+	require.False(t, transport.TLSClientConfig.InsecureSkipVerify)
+	require.NotNil(t, transport.TLSClientConfig.RootCAs)
+
+	_, err = server.Certificate().Verify(x509.VerifyOptions{Roots: transport.TLSClientConfig.RootCAs})
+	require.NoError(t, err)
+
+	resp, err := client.HTTPClient.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestOptionsRejectsInvalidCACertFile(t *testing.T) {
+	_, err := Options(config.Runner{CACertFile: writeFile(t, []byte("not a certificate"))})
+	require.Error(t, err)
+
+	_, err = Options(config.Runner{CACertFile: filepath.Join(t.TempDir(), "missing.pem")})
+	require.Error(t, err)
+}
+
+// writeKeyPair writes a self-signed client certificate and its key to PEM files and returns their paths.
+func writeKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "runner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := writeFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyFile := writeFile(t, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func TestOptionsPresentsClientCert(t *testing.T) {
+	var peerCN string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peerCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	certFile, keyFile := writeKeyPair(t)
+	caFile := writeFile(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	options, err := Options(config.Runner{CACertFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile})
+	require.NoError(t, err)
+	client := p42.NewClient(server.URL, options...)
+
+	transport := httpTransport(t, client)
+	require.Len(t, transport.TLSClientConfig.Certificates, 1)
+	require.NotNil(t, transport.TLSClientConfig.RootCAs)
+
+	resp, err := client.HTTPClient.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "runner", peerCN)
+}
+
+func TestOptionsRejectsInvalidClientCert(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t)
+	_, otherKeyFile := writeKeyPair(t)
+
+	_, err := Options(config.Runner{ClientCertFile: certFile})
+	require.Error(t, err)
+
+	_, err = Options(config.Runner{ClientCertFile: certFile, ClientKeyFile: otherKeyFile})
+	require.Error(t, err)
+
+	_, err = Options(config.Runner{ClientCertFile: keyFile, ClientKeyFile: keyFile})
+	require.Error(t, err)
+}
+
+func TestOptionsSetsUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	options, err := Options(config.Runner{RunnerToken: "p42r_token", SkipSSLVerify: true})
+	require.NoError(t, err)
+	client := p42.NewClient(server.URL, options...)
+	require.True(t, httpTransport(t, client).TLSClientConfig.InsecureSkipVerify)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "Go-http-client/1.1")
+	resp, err := client.HTTPClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, version.UserAgent(), userAgent)
+	require.Equal(t, "Go-http-client/1.1", req.Header.Get("User-Agent"))
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/util"
@@ -26,19 +27,34 @@ const (
 type Provider struct {
 	containerPath string
 	logDir        string
+	registryAuth  p42runtime.RegistryAuth
+}
+
+// Option configures a Provider.
+type Option func(p *Provider)
+
+// WithRegistryAuth sets the credentials used to log in to private registries before pulling images.
+func WithRegistryAuth(auth p42runtime.RegistryAuth) Option {
+	return func(p *Provider) {
+		p.registryAuth = auth
+	}
 }
 
 // NewProvider creates a new Provider with the given container binary path and log directory.
 // If containerPath is empty, it defaults to "container".
 // The logDir parameter specifies where job logs are stored.
-func NewProvider(containerPath string, logDir string) *Provider {
+func NewProvider(containerPath string, logDir string, options ...Option) *Provider {
 	if containerPath == "" {
 		containerPath = "container"
 	}
-	return &Provider{
+	ret := &Provider{
 		containerPath: containerPath,
 		logDir:        logDir,
 	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
 }
 
 // Name returns the configuration name of the runtime.
@@ -52,22 +68,72 @@ func (p *Provider) IsInstalled() bool {
 	return err == nil
 }
 
-// PullImage pulls the specified container image.
+// PullImage pulls the specified container image. If credentials are configured for the image's registry, it logs
+// in to the registry first.
 func (p *Provider) PullImage(ctx context.Context, image string) error {
+	if err := p.login(ctx, image); err != nil {
+		return err
+	}
+
 	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
 	//     containerPath is user-configurable, but we intentionally allow users to specify
 	//     their container binary location. image is validated before reaching this method.
 	cmd := exec.CommandContext(ctx, p.containerPath, "image", "pull", image)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		err = p42runtime.WrapExecError(p.containerPath, err)
+		if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+			return err
+		}
 		return fmt.Errorf("failed to pull image %s: %w\n%s", image, err, string(output))
 	}
 	return nil
 }
 
+// login logs in to the registry of image if credentials are configured for it. The password is passed on stdin so
+// it doesn't show up in the process list.
+func (p *Provider) login(ctx context.Context, image string) error {
+	if len(p.registryAuth) == 0 {
+		return nil
+	}
+
+	host, creds, ok, err := p.registryAuth.Lookup(image)
+	if err != nil || !ok {
+		return err
+	}
+
+	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
+	//     containerPath is user-configurable, but we intentionally allow users to specify
+	//     their container binary location. host is validated by Lookup.
+	cmd := exec.CommandContext(ctx, p.containerPath, loginArgs(host, creds)...)
+	cmd.Stdin = strings.NewReader(creds.Password)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = p42runtime.WrapExecError(p.containerPath, err)
+		if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+			return err
+		}
+		return fmt.Errorf("failed to log in to registry %s: %w\n%s", host, err, string(output))
+	}
+	return nil
+}
+
+// loginArgs returns the container arguments that log in to host with creds. The password is read from stdin.
+func loginArgs(host string, creds p42runtime.RegistryCredentials) []string {
+	return []string{"registry", "login", "--username", creds.Username, "--password-stdin", host}
+}
+
 // RunJob runs a job with the specified options.
 // If p.logDir is set, logs are written to {logDir}/{JobID}.
+// It fails before starting the container if opts requests more memory than the host has.
 func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error {
+	if err := p42runtime.ValidateJobArgs(opts); err != nil {
+		return err
+	}
+	if err := p42runtime.CheckJobMemory(opts); err != nil {
+		return err
+	}
+
 	args := []string{"run"}
 
 	if opts.CPUs > 0 {
@@ -85,15 +151,20 @@ func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error
 	if opts.Entrypoint != "" {
 		args = append(args, "--entrypoint", opts.Entrypoint)
 	}
+	args = append(args, p42runtime.EnvArgs(opts.Env)...)
+	args = append(args, p42runtime.MountArgs(opts.Mounts)...)
+	args = append(args, p42runtime.NetworkArgs(opts.Network)...)
 
-	args = append(args, "--rm")
+	if !opts.KeepOnExit {
+		args = append(args, "--rm")
+	}
 	args = append(args, opts.Image)
 	args = append(args, opts.Args...)
 
 	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
 	//     containerPath is user-configurable, but we intentionally allow users to specify
 	//     their container binary location. JobID and Image are validated before reaching
-	//     this method.
+	//     this method, and the rest of the job options are validated by ValidateJobArgs above.
 	cmd := exec.CommandContext(ctx, p.containerPath, args...)
 	cmd.Stdin = opts.Stdin
 
@@ -103,24 +174,26 @@ func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error
 		if err := os.MkdirAll(p.logDir, 0o755); err != nil {
 			return fmt.Errorf("failed to create log directory: %w", err)
 		}
-		logFile, err := os.Create(logPath)
+		if err := p42runtime.CheckLogDiskSpace(p.logDir, opts.MinFreeDiskBytes); err != nil {
+			return err
+		}
+		logFile, err := p42runtime.CreateJobLog(logPath, opts)
 		if err != nil {
 			return fmt.Errorf("failed to create log file: %w", err)
 		}
 		defer logFile.Close()
-		cmd.Stdout = logFile
-		cmd.Stderr = logFile
+		cmd.Stdout, cmd.Stderr = p42runtime.TeeJobOutput(logFile, opts)
 	} else {
 		cmd.Stdout = opts.Stdout
 		cmd.Stderr = opts.Stderr
 	}
 
-	return cmd.Run()
+	return p42runtime.WrapExecError(p.containerPath, cmd.Run())
 }
 
 // KillJob terminates the job with the given ID.
-// This streams output directly to os.Stdout/os.Stderr and panics on exit error,
-// matching the original behavior in container.go.
+// This streams output directly to os.Stdout/os.Stderr. If the container binary exits with an error, the returned
+// error carries its exit code (see util.ExitCodeOf).
 func (p *Provider) KillJob(ctx context.Context, jobID string) error {
 	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
 	//     containerPath is user-configurable, but we intentionally allow users to specify
@@ -131,9 +204,10 @@ func (p *Provider) KillJob(ctx context.Context, jobID string) error {
 
 	err := cmd.Run()
 	if err != nil {
+		err = fmt.Errorf("failed to kill job %s: %w", jobID, err)
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			panic(util.ExitCode(exitErr.ExitCode()))
+			return util.WithExitCode(util.ExitCode(exitErr.ExitCode()), err)
 		}
 		return err
 	}
@@ -204,7 +278,7 @@ func (p *Provider) GetAllJobIDs(ctx context.Context) ([]string, error) {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasPrefix(name, containerPrefix) {
+		if !strings.HasPrefix(name, containerPrefix) || p42runtime.IsRotatedLog(name) {
 			continue
 		}
 		ids = append(ids, name)
@@ -216,25 +290,10 @@ func (p *Provider) GetAllJobIDs(ctx context.Context) ([]string, error) {
 // ValidateJobID checks if the given job ID is valid for this runtime.
 // A valid job ID has the format "plan42-{taskID}-{turnIndex}".
 func (p *Provider) ValidateJobID(jobID string) error {
-	if !strings.HasPrefix(jobID, containerPrefix) {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	trimmed := strings.TrimPrefix(jobID, containerPrefix)
-	idx := strings.LastIndex(trimmed, "-")
-	if idx == -1 {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	_, err := strconv.Atoi(trimmed[idx+1:])
-	if err != nil {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	return nil
+	return p42runtime.ValidateJobID(jobID)
 }
 
-// DeleteJobLog removes the log file for the specified job.
+// DeleteJobLog removes the log file for the specified job, along with any rolled over files.
 func (p *Provider) DeleteJobLog(jobID string) error {
 	if err := p.ValidateJobID(jobID); err != nil {
 		return err
@@ -244,12 +303,22 @@ func (p *Provider) DeleteJobLog(jobID string) error {
 		return nil
 	}
 
-	logPath := filepath.Join(p.logDir, jobID)
+	return p42runtime.RemoveJobLog(filepath.Join(p.logDir, jobID))
+}
 
-	err := os.Remove(logPath)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return err
+// JobLogModTime returns the last modification time of the log file for the specified job.
+func (p *Provider) JobLogModTime(jobID string) (time.Time, error) {
+	if err := p.ValidateJobID(jobID); err != nil {
+		return time.Time{}, err
 	}
 
-	return nil
+	if p.logDir == "" {
+		return time.Time{}, fmt.Errorf("no log directory configured")
+	}
+
+	info, err := os.Stat(filepath.Join(p.logDir, jobID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
 }
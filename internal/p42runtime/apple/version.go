@@ -0,0 +1,95 @@
+package apple
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+)
+
+// MinVersion is the oldest release of the container cli the provider supports. Older releases don't accept some of
+// the flags RunJob and login pass.
+var MinVersion = Version{Major: 0, Minor: 5, Patch: 0}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// Version is a container cli release version.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion extracts the version from the output of `container --version`, e.g.
+// "container CLI version 0.5.0 (build: release, commit: 3e4b0a1)".
+func ParseVersion(output string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(output)
+	if m == nil {
+		return Version{}, fmt.Errorf("unable to find a version in %q", strings.TrimSpace(output))
+	}
+	var parts [3]int
+	for i, s := range m[1:] {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", m[0], err)
+		}
+		parts[i] = n
+	}
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2]}, nil
+}
+
+// Less reports whether v is an older release than other.
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Version returns the version of the installed container cli.
+func (p *Provider) Version(ctx context.Context) (Version, error) {
+	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
+	//     containerPath is user-configurable, but we intentionally allow users to specify
+	//     their container binary location.
+	output, err := exec.CommandContext(ctx, p.containerPath, "--version").Output()
+	if err != nil {
+		err = p42runtime.WrapExecError(p.containerPath, err)
+		if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+			return Version{}, err
+		}
+		return Version{}, fmt.Errorf("failed to get container version: %w", err)
+	}
+	return ParseVersion(string(output))
+}
+
+// CheckVersion returns the version of the installed container cli, and an error if it's older than MinVersion.
+func (p *Provider) CheckVersion(ctx context.Context) (Version, error) {
+	v, err := p.Version(ctx)
+	if err != nil {
+		return Version{}, err
+	}
+	return v, checkMinVersion(v)
+}
+
+func checkMinVersion(v Version) error {
+	if v.Less(MinVersion) {
+		return fmt.Errorf(
+			"apple container %s is older than the minimum supported version %s; install a newer release from https://github.com/apple/container/releases",
+			v,
+			MinVersion,
+		)
+	}
+	return nil
+}
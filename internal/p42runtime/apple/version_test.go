@@ -0,0 +1,73 @@
+package apple
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected Version
+		wantErr  bool
+	}{
+		{name: "release", output: "container CLI version 0.5.0 (build: release, commit: 3e4b0a1)\n", expected: Version{0, 5, 0}},
+		{name: "bare", output: "1.2.3", expected: Version{1, 2, 3}},
+		{name: "multi digit", output: "container CLI version 0.12.10", expected: Version{0, 12, 10}},
+		{name: "prerelease", output: "container CLI version 1.0.0-rc.1", expected: Version{1, 0, 0}},
+		{name: "no version", output: "container CLI version unknown", wantErr: true},
+		{name: "partial version", output: "container CLI version 1.0", wantErr: true},
+		{name: "empty", output: "", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := ParseVersion(tc.output)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestCheckMinVersion(t *testing.T) {
+	testCases := []struct {
+		version Version
+		wantErr bool
+	}{
+		{version: MinVersion},
+		{version: Version{MinVersion.Major, MinVersion.Minor, MinVersion.Patch + 1}},
+		{version: Version{MinVersion.Major, MinVersion.Minor + 1, 0}},
+		{version: Version{MinVersion.Major + 1, 0, 0}},
+		{version: Version{0, 4, 9}, wantErr: true},
+		{version: Version{0, 1, 0}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version.String(), func(t *testing.T) {
+			err := checkMinVersion(tc.version)
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.version.String())
+			require.ErrorContains(t, err, "minimum supported version "+MinVersion.String())
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "container")
+	script := "#!/bin/sh\necho 'container CLI version 0.4.1 (build: release, commit: 1234567)'\n"
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+
+	v, err := NewProvider(binPath, "").CheckVersion(t.Context())
+	require.Equal(t, Version{0, 4, 1}, v)
+	require.ErrorContains(t, err, "apple container 0.4.1 is older than the minimum supported version")
+}
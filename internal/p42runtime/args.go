@@ -0,0 +1,142 @@
+package p42runtime
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidJobArgs is returned when a job's entrypoint or arguments are rejected by ValidateJobArgs.
+var ErrInvalidJobArgs = errors.New("invalid job arguments")
+
+// shellMetacharacters are characters that have no business in the entrypoint or arguments of a job. Jobs are run
+// without a shell, but the runtime may pass them through one inside the container.
+const shellMetacharacters = "`$;&|<>(){}!*?\\'\"\n\r"
+
+var (
+	// envNamePattern matches the environment variable names accepted by ValidateJobArgs.
+	envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// networkNamePattern matches the user defined network names accepted by ValidateJobArgs.
+	networkNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+// Network modes accepted for JobOptions.Network, in addition to user defined network names.
+const (
+	NetworkNone   = "none"
+	NetworkHost   = "host"
+	NetworkBridge = "bridge"
+)
+
+// ValidateJobArgs checks the entrypoint, arguments, environment, mounts, and network of a job before they are passed
+// to the runtime. It rejects values containing null bytes or shell metacharacters, an entrypoint that would be parsed
+// as a runtime flag, environment variable names that aren't valid identifiers, mounts of missing or relative paths,
+// and network names that aren't a known mode or a plain name.
+func ValidateJobArgs(opts JobOptions) error {
+	if strings.HasPrefix(opts.Entrypoint, "-") {
+		return fmt.Errorf("%w: entrypoint %q must not start with '-'", ErrInvalidJobArgs, opts.Entrypoint)
+	}
+	if err := validateJobArg(opts.Entrypoint); err != nil {
+		return fmt.Errorf("%w: entrypoint %q %w", ErrInvalidJobArgs, opts.Entrypoint, err)
+	}
+	for i, arg := range opts.Args {
+		if err := validateJobArg(arg); err != nil {
+			return fmt.Errorf("%w: argument %d (%q) %w", ErrInvalidJobArgs, i, arg, err)
+		}
+	}
+	for name, value := range opts.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidJobArgs, name)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("%w: environment variable %s contains a null byte", ErrInvalidJobArgs, name)
+		}
+	}
+	for _, mount := range opts.Mounts {
+		if err := validateMount(mount); err != nil {
+			return fmt.Errorf("%w: mount %s:%s %w", ErrInvalidJobArgs, mount.Source, mount.Target, err)
+		}
+	}
+	if !validNetwork(opts.Network) {
+		return fmt.Errorf("%w: invalid network %q", ErrInvalidJobArgs, opts.Network)
+	}
+	return nil
+}
+
+// validNetwork reports whether network is empty (the runtime default), one of the network modes, or a user defined
+// network name.
+func validNetwork(network string) bool {
+	switch network {
+	case "", NetworkNone, NetworkHost, NetworkBridge:
+		return true
+	}
+	return networkNamePattern.MatchString(network)
+}
+
+func validateMount(mount Mount) error {
+	if !filepath.IsAbs(mount.Source) {
+		return fmt.Errorf("source %q must be an absolute path", mount.Source)
+	}
+	// the target is a path inside the (linux) container, regardless of the host OS.
+	if !path.IsAbs(mount.Target) {
+		return fmt.Errorf("target %q must be an absolute path", mount.Target)
+	}
+	// the runtime splits the volume argument on ':' and ','.
+	if strings.ContainsAny(mount.Source+mount.Target, ":,\x00") {
+		return errors.New("must not contain ':', ',' or null bytes")
+	}
+	if _, err := os.Stat(mount.Source); err != nil {
+		return fmt.Errorf("source is not accessible: %w", err)
+	}
+	return nil
+}
+
+// MountArgs returns the "-v SOURCE:TARGET[:ro]" runtime arguments that mount mounts in a container. mounts must have
+// been validated by ValidateJobArgs.
+func MountArgs(mounts []Mount) []string {
+	ret := make([]string, 0, 2*len(mounts))
+	for _, mount := range mounts {
+		volume := mount.Source + ":" + mount.Target
+		if mount.ReadOnly {
+			volume += ":ro"
+		}
+		ret = append(ret, "-v", volume)
+	}
+	return ret
+}
+
+// NetworkArgs returns the "--network NETWORK" runtime arguments that attach a container to network, or none if network
+// is empty.
+func NetworkArgs(network string) []string {
+	if network == "" {
+		return nil
+	}
+	return []string{"--network", network}
+}
+
+// EnvArgs returns the "-e NAME=VALUE" runtime arguments that set env in a container, sorted by name so the command
+// line is deterministic. env must have been validated by ValidateJobArgs.
+func EnvArgs(env map[string]string) []string {
+	names := slices.Sorted(maps.Keys(env))
+	ret := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		ret = append(ret, "-e", name+"="+env[name])
+	}
+	return ret
+}
+
+func validateJobArg(arg string) error {
+	if strings.ContainsRune(arg, 0) {
+		return errors.New("contains a null byte")
+	}
+	if i := strings.IndexAny(arg, shellMetacharacters); i >= 0 {
+		return fmt.Errorf("contains shell metacharacter %q", arg[i])
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package p42runtime
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidateJobArgs(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    JobOptions
+		wantErr bool
+	}{
+		{name: "empty"},
+		{
+			name: "agent invocation",
+			opts: JobOptions{
+				Entrypoint: "/usr/bin/agent-wrapper",
+				Args:       []string{"--encrypted-input=false", "--plan42-proxy", "--log-agent-output"},
+			},
+		},
+		{name: "args with spaces and paths", opts: JobOptions{Args: []string{"--dir", "/work/my repo", "a,b:c@d"}}},
+		{name: "entrypoint flag", opts: JobOptions{Entrypoint: "--privileged"}, wantErr: true},
+		{name: "entrypoint null byte", opts: JobOptions{Entrypoint: "/bin/sh\x00"}, wantErr: true},
+		{name: "entrypoint semicolon", opts: JobOptions{Entrypoint: "/bin/true;reboot"}, wantErr: true},
+		{name: "arg null byte", opts: JobOptions{Args: []string{"ok", "bad\x00"}}, wantErr: true},
+		{name: "arg command substitution", opts: JobOptions{Args: []string{"$(id)"}}, wantErr: true},
+		{name: "arg backtick", opts: JobOptions{Args: []string{"`id`"}}, wantErr: true},
+		{name: "arg pipe", opts: JobOptions{Args: []string{"a|b"}}, wantErr: true},
+		{name: "arg redirect", opts: JobOptions{Args: []string{">/etc/passwd"}}, wantErr: true},
+		{name: "arg newline", opts: JobOptions{Args: []string{"a\nb"}}, wantErr: true},
+		{name: "env", opts: JobOptions{Env: map[string]string{"HTTPS_PROXY": "http://proxy:3128", "_x1": "a b;c"}}},
+		{name: "env name with equals", opts: JobOptions{Env: map[string]string{"A=B": "c"}}, wantErr: true},
+		{name: "env name with space", opts: JobOptions{Env: map[string]string{"A B": "c"}}, wantErr: true},
+		{name: "env name starting with digit", opts: JobOptions{Env: map[string]string{"1A": "c"}}, wantErr: true},
+		{name: "env empty name", opts: JobOptions{Env: map[string]string{"": "c"}}, wantErr: true},
+		{name: "env value null byte", opts: JobOptions{Env: map[string]string{"A": "c\x00"}}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJobArgs(tc.opts)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidJobArgs) {
+					t.Fatalf("expected ErrInvalidJobArgs, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateJobMounts(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		mount   Mount
+		wantErr bool
+	}{
+		{name: "absolute", mount: Mount{Source: dir, Target: "/cache"}},
+		{name: "read only", mount: Mount{Source: dir, Target: "/cache", ReadOnly: true}},
+		{name: "relative source", mount: Mount{Source: "cache", Target: "/cache"}, wantErr: true},
+		{name: "dot source", mount: Mount{Source: "./cache", Target: "/cache"}, wantErr: true},
+		{name: "relative target", mount: Mount{Source: dir, Target: "cache"}, wantErr: true},
+		{name: "missing source", mount: Mount{Source: filepath.Join(dir, "missing"), Target: "/cache"}, wantErr: true},
+		{name: "colon in target", mount: Mount{Source: dir, Target: "/cache:rw"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJobArgs(JobOptions{Mounts: []Mount{tc.mount}})
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidJobArgs) {
+					t.Fatalf("expected ErrInvalidJobArgs, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMountArgs(t *testing.T) {
+	got := MountArgs([]Mount{
+		{Source: "/home/user/.cache/go-build", Target: "/root/.cache/go-build"},
+		{Source: "/srv/scratch", Target: "/scratch", ReadOnly: true},
+	})
+	want := []string{
+		"-v", "/home/user/.cache/go-build:/root/.cache/go-build",
+		"-v", "/srv/scratch:/scratch:ro",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateJobNetwork(t *testing.T) {
+	for _, network := range []string{"", NetworkNone, NetworkHost, NetworkBridge, "plan42-agents", "agents_net.1"} {
+		if err := ValidateJobArgs(JobOptions{Network: network}); err != nil {
+			t.Fatalf("unexpected error for network %q: %v", network, err)
+		}
+	}
+
+	for _, network := range []string{"-net", "container:other", "a b", "net;reboot", "ns:/proc/1/ns/net", ".hidden"} {
+		if err := ValidateJobArgs(JobOptions{Network: network}); !errors.Is(err, ErrInvalidJobArgs) {
+			t.Fatalf("expected ErrInvalidJobArgs for network %q, got %v", network, err)
+		}
+	}
+}
+
+func TestNetworkArgs(t *testing.T) {
+	if got := NetworkArgs(""); len(got) != 0 {
+		t.Fatalf("expected no args for the default network, got %q", got)
+	}
+	if got, want := NetworkArgs(NetworkNone), []string{"--network", "none"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestEnvArgs(t *testing.T) {
+	got := EnvArgs(map[string]string{
+		"NO_PROXY":    "localhost,127.0.0.1",
+		"HTTPS_PROXY": "http://proxy:3128",
+		"EMPTY":       "",
+	})
+	want := []string{
+		"-e", "EMPTY=",
+		"-e", "HTTPS_PROXY=http://proxy:3128",
+		"-e", "NO_PROXY=localhost,127.0.0.1",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	if got := EnvArgs(nil); len(got) != 0 {
+		t.Fatalf("expected no args for empty env, got %q", got)
+	}
+}
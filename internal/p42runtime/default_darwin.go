@@ -0,0 +1,4 @@
+package p42runtime
+
+// DefaultRuntime is the runtime used when the config doesn't set one.
+const DefaultRuntime = RuntimeApple
@@ -0,0 +1,6 @@
+//go:build !darwin
+
+package p42runtime
+
+// DefaultRuntime is the runtime used when the config doesn't set one. The apple runtime only exists on macOS.
+const DefaultRuntime = RuntimePodman
@@ -0,0 +1,34 @@
+package p42runtime
+
+import (
+	"fmt"
+)
+
+// BytesPerMB is the number of bytes in a megabyte, the unit of the min_free_disk_mb setting.
+const BytesPerMB = 1024 * 1024
+
+// CheckLogDiskSpace returns an error if the filesystem holding logDir has less than minFreeBytes available, so a job
+// fails before its container starts rather than when its log can no longer be written. It does nothing if
+// minFreeBytes is 0, or if the free space can't be determined.
+func CheckLogDiskSpace(logDir string, minFreeBytes uint64) error {
+	return checkFreeDiskSpace(logDir, minFreeBytes, freeDiskBytes)
+}
+
+func checkFreeDiskSpace(path string, minFreeBytes uint64, freeBytes func(path string) (uint64, error)) error {
+	if minFreeBytes == 0 {
+		return nil
+	}
+
+	// the check is best effort, so if free space is unknown, let the job run.
+	free, err := freeBytes(path)
+	if err == nil && free < minFreeBytes {
+		return fmt.Errorf(
+			"only %dMB of disk space is free for job logs in %s, but at least %dMB is required; "+
+				"free up space or lower min_free_disk_mb",
+			free/BytesPerMB,
+			path,
+			minFreeBytes/BytesPerMB,
+		)
+	}
+	return nil
+}
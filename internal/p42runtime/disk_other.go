@@ -0,0 +1,12 @@
+//go:build !unix
+
+package p42runtime
+
+import (
+	"errors"
+)
+
+// freeDiskBytes returns the space available to unprivileged users on the filesystem holding path.
+func freeDiskBytes(_ string) (uint64, error) {
+	return 0, errors.New("reading free disk space is not supported on this platform")
+}
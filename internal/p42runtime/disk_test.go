@@ -0,0 +1,52 @@
+package p42runtime
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckFreeDiskSpace(t *testing.T) {
+	testCases := []struct {
+		name    string
+		minFree uint64
+		free    uint64
+		statErr error
+		wantErr bool
+	}{
+		{name: "enough space", minFree: 100 * BytesPerMB, free: 200 * BytesPerMB},
+		{name: "exactly the minimum", minFree: 100 * BytesPerMB, free: 100 * BytesPerMB},
+		{name: "low space", minFree: 100 * BytesPerMB, free: 99 * BytesPerMB, wantErr: true},
+		{name: "disabled", minFree: 0, free: 0},
+		{name: "unknown free space", minFree: 100 * BytesPerMB, statErr: errors.New("statfs failed")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var statted string
+			statfs := func(path string) (uint64, error) {
+				statted = path
+				return tc.free, tc.statErr
+			}
+			err := checkFreeDiskSpace("/var/log/plan42", tc.minFree, statfs)
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("checkFreeDiskSpace returned error: %v", err)
+				}
+				return
+			}
+			if statted != "/var/log/plan42" {
+				t.Fatalf("expected the log directory to be checked, got %q", statted)
+			}
+			if err == nil || !strings.Contains(err.Error(), "only 99MB of disk space is free") {
+				t.Fatalf("expected a low disk space error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckLogDiskSpace(t *testing.T) {
+	if err := CheckLogDiskSpace(t.TempDir(), 1); err != nil {
+		t.Fatalf("CheckLogDiskSpace returned error: %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package p42runtime
+
+import (
+	"syscall"
+)
+
+// freeDiskBytes returns the space available to unprivileged users on the filesystem holding path.
+func freeDiskBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	// #nosec G115: the block count and size are never negative.
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
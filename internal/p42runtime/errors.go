@@ -0,0 +1,34 @@
+package p42runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+)
+
+// ErrRuntimeUnavailable is returned when the runtime's binary can't be executed, for example because it was
+// uninstalled or PATH changed after the runner started.
+var ErrRuntimeUnavailable = errors.New("container runtime unavailable")
+
+// WrapExecError wraps err in ErrRuntimeUnavailable if it shows that binary could not be started. Other errors,
+// including non-zero exits, are returned unchanged.
+func WrapExecError(binary string, err error) error {
+	var execErr *exec.Error
+	if errors.As(err, &execErr) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: unable to run %s: %w", ErrRuntimeUnavailable, binary, err)
+	}
+	return err
+}
+
+// CheckAvailable returns an ErrRuntimeUnavailable error if provider's runtime is not installed.
+func CheckAvailable(provider Provider) error {
+	if !provider.IsInstalled() {
+		return fmt.Errorf(
+			"%w: the %s runtime is not installed or not on the runner's PATH; reinstall it or update the runner config",
+			ErrRuntimeUnavailable,
+			provider.Name(),
+		)
+	}
+	return nil
+}
@@ -2,12 +2,17 @@ package p42runtime
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/plan42-ai/cli/internal/util"
 	"github.com/plan42-ai/sdk-go/p42"
@@ -17,13 +22,28 @@ const (
 	// jobPrefix is the prefix for all Plan42 job IDs.
 	jobPrefix = "plan42-"
 
-	// maxConcurrency is the maximum number of concurrent API calls for fetching job data.
-	maxConcurrency = 10
+	// DefaultFetchConcurrency is the default number of concurrent API calls for fetching job data.
+	DefaultFetchConcurrency = 10
+
+	// MaxTurnIndex is the largest turn index accepted in a job ID.
+	MaxTurnIndex = 10000
+
+	// MaxJobIDLength is the longest job ID accepted as a container name. Job IDs are also used as container hostnames
+	// by some runtimes, which limits them to a single 63 character DNS label.
+	MaxJobIDLength = 63
 )
 
+// jobIDPattern matches the characters container runtimes accept in a container name.
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ErrJobDataUnavailable is returned by GetJobs when the task and turn data couldn't be fetched for any job, which
+// usually means the P42 API is unreachable or the token is invalid.
+var ErrJobDataUnavailable = errors.New("unable to fetch job data")
+
 // parseJobID parses a job ID into its components.
 // Format: "plan42-{taskID}-{turnIndex}"
-// Returns error if format is invalid.
+// Returns error if format is invalid, the task ID is empty, or the turn index
+// is outside the range [0, MaxTurnIndex].
 func parseJobID(id string) (taskID string, turnIndex int, err error) {
 	if !strings.HasPrefix(id, jobPrefix) {
 		return "", 0, fmt.Errorf("invalid job id: missing %q prefix", jobPrefix)
@@ -35,52 +55,103 @@ func parseJobID(id string) (taskID string, turnIndex int, err error) {
 		return "", 0, fmt.Errorf("invalid job id: missing turn index separator")
 	}
 
+	taskID = trimmed[:idx]
+
+	// A negative turn index shows up as a doubled separator, e.g. "plan42-foo--1".
+	if strings.HasSuffix(taskID, "-") {
+		return "", 0, fmt.Errorf("invalid job id: turn index must not be negative")
+	}
+
+	if taskID == "" {
+		return "", 0, fmt.Errorf("invalid job id: missing task id")
+	}
+
 	turnIndex, err = strconv.Atoi(trimmed[idx+1:])
 	if err != nil {
 		return "", 0, fmt.Errorf("invalid job id: turn index is not a number")
 	}
 
-	taskID = trimmed[:idx]
+	if turnIndex < 0 {
+		return "", 0, fmt.Errorf("invalid job id: turn index must not be negative")
+	}
+
+	if turnIndex > MaxTurnIndex {
+		return "", 0, fmt.Errorf("invalid job id: turn index exceeds maximum of %d", MaxTurnIndex)
+	}
+
 	return taskID, turnIndex, nil
 }
 
+// ValidateJobID checks that id has the format "plan42-{taskID}-{turnIndex}",
+// with a non-empty task ID and a turn index in the range [0, MaxTurnIndex],
+// and that it is usable as a container name: at most MaxJobIDLength
+// characters, containing only letters, digits, '_', '.' and '-'.
+func ValidateJobID(id string) error {
+	if _, _, err := parseJobID(id); err != nil {
+		return err
+	}
+	if len(id) > MaxJobIDLength {
+		return fmt.Errorf("invalid job id: length %d exceeds maximum of %d", len(id), MaxJobIDLength)
+	}
+	if !jobIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid job id: must contain only letters, digits, '_', '.' and '-'")
+	}
+	return nil
+}
+
 // fetchJobs populates TaskTitle and CreatedDate for each job by calling the P42 API.
 // Jobs must have TaskID, TurnIndex, and Running already set.
-// Uses worker goroutines for concurrent API calls.
-func fetchJobs(ctx context.Context, jobs []*Job, client *p42.Client, tenantID string, verbose bool) {
+// Uses up to concurrency worker goroutines for concurrent API calls.
+func fetchJobs(ctx context.Context, jobs []*Job, client *p42.Client, tenantID string, verbose bool, concurrency int) {
 	if len(jobs) == 0 {
 		return
 	}
 
-	jobCh := make(chan *Job, maxConcurrency)
+	nWorkers := min(max(concurrency, 1), len(jobs))
+	jobCh := make(chan *Job, nWorkers)
 	var wg sync.WaitGroup
 
 	// Start worker goroutines
-	for i := 0; i < maxConcurrency; i++ {
+	for i := 0; i < nWorkers; i++ {
 		wg.Add(1)
 		go fetchWorker(ctx, client, tenantID, verbose, jobCh, &wg)
 	}
 
-	// Send jobs to workers
-	for _, job := range jobs {
-		jobCh <- job
-	}
-	close(jobCh)
+	// Send jobs to workers, stopping if ctx is cancelled.
+	func() {
+		defer close(jobCh)
+		for i, job := range jobs {
+			select {
+			case jobCh <- job:
+			case <-ctx.Done():
+				for _, skipped := range jobs[i:] {
+					skipped.FetchErr = errors.Join(skipped.FetchErr, fmt.Errorf("job data not fetched: %w", ctx.Err()))
+				}
+				return
+			}
+		}
+	}()
 
 	// Wait for all workers to complete
 	wg.Wait()
 }
 
-// fetchWorker processes jobs from the channel and populates TaskTitle and CreatedDate.
+// fetchWorker processes jobs from the channel and populates TaskTitle and CreatedDate. Errors are recorded in
+// FetchErr.
 func fetchWorker(ctx context.Context, client *p42.Client, tenantID string, verbose bool, jobCh <-chan *Job, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for job := range jobCh {
+		if err := ctx.Err(); err != nil {
+			job.FetchErr = errors.Join(job.FetchErr, fmt.Errorf("job data not fetched: %w", err))
+			continue
+		}
 		task, err := client.GetTask(ctx, &p42.GetTaskRequest{
 			TenantID:       tenantID,
 			TaskID:         job.TaskID,
 			IncludeDeleted: util.Pointer(true),
 		})
 		if err != nil {
+			job.FetchErr = errors.Join(job.FetchErr, fmt.Errorf("failed to get task: %w", err))
 			if verbose {
 				slog.ErrorContext(ctx, "GetTask failed", "taskID", job.TaskID, "error", err)
 			}
@@ -98,6 +169,7 @@ func fetchWorker(ctx context.Context, client *p42.Client, tenantID string, verbo
 			},
 		)
 		if err != nil {
+			job.FetchErr = errors.Join(job.FetchErr, fmt.Errorf("failed to get turn: %w", err))
 			if verbose {
 				slog.ErrorContext(
 					ctx,
@@ -113,13 +185,16 @@ func fetchWorker(ctx context.Context, client *p42.Client, tenantID string, verbo
 	}
 }
 
-// sortJobs sorts jobs by CreatedDate (descending - newest first), then TaskTitle, then TaskID.
+// sortJobs sorts jobs by CreatedDate (descending - newest first), then TaskTitle, then TaskID, then TurnIndex.
 func sortJobs(jobs []*Job) {
 	sort.Slice(jobs, func(i, j int) bool {
 		left := jobs[i]
 		right := jobs[j]
 		if left.CreatedDate.Equal(right.CreatedDate) {
 			if left.TaskTitle == right.TaskTitle {
+				if left.TaskID == right.TaskID {
+					return left.TurnIndex < right.TurnIndex
+				}
 				return left.TaskID < right.TaskID
 			}
 			return left.TaskTitle < right.TaskTitle
@@ -128,6 +203,125 @@ func sortJobs(jobs []*Job) {
 	})
 }
 
+// jobJSON is the machine-readable form of a Job. The field names are part of the CLI's output contract, so they
+// must not change.
+type jobJSON struct {
+	TaskID    string  `json:"task_id"`
+	TurnIndex int     `json:"turn_index"`
+	Running   bool    `json:"running"`
+	Title     string  `json:"title"`
+	CreatedAt *string `json:"created_at"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// MarshalJobsJSON marshals jobs to a JSON array with stable field names. CreatedDate is formatted as RFC3339 in UTC,
+// or null if it is unknown. Errors fetching the job's data are reported in "error". An empty list marshals to [].
+func MarshalJobsJSON(jobs []*Job) ([]byte, error) {
+	out := make([]jobJSON, 0, len(jobs))
+	for _, job := range jobs {
+		var createdAt *string
+		if !job.CreatedDate.IsZero() {
+			createdAt = util.Pointer(job.CreatedDate.UTC().Format(time.RFC3339))
+		}
+		var fetchErr string
+		if job.FetchErr != nil {
+			fetchErr = job.FetchErr.Error()
+		}
+		out = append(out, jobJSON{
+			TaskID:    job.TaskID,
+			TurnIndex: job.TurnIndex,
+			Running:   job.Running,
+			Title:     job.TaskTitle,
+			CreatedAt: createdAt,
+			Error:     fetchErr,
+		})
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// KillTaskJobs kills the running jobs that belong to taskID and returns their IDs. If turnIndex is non-nil, only that
+// turn's job is killed. Otherwise, if more than one turn of the task is running, all must be set to kill them all;
+// this guards against accidentally killing turns the caller didn't know about.
+func KillTaskJobs(ctx context.Context, provider Provider, taskID string, turnIndex *int, all bool) ([]string, error) {
+	runningIDs, err := provider.GetRunningJobIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch running job IDs: %w", err)
+	}
+
+	var matches []string
+	for _, id := range runningIDs {
+		jobTaskID, jobTurnIndex, parseErr := parseJobID(id)
+		if parseErr != nil || jobTaskID != taskID {
+			continue
+		}
+		if turnIndex != nil && jobTurnIndex != *turnIndex {
+			continue
+		}
+		matches = append(matches, id)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no running jobs found for task %s", taskID)
+	}
+	if len(matches) > 1 && turnIndex == nil && !all {
+		return nil, fmt.Errorf(
+			"task %s has %d running jobs (%s): specify a turn index or use --all",
+			taskID,
+			len(matches),
+			strings.Join(matches, ", "),
+		)
+	}
+
+	var killed []string
+	for _, id := range matches {
+		// the provider's error already names the job.
+		err = provider.KillJob(ctx, id)
+		if err != nil {
+			return killed, err
+		}
+		killed = append(killed, id)
+	}
+	return killed, nil
+}
+
+// FindTaskJob returns the ID of the job for taskID that has a log, running or not. If turnIndex is non-nil, only
+// that turn's job matches. Otherwise, the task must have a single job; if it has more, they are listed in the error
+// so the caller can pick a turn.
+func FindTaskJob(ctx context.Context, provider Provider, taskID string, turnIndex *int) (string, error) {
+	allIDs, err := provider.GetAllJobIDs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch job IDs: %w", err)
+	}
+
+	var matches []string
+	for _, id := range allIDs {
+		jobTaskID, jobTurnIndex, parseErr := parseJobID(id)
+		if parseErr != nil || jobTaskID != taskID {
+			continue
+		}
+		if turnIndex != nil && jobTurnIndex != *turnIndex {
+			continue
+		}
+		matches = append(matches, id)
+	}
+
+	switch {
+	case len(matches) == 0 && turnIndex != nil:
+		return "", fmt.Errorf("no job found for turn %d of task %s", *turnIndex, taskID)
+	case len(matches) == 0:
+		return "", fmt.Errorf("no jobs found for task %s", taskID)
+	case len(matches) > 1:
+		slices.Sort(matches)
+		return "", fmt.Errorf(
+			"task %s has %d jobs (%s): specify a turn index",
+			taskID,
+			len(matches),
+			strings.Join(matches, ", "),
+		)
+	}
+	return matches[0], nil
+}
+
 // GetCompletedJobIDs returns IDs of jobs that have log files but are no longer running.
 // It computes this as: all job IDs with logs - running job IDs.
 func GetCompletedJobIDs(ctx context.Context, provider Provider) ([]string, error) {
@@ -160,13 +354,89 @@ func GetCompletedJobIDs(ctx context.Context, provider Provider) ([]string, error
 	return completedIDs, nil
 }
 
+// PruneJobLogs deletes the logs of completed jobs whose logs were last modified more than olderThan ago, and returns
+// their IDs. If dryRun is set, nothing is deleted, and the returned IDs are the logs that would have been deleted.
+// Logs are deleted via provider.DeleteJobLog, so IDs that aren't valid job IDs are never touched.
+func PruneJobLogs(ctx context.Context, provider Provider, olderThan time.Duration, dryRun bool) ([]string, error) {
+	completedIDs, err := GetCompletedJobIDs(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var pruned []string
+	for _, jobID := range completedIDs {
+		if provider.ValidateJobID(jobID) != nil {
+			continue
+		}
+
+		modTime, err := provider.JobLogModTime(jobID)
+		if err != nil {
+			return pruned, fmt.Errorf("failed to stat log for %s: %w", jobID, err)
+		}
+		if !modTime.Before(cutoff) {
+			continue
+		}
+
+		if !dryRun {
+			err = provider.DeleteJobLog(jobID)
+			if err != nil {
+				return pruned, fmt.Errorf("failed to delete log for %s: %w", jobID, err)
+			}
+		}
+		pruned = append(pruned, jobID)
+	}
+	return pruned, nil
+}
+
+// GetJobsFilter restricts the jobs returned by GetJobs. The zero value matches every job.
+type GetJobsFilter struct {
+	// TaskIDPrefix, if set, only matches jobs whose task ID starts with it.
+	TaskIDPrefix string
+	// RunningOnly only matches running jobs, even when completed jobs are requested.
+	RunningOnly bool
+	// Since, if set, only matches jobs created at or after it. Creation dates come from the P42 API, so this is
+	// applied after fetching, and jobs whose creation date could not be fetched are excluded.
+	Since time.Time
+}
+
+func (f *GetJobsFilter) matchesID(taskID string) bool {
+	return strings.HasPrefix(taskID, f.TaskIDPrefix)
+}
+
+func (f *GetJobsFilter) matchesCreated(job *Job) bool {
+	return f.Since.IsZero() || !job.CreatedDate.Before(f.Since)
+}
+
+// GetJobsOptions configures GetJobs.
+type GetJobsOptions struct {
+	// Verbose logs API errors encountered while fetching job data.
+	Verbose bool
+	// IncludeCompleted also returns jobs that have logs but are no longer running.
+	IncludeCompleted bool
+	// Filter restricts the jobs returned.
+	Filter GetJobsFilter
+	// FetchConcurrency is the number of concurrent API calls used to fetch job data. Values < 1 use
+	// DefaultFetchConcurrency.
+	FetchConcurrency int
+}
+
 // GetJobs returns a fully populated, sorted list of jobs.
 // It performs the following steps:
 // 1. Gets running job IDs from provider.
 // 2. Optionally gets completed job IDs from provider.
-// 3. Fetches job data from the API (TaskTitle, CreatedDate).
-// 4. Sorts by CreatedDate (descending), TaskTitle, TaskID.
-func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantID string, verbose bool, includeCompleted bool) ([]*Job, error) {
+// 3. Drops jobs excluded by the task ID and running filters.
+// 4. Fetches job data from the API (TaskTitle, CreatedDate). Errors are recorded on each job's FetchErr, and
+// ErrJobDataUnavailable is returned if the data couldn't be fetched for any job.
+// 5. Drops jobs created before filter.Since.
+// 6. Sorts by CreatedDate (descending), TaskTitle, TaskID, TurnIndex.
+func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantID string, opts GetJobsOptions) ([]*Job, error) {
+	filter := opts.Filter
+	concurrency := opts.FetchConcurrency
+	if concurrency < 1 {
+		concurrency = DefaultFetchConcurrency
+	}
+
 	seen := make(map[string]bool)
 	var jobs []*Job
 
@@ -181,6 +451,9 @@ func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantI
 			continue
 		}
 		seen[id] = true
+		if !filter.matchesID(taskID) {
+			continue
+		}
 		jobs = append(jobs, &Job{
 			TaskID:    taskID,
 			TurnIndex: turnIndex,
@@ -188,7 +461,7 @@ func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantI
 		})
 	}
 
-	if includeCompleted {
+	if opts.IncludeCompleted && !filter.RunningOnly {
 		allJobIDs, allErr := provider.GetAllJobIDs(ctx)
 		if allErr != nil {
 			return nil, fmt.Errorf("failed to fetch all job IDs: %w", allErr)
@@ -205,6 +478,9 @@ func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantI
 			}
 
 			seen[id] = true
+			if !filter.matchesID(taskID) {
+				continue
+			}
 			jobs = append(jobs, &Job{
 				TaskID:    taskID,
 				TurnIndex: turnIndex,
@@ -213,7 +489,16 @@ func GetJobs(ctx context.Context, provider Provider, client *p42.Client, tenantI
 		}
 	}
 
-	fetchJobs(ctx, jobs, client, tenantID, verbose)
+	fetchJobs(ctx, jobs, client, tenantID, opts.Verbose, concurrency)
+	if len(jobs) > 0 && !slices.ContainsFunc(jobs, func(job *Job) bool { return job.FetchErr == nil }) {
+		return nil, fmt.Errorf("%w: all %d jobs failed: %w", ErrJobDataUnavailable, len(jobs), jobs[0].FetchErr)
+	}
+
+	if !filter.Since.IsZero() {
+		jobs = slices.DeleteFunc(jobs, func(job *Job) bool {
+			return !filter.matchesCreated(job)
+		})
+	}
 	sortJobs(jobs)
 
 	return jobs, nil
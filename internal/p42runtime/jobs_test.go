@@ -3,20 +3,26 @@ package p42runtime
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/plan42-ai/cli/internal/util"
 	"github.com/plan42-ai/sdk-go/p42"
 )
 
 type stubProvider struct {
 	runningIDs []string
 	allIDs     []string
+	killedIDs  []string
+	// killErr is returned by KillJob for every job, after recording it, unless nil.
+	killErr func(jobID string) error
 }
 
 func (p *stubProvider) Name() string {
@@ -35,7 +41,11 @@ func (p *stubProvider) RunJob(_ context.Context, _ JobOptions) error {
 	return nil
 }
 
-func (p *stubProvider) KillJob(_ context.Context, _ string) error {
+func (p *stubProvider) KillJob(_ context.Context, jobID string) error {
+	p.killedIDs = append(p.killedIDs, jobID)
+	if p.killErr != nil {
+		return p.killErr(jobID)
+	}
 	return nil
 }
 
@@ -55,11 +65,53 @@ func (p *stubProvider) DeleteJobLog(_ string) error {
 	return nil
 }
 
+func (p *stubProvider) JobLogModTime(_ string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
 func newTestClient(t *testing.T, tenantID string, taskData map[string]p42.Task, turnData map[string]map[int]p42.Turn) *p42.Client {
 	t.Helper()
+	return newCountingTestClient(t, tenantID, taskData, turnData, &apiStats{})
+}
+
+// apiStats records the API calls made against a test client.
+type apiStats struct {
+	calls       atomic.Int64
+	inFlight    atomic.Int64
+	maxInFlight atomic.Int64
+}
+
+func (s *apiStats) begin() {
+	s.calls.Add(1)
+	n := s.inFlight.Add(1)
+	for {
+		current := s.maxInFlight.Load()
+		if n <= current || s.maxInFlight.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+func (s *apiStats) end() {
+	s.inFlight.Add(-1)
+}
+
+// newCountingTestClient is like newTestClient, but records the API calls made in stats.
+func newCountingTestClient(
+	t *testing.T,
+	tenantID string,
+	taskData map[string]p42.Task,
+	turnData map[string]map[int]p42.Turn,
+	stats *apiStats,
+) *p42.Client {
+	t.Helper()
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		stats.begin()
+		defer stats.end()
+		// hold each call briefly so concurrent calls overlap.
+		time.Sleep(time.Millisecond)
 		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 		if len(segments) == 5 && segments[0] == "v1" && segments[1] == "tenants" && segments[2] == tenantID && segments[3] == "tasks" {
 			taskID := segments[4]
@@ -161,7 +213,7 @@ func TestGetJobsIncludesCompletedWithoutDuplication(t *testing.T) {
 	provider := &stubProvider{runningIDs: running, allIDs: all}
 	client := newTestClient(t, tenantID, tasks, turns)
 
-	jobs, err := GetJobs(context.Background(), provider, client, tenantID, false, true)
+	jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{IncludeCompleted: true})
 	if err != nil {
 		t.Fatalf("GetJobs returned error: %v", err)
 	}
@@ -200,6 +252,89 @@ func TestGetJobsIncludesCompletedWithoutDuplication(t *testing.T) {
 	}
 }
 
+func TestGetJobsRecordsFetchErrors(t *testing.T) {
+	tenantID := "tenant-123"
+	running := []string{"plan42-alpha-1", "plan42-beta-2", "plan42-gamma-3"}
+
+	baseTime := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tasks, turns, err := buildJobData(running, tenantID, baseTime)
+	if err != nil {
+		t.Fatalf("unexpected build job data error: %v", err)
+	}
+	// beta's task was deleted, and gamma's task and turn were both deleted.
+	delete(tasks, "beta")
+	delete(tasks, "gamma")
+	delete(turns, "gamma")
+
+	provider := &stubProvider{runningIDs: running}
+	client := newTestClient(t, tenantID, tasks, turns)
+
+	jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{})
+	if err != nil {
+		t.Fatalf("GetJobs returned error: %v", err)
+	}
+
+	byTask := make(map[string]*Job)
+	for _, job := range jobs {
+		byTask[job.TaskID] = job
+	}
+
+	if byTask["alpha"].FetchErr != nil {
+		t.Errorf("alpha: unexpected fetch error: %v", byTask["alpha"].FetchErr)
+	}
+	if byTask["alpha"].DisplayTitle() != "Task alpha" {
+		t.Errorf("alpha: unexpected title %q", byTask["alpha"].DisplayTitle())
+	}
+
+	beta := byTask["beta"]
+	if beta.FetchErr == nil || !strings.Contains(beta.FetchErr.Error(), "failed to get task") {
+		t.Errorf("beta: expected a task fetch error, got %v", beta.FetchErr)
+	}
+	if beta.DisplayTitle() != unavailableTitle {
+		t.Errorf("beta: expected title %q, got %q", unavailableTitle, beta.DisplayTitle())
+	}
+	if beta.CreatedDate.IsZero() {
+		t.Error("beta: expected created date from the turn")
+	}
+
+	gamma := byTask["gamma"]
+	if gamma.FetchErr == nil || !strings.Contains(gamma.FetchErr.Error(), "failed to get task") ||
+		!strings.Contains(gamma.FetchErr.Error(), "failed to get turn") {
+		t.Errorf("gamma: expected task and turn fetch errors, got %v", gamma.FetchErr)
+	}
+}
+
+func TestGetJobsAllFetchesFail(t *testing.T) {
+	tenantID := "tenant-123"
+	provider := &stubProvider{runningIDs: []string{"plan42-alpha-1", "plan42-beta-2"}}
+	client := newTestClient(t, tenantID, nil, nil)
+
+	jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{})
+	if !errors.Is(err, ErrJobDataUnavailable) {
+		t.Fatalf("expected ErrJobDataUnavailable, got %v", err)
+	}
+	if jobs != nil {
+		t.Fatalf("expected no jobs, got %d", len(jobs))
+	}
+}
+
+func TestSortJobsTurnIndexTiebreaker(t *testing.T) {
+	created := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	for range 10 {
+		jobs := []*Job{
+			{TaskID: "task-1", TurnIndex: 3, TaskTitle: "Fix bug", CreatedDate: created},
+			{TaskID: "task-1", TurnIndex: 1, TaskTitle: "Fix bug", CreatedDate: created},
+			{TaskID: "task-1", TurnIndex: 2, TaskTitle: "Fix bug", CreatedDate: created},
+		}
+		sortJobs(jobs)
+		for i, job := range jobs {
+			if job.TurnIndex != i+1 {
+				t.Fatalf("expected turn %d at position %d, got %d", i+1, i, job.TurnIndex)
+			}
+		}
+	}
+}
+
 func TestGetJobsRunningOnly(t *testing.T) {
 	tenantID := "tenant-123"
 	running := []string{"plan42-delta-1", "plan42-epsilon-2"}
@@ -213,7 +348,7 @@ func TestGetJobsRunningOnly(t *testing.T) {
 	provider := &stubProvider{runningIDs: running, allIDs: running}
 	client := newTestClient(t, tenantID, tasks, turns)
 
-	jobs, err := GetJobs(context.Background(), provider, client, tenantID, false, false)
+	jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{})
 	if err != nil {
 		t.Fatalf("GetJobs returned error: %v", err)
 	}
@@ -228,3 +363,357 @@ func TestGetJobsRunningOnly(t *testing.T) {
 		}
 	}
 }
+
+func TestGetJobsFilter(t *testing.T) {
+	tenantID := "tenant-123"
+	running := []string{"plan42-alpha-1", "plan42-beta-1"}
+	all := []string{"plan42-alpha-1", "plan42-beta-1", "plan42-alpha-2", "plan42-gamma-1"}
+
+	// job i is created at baseTime + i hours.
+	baseTime := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tasks, turns, err := buildJobData(all, tenantID, baseTime)
+	if err != nil {
+		t.Fatalf("unexpected build job data error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		filter   GetJobsFilter
+		expected []string
+	}{
+		{name: "no filter", filter: GetJobsFilter{}, expected: []string{"gamma-1", "alpha-2", "beta-1", "alpha-1"}},
+		{name: "task id prefix", filter: GetJobsFilter{TaskIDPrefix: "al"}, expected: []string{"alpha-2", "alpha-1"}},
+		{name: "running only", filter: GetJobsFilter{RunningOnly: true}, expected: []string{"beta-1", "alpha-1"}},
+		{
+			name:     "running with prefix",
+			filter:   GetJobsFilter{TaskIDPrefix: "alpha", RunningOnly: true},
+			expected: []string{"alpha-1"},
+		},
+		{
+			name:     "since",
+			filter:   GetJobsFilter{Since: baseTime.Add(2 * time.Hour)},
+			expected: []string{"gamma-1", "alpha-2"},
+		},
+		{name: "no matches", filter: GetJobsFilter{TaskIDPrefix: "zeta"}, expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats apiStats
+			provider := &stubProvider{runningIDs: running, allIDs: all}
+			client := newCountingTestClient(t, tenantID, tasks, turns, &stats)
+
+			jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{IncludeCompleted: true, Filter: tc.filter})
+			if err != nil {
+				t.Fatalf("GetJobs returned error: %v", err)
+			}
+
+			var got []string
+			for _, job := range jobs {
+				got = append(got, fmt.Sprintf("%s-%d", job.TaskID, job.TurnIndex))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+				t.Fatalf("expected jobs %v, got %v", tc.expected, got)
+			}
+
+			// Since is applied after fetching, so it doesn't reduce API calls. Every other filter should only
+			// fetch (GetTask + GetTurn) the jobs it returns.
+			expectedCalls := int64(2 * len(tc.expected))
+			if !tc.filter.Since.IsZero() {
+				expectedCalls = int64(2 * len(all))
+			}
+			if stats.calls.Load() != expectedCalls {
+				t.Fatalf("expected %d API calls, got %d", expectedCalls, stats.calls.Load())
+			}
+		})
+	}
+}
+
+func TestGetJobsFetchConcurrency(t *testing.T) {
+	tenantID := "tenant-123"
+	var all []string
+	for i := 0; i < 20; i++ {
+		all = append(all, fmt.Sprintf("plan42-task%02d-1", i))
+	}
+
+	baseTime := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	tasks, turns, err := buildJobData(all, tenantID, baseTime)
+	if err != nil {
+		t.Fatalf("unexpected build job data error: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		concurrency    int
+		maxInFlightCap int64
+	}{
+		{name: "serialized", concurrency: 1, maxInFlightCap: 1},
+		{name: "limited", concurrency: 3, maxInFlightCap: 3},
+		{name: "default", concurrency: 0, maxInFlightCap: DefaultFetchConcurrency},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats apiStats
+			provider := &stubProvider{runningIDs: all, allIDs: all}
+			client := newCountingTestClient(t, tenantID, tasks, turns, &stats)
+
+			jobs, err := GetJobs(context.Background(), provider, client, tenantID, GetJobsOptions{FetchConcurrency: tc.concurrency})
+			if err != nil {
+				t.Fatalf("GetJobs returned error: %v", err)
+			}
+
+			if len(jobs) != len(all) {
+				t.Fatalf("expected %d jobs, got %d", len(all), len(jobs))
+			}
+			for _, job := range jobs {
+				if job.TaskTitle != tasks[job.TaskID].Title {
+					t.Errorf("job %s title %q, expected %q", job.TaskID, job.TaskTitle, tasks[job.TaskID].Title)
+				}
+				if !job.CreatedDate.Equal(turns[job.TaskID][job.TurnIndex].CreatedAt) {
+					t.Errorf("job %s has unexpected created date %v", job.TaskID, job.CreatedDate)
+				}
+			}
+
+			if stats.calls.Load() != int64(2*len(all)) {
+				t.Fatalf("expected %d API calls, got %d", 2*len(all), stats.calls.Load())
+			}
+			if stats.maxInFlight.Load() > tc.maxInFlightCap {
+				t.Fatalf("expected at most %d concurrent calls, got %d", tc.maxInFlightCap, stats.maxInFlight.Load())
+			}
+		})
+	}
+}
+
+func TestGetJobsCancelled(t *testing.T) {
+	tenantID := "tenant-123"
+	var all []string
+	for i := 0; i < 20; i++ {
+		all = append(all, fmt.Sprintf("plan42-task%02d-1", i))
+	}
+
+	// every call blocks until its request is cancelled.
+	var calls atomic.Int64
+	started := make(chan struct{}, len(all)*2)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := p42.NewClient(server.URL)
+
+	const concurrency = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := GetJobs(ctx, &stubProvider{runningIDs: all}, client, tenantID, GetJobsOptions{FetchConcurrency: concurrency})
+		done <- err
+	}()
+
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrJobDataUnavailable) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected a cancelled ErrJobDataUnavailable error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetJobs didn't return after its context was cancelled")
+	}
+
+	// no job is fetched after the cancellation, so at most one call per worker was made.
+	if n := calls.Load(); n > concurrency {
+		t.Fatalf("expected at most %d API calls, got %d", concurrency, n)
+	}
+}
+
+func TestKillTaskJobs(t *testing.T) {
+	running := []string{"plan42-alpha-1", "plan42-alpha-2", "plan42-alphabet-1", "plan42-beta-1", "not-a-job"}
+
+	testCases := []struct {
+		name      string
+		taskID    string
+		turnIndex *int
+		all       bool
+		expected  []string
+		wantErr   bool
+	}{
+		{name: "single turn", taskID: "beta", expected: []string{"plan42-beta-1"}},
+		{name: "does not match task id prefixes", taskID: "alphabet", expected: []string{"plan42-alphabet-1"}},
+		{name: "multiple turns require all", taskID: "alpha", wantErr: true},
+		{name: "multiple turns with all", taskID: "alpha", all: true, expected: []string{"plan42-alpha-1", "plan42-alpha-2"}},
+		{name: "specific turn", taskID: "alpha", turnIndex: util.Pointer(2), expected: []string{"plan42-alpha-2"}},
+		{name: "unknown turn", taskID: "alpha", turnIndex: util.Pointer(3), wantErr: true},
+		{name: "unknown task", taskID: "gamma", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &stubProvider{runningIDs: running}
+
+			killed, err := KillTaskJobs(context.Background(), provider, tc.taskID, tc.turnIndex, tc.all)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got killed jobs %v", killed)
+				}
+				if len(provider.killedIDs) != 0 {
+					t.Fatalf("expected no jobs to be killed, got %v", provider.killedIDs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("KillTaskJobs returned error: %v", err)
+			}
+			if fmt.Sprint(provider.killedIDs) != fmt.Sprint(tc.expected) {
+				t.Fatalf("expected %v to be killed, got %v", tc.expected, provider.killedIDs)
+			}
+			if fmt.Sprint(killed) != fmt.Sprint(tc.expected) {
+				t.Fatalf("expected %v to be returned, got %v", tc.expected, killed)
+			}
+		})
+	}
+}
+
+func TestKillTaskJobsError(t *testing.T) {
+	provider := &stubProvider{
+		runningIDs: []string{"plan42-alpha-1"},
+		// providers name the job in their errors, as podman and apple container do.
+		killErr: func(jobID string) error {
+			return fmt.Errorf("failed to kill job %s: exit status 125", jobID)
+		},
+	}
+
+	killed, err := KillTaskJobs(context.Background(), provider, "alpha", nil, false)
+	if err == nil {
+		t.Fatalf("expected error, got killed jobs %v", killed)
+	}
+	if expected := "failed to kill job plan42-alpha-1: exit status 125"; err.Error() != expected {
+		t.Fatalf("expected error %q, got %q", expected, err.Error())
+	}
+	if len(killed) != 0 {
+		t.Fatalf("expected no jobs to be reported killed, got %v", killed)
+	}
+}
+
+func TestFindTaskJob(t *testing.T) {
+	all := []string{"plan42-alpha-1", "plan42-alpha-2", "plan42-alphabet-1", "plan42-beta-1", "not-a-job"}
+
+	testCases := []struct {
+		name      string
+		taskID    string
+		turnIndex *int
+		expected  string
+		errSubstr string
+	}{
+		{name: "single turn", taskID: "beta", expected: "plan42-beta-1"},
+		{name: "does not match task id prefixes", taskID: "alphabet", expected: "plan42-alphabet-1"},
+		{name: "multiple turns are listed", taskID: "alpha", errSubstr: "plan42-alpha-1, plan42-alpha-2"},
+		{name: "specific turn", taskID: "alpha", turnIndex: util.Pointer(2), expected: "plan42-alpha-2"},
+		{name: "unknown turn", taskID: "alpha", turnIndex: util.Pointer(3), errSubstr: "no job found for turn 3"},
+		{name: "unknown task", taskID: "gamma", errSubstr: "no jobs found for task gamma"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &stubProvider{allIDs: all}
+
+			jobID, err := FindTaskJob(context.Background(), provider, tc.taskID, tc.turnIndex)
+			if tc.errSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errSubstr) {
+					t.Fatalf("expected error containing %q, got job %q, error %v", tc.errSubstr, jobID, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindTaskJob returned error: %v", err)
+			}
+			if jobID != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, jobID)
+			}
+		})
+	}
+}
+
+func TestValidateJobID(t *testing.T) {
+	testCases := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "valid", id: "plan42-alpha-1", wantErr: false},
+		{name: "valid uuid task id", id: "plan42-0b7c8e4e-9a4f-4c1e-8f7a-2d3b4c5d6e7f-3", wantErr: false},
+		{name: "zero turn index", id: "plan42-alpha-0", wantErr: false},
+		{name: "max turn index", id: fmt.Sprintf("plan42-alpha-%d", MaxTurnIndex), wantErr: false},
+		{name: "missing prefix", id: "alpha-1", wantErr: true},
+		{name: "missing separator", id: "plan42-alpha", wantErr: true},
+		{name: "negative turn index", id: "plan42-alpha--1", wantErr: true},
+		{name: "empty task id", id: "plan42--1", wantErr: true},
+		{name: "empty task id without sign", id: "plan42-1", wantErr: true},
+		{name: "non-numeric turn index", id: "plan42-alpha-x", wantErr: true},
+		{name: "empty turn index", id: "plan42-alpha-", wantErr: true},
+		{name: "oversized turn index", id: fmt.Sprintf("plan42-alpha-%d", MaxTurnIndex+1), wantErr: true},
+		{name: "overflowing turn index", id: "plan42-alpha-99999999999999999999", wantErr: true},
+		{name: "max length", id: "plan42-" + strings.Repeat("a", MaxJobIDLength-9) + "-1", wantErr: false},
+		{name: "too long", id: "plan42-" + strings.Repeat("a", MaxJobIDLength-8) + "-1", wantErr: true},
+		{name: "urn uuid task id", id: "plan42-urn:uuid:0b7c8e4e-9a4f-4c1e-8f7a-2d3b4c5d6e7f-3", wantErr: true},
+		{name: "braced uuid task id", id: "plan42-{0b7c8e4e-9a4f-4c1e-8f7a-2d3b4c5d6e7f}-3", wantErr: true},
+		{name: "pathological task id", id: "plan42-" + strings.Repeat("0b7c8e4e-9a4f-4c1e-8f7a-2d3b4c5d6e7f", 3) + "-1", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJobID(tc.id)
+			if tc.wantErr && err == nil {
+				t.Fatalf("ValidateJobID(%q) returned nil, expected error", tc.id)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("ValidateJobID(%q) returned error: %v", tc.id, err)
+			}
+		})
+	}
+}
+
+func TestMarshalJobsJSON(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("PST", -8*60*60))
+	jobs := []*Job{
+		{TaskID: "task-1", TurnIndex: 2, Running: true, TaskTitle: "Fix bug", CreatedDate: created},
+		{TaskID: "task-2", TurnIndex: 1, Running: false, FetchErr: errors.New("task not found")},
+	}
+
+	data, err := MarshalJobsJSON(jobs)
+	if err != nil {
+		t.Fatalf("MarshalJobsJSON returned error: %v", err)
+	}
+
+	expected := `[
+  {
+    "task_id": "task-1",
+    "turn_index": 2,
+    "running": true,
+    "title": "Fix bug",
+    "created_at": "2024-03-01T20:30:00Z"
+  },
+  {
+    "task_id": "task-2",
+    "turn_index": 1,
+    "running": false,
+    "title": "",
+    "created_at": null,
+    "error": "task not found"
+  }
+]`
+	if string(data) != expected {
+		t.Fatalf("unexpected JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func TestMarshalJobsJSONEmpty(t *testing.T) {
+	data, err := MarshalJobsJSON(nil)
+	if err != nil {
+		t.Fatalf("MarshalJobsJSON returned error: %v", err)
+	}
+	if string(data) != "[]" {
+		t.Fatalf("expected empty array, got %s", data)
+	}
+}
@@ -0,0 +1,162 @@
+package p42runtime
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxLogFiles is the number of rolled over files kept for a job log when JobOptions.MaxLogFiles isn't set.
+const DefaultMaxLogFiles = 5
+
+// RotatingFile is a log file that's rolled over when it reaches a maximum size. The file at path is renamed to
+// path.1, path.1 to path.2, and so on, dropping the oldest once there are more than keep, and writing continues in a
+// new file at path. It is safe for concurrent use, so a job's stdout and stderr can share one.
+type RotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+	size    int64
+}
+
+// NewRotatingFile creates the log file at path, truncating any existing file, that rolls over once it reaches maxSize
+// bytes and keeps up to keep rolled over files.
+func NewRotatingFile(path string, maxSize int64, keep int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("max log size must be positive")
+	}
+	if keep < 1 {
+		return nil, errors.New("number of log files to keep must be at least 1")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingFile{path: path, maxSize: maxSize, keep: keep, file: file}, nil
+}
+
+// Write writes p to the current file, rolling it over first if p would take it past the maximum size. p is never
+// split, so a file only exceeds the maximum size when a single write does.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// rotate rolls the current file over. The current file stays open until the new one has been created, so if any step
+// fails, writing carries on in the current file and the rollover is retried by the next write.
+func (f *RotatingFile) rotate() error {
+	for i := f.keep - 1; i >= 1; i-- {
+		err := os.Rename(RotatedLogPath(f.path, i), RotatedLogPath(f.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, RotatedLogPath(f.path, 1)); err != nil {
+		return err
+	}
+
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+	// writing has already moved on to the new file, so there's nothing to do if closing the old one fails.
+	_ = f.file.Close()
+	f.file = file
+	f.size = 0
+	return nil
+}
+
+// CreateJobLog creates the log file for a job at path. If opts.MaxLogSize is set, the file is rolled over when it
+// reaches that size, keeping opts.MaxLogFiles, or DefaultMaxLogFiles, rolled over files. Otherwise the whole output
+// of the job goes to the one file.
+func CreateJobLog(path string, opts JobOptions) (io.WriteCloser, error) {
+	if opts.MaxLogSize <= 0 {
+		return os.Create(path)
+	}
+	return NewRotatingFile(path, opts.MaxLogSize, cmp.Or(opts.MaxLogFiles, DefaultMaxLogFiles))
+}
+
+// RotatedLogPath returns the path of the nth rolled over file of the log at path, where 1 is the most recent.
+func RotatedLogPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// JobLogFiles returns the files holding the job log at path, oldest first: its rolled over files followed by path
+// itself.
+func JobLogFiles(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	rotated := make(map[string]int)
+	for _, name := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(name, path+"."))
+		if err == nil && n > 0 {
+			rotated[name] = n
+		}
+	}
+	ret := slices.SortedFunc(maps.Keys(rotated), func(a, b string) int {
+		return cmp.Compare(rotated[b], rotated[a])
+	})
+	return append(ret, path), nil
+}
+
+// IsRotatedLog reports whether name is the name of a rolled over log file, e.g. "plan42-task-0.1", rather than a
+// job's current log.
+func IsRotatedLog(name string) bool {
+	idx := strings.LastIndex(name, ".")
+	if idx == -1 {
+		return false
+	}
+	n, err := strconv.Atoi(name[idx+1:])
+	return err == nil && n > 0 && ValidateJobID(name[:idx]) == nil
+}
+
+// RemoveJobLog removes the job log at path along with its rolled over files. A log that doesn't exist isn't an error.
+func RemoveJobLog(path string) error {
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	for _, name := range append([]string{path}, rotated...) {
+		if name != path && !IsRotatedLog(filepath.Base(name)) {
+			continue
+		}
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
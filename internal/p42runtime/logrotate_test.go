@@ -0,0 +1,191 @@
+package p42runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan42-task-0")
+	f, err := CreateJobLog(path, JobOptions{MaxLogSize: 10, MaxLogFiles: 2})
+	if err != nil {
+		t.Fatalf("CreateJobLog returned error: %v", err)
+	}
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// every write after the first goes past 10 bytes, so each line ends up in its own file, and the oldest is dropped.
+	expected := map[string]string{
+		path:                    "line-4\n",
+		RotatedLogPath(path, 1): "line-3\n",
+		RotatedLogPath(path, 2): "line-2\n",
+	}
+	for p, content := range expected {
+		if got := readLog(t, p); got != content {
+			t.Errorf("expected %s to contain %q, got %q", filepath.Base(p), content, got)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d log files, got %d", len(expected), len(entries))
+	}
+}
+
+func TestRotatingFileRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan42-task-0")
+	f, err := NewRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingFile returned error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// a non-empty directory where the rolled over file goes makes the rename fail.
+	blocker := RotatedLogPath(path, 1)
+	if err := os.MkdirAll(filepath.Join(blocker, "dir"), 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", blocker, err)
+	}
+
+	if _, err := f.Write([]byte("line-1\n")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	_, err = f.Write([]byte("line-2\n"))
+	if err == nil {
+		t.Fatal("expected Write to fail when the log can't be rolled over")
+	}
+	if errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected the log to stay open, got %v", err)
+	}
+
+	// once the rename can succeed, writing carries on.
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatalf("failed to remove %s: %v", blocker, err)
+	}
+	if _, err := f.Write([]byte("line-3\n")); err != nil {
+		t.Fatalf("Write after a failed rollover returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if got := readLog(t, path); got != "line-3\n" {
+		t.Errorf("expected %q in the current log, got %q", "line-3\n", got)
+	}
+	if got := readLog(t, blocker); got != "line-1\n" {
+		t.Errorf("expected %q in the rolled over log, got %q", "line-1\n", got)
+	}
+}
+
+func TestCreateJobLogWithoutRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan42-task-0")
+	f, err := CreateJobLog(path, JobOptions{})
+	if err != nil {
+		t.Fatalf("CreateJobLog returned error: %v", err)
+	}
+	for range 3 {
+		if _, err := f.Write([]byte("0123456789")); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if got := readLog(t, path); len(got) != 30 {
+		t.Fatalf("expected the whole output in one file, got %d bytes", len(got))
+	}
+	if _, err := os.Stat(RotatedLogPath(path, 1)); !os.IsNotExist(err) {
+		t.Fatalf("expected no rolled over file, got %v", err)
+	}
+}
+
+func TestIsRotatedLog(t *testing.T) {
+	testCases := map[string]bool{
+		"plan42-task-0":      false,
+		"plan42-task-0.1":    true,
+		"plan42-task-12.10":  true,
+		"plan42-task-0.0":    false,
+		"plan42-task-0.old":  false,
+		"plan42-task.v2-3":   false,
+		"plan42-task.v2-3.1": true,
+		"notes.1":            false,
+	}
+	for name, expected := range testCases {
+		if got := IsRotatedLog(name); got != expected {
+			t.Errorf("IsRotatedLog(%q) = %v, expected %v", name, got, expected)
+		}
+	}
+}
+
+func TestJobLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan42-task-0")
+	names := []string{
+		"plan42-task-0", "plan42-task-0.1", "plan42-task-0.2", "plan42-task-0.10",
+		"plan42-task-0.0", "plan42-task-0.bak", "plan42-task-1.3",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("log"), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	files, err := JobLogFiles(path)
+	if err != nil {
+		t.Fatalf("JobLogFiles returned error: %v", err)
+	}
+	expected := []string{RotatedLogPath(path, 10), RotatedLogPath(path, 2), RotatedLogPath(path, 1), path}
+	if !slices.Equal(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestRemoveJobLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan42-task-0")
+	for _, name := range []string{"plan42-task-0", "plan42-task-0.1", "plan42-task-0.2", "plan42-task-0.bak", "plan42-task-1"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("log"), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	if err := RemoveJobLog(path); err != nil {
+		t.Fatalf("RemoveJobLog returned error: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+	if expected := []string{"plan42-task-0.bak", "plan42-task-1"}; !slices.Equal(remaining, expected) {
+		t.Fatalf("expected %v to remain, got %v", expected, remaining)
+	}
+
+	if err := RemoveJobLog(path); err != nil {
+		t.Fatalf("RemoveJobLog of a missing log returned error: %v", err)
+	}
+}
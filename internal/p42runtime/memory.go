@@ -0,0 +1,66 @@
+package p42runtime
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+// CheckJobMemory returns an error if opts requests more memory than the host has. It does nothing if
+// opts.SkipMemoryCheck is set, no memory was requested, or host memory can't be determined.
+func CheckJobMemory(opts JobOptions) error {
+	if opts.SkipMemoryCheck || opts.MemoryInGB <= 0 {
+		return nil
+	}
+
+	// the check is best effort, so if host memory is unknown, let the runtime decide.
+	hostBytes, err := hostMemoryBytes()
+	if err == nil && uint64(opts.MemoryInGB)*bytesPerGB > hostBytes {
+		return fmt.Errorf(
+			"job requests %dG of memory, but the host only has %.1fG",
+			opts.MemoryInGB,
+			float64(hostBytes)/bytesPerGB,
+		)
+	}
+	return nil
+}
+
+// parseMeminfo returns the MemTotal value, in bytes, from the contents of /proc/meminfo.
+func parseMeminfo(data []byte) (uint64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal value %q: %w", fields[1], err)
+		}
+		if len(fields) > 2 && fields[2] != "kB" {
+			return 0, fmt.Errorf("unexpected MemTotal unit %q", fields[2])
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("MemTotal not found in meminfo")
+}
+
+// parseSysctlMemsize parses the output of `sysctl -n hw.memsize`, which is the host memory in bytes.
+func parseSysctlMemsize(output []byte) (uint64, error) {
+	value := strings.TrimSpace(string(output))
+	// tolerate the non -n form, e.g. "hw.memsize: 17179869184".
+	value = strings.TrimSpace(strings.TrimPrefix(value, "hw.memsize:"))
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hw.memsize value %q: %w", value, err)
+	}
+	return n, nil
+}
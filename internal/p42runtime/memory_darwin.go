@@ -0,0 +1,14 @@
+package p42runtime
+
+import (
+	"os/exec"
+)
+
+// hostMemoryBytes returns the total physical memory of the host.
+func hostMemoryBytes() (uint64, error) {
+	output, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+	if err != nil {
+		return 0, err
+	}
+	return parseSysctlMemsize(output)
+}
@@ -0,0 +1,14 @@
+package p42runtime
+
+import (
+	"os"
+)
+
+// hostMemoryBytes returns the total physical memory of the host.
+func hostMemoryBytes() (uint64, error) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	return parseMeminfo(data)
+}
@@ -0,0 +1,12 @@
+//go:build !darwin && !linux
+
+package p42runtime
+
+import (
+	"errors"
+)
+
+// hostMemoryBytes returns the total physical memory of the host.
+func hostMemoryBytes() (uint64, error) {
+	return 0, errors.New("reading host memory is not supported on this platform")
+}
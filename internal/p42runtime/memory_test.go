@@ -0,0 +1,95 @@
+package p42runtime
+
+import (
+	"testing"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected uint64
+		wantErr  bool
+	}{
+		{
+			name:     "typical",
+			data:     "MemTotal:       16318812 kB\nMemFree:         1234567 kB\nMemAvailable:    9876543 kB\n",
+			expected: 16318812 * 1024,
+		},
+		{
+			name:     "not first line",
+			data:     "Something:  1 kB\nMemTotal: 2048 kB\n",
+			expected: 2048 * 1024,
+		},
+		{name: "missing", data: "MemFree: 1234 kB\n", wantErr: true},
+		{name: "invalid number", data: "MemTotal: lots kB\n", wantErr: true},
+		{name: "unexpected unit", data: "MemTotal: 2048 MB\n", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseMeminfo([]byte(tc.data))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMeminfo returned error: %v", err)
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseSysctlMemsize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected uint64
+		wantErr  bool
+	}{
+		{name: "value only", output: "17179869184\n", expected: 17179869184},
+		{name: "with name", output: "hw.memsize: 34359738368\n", expected: 34359738368},
+		{name: "empty", output: "", wantErr: true},
+		{name: "garbage", output: "unknown oid 'hw.memsize'", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSysctlMemsize([]byte(tc.output))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSysctlMemsize returned error: %v", err)
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckJobMemory(t *testing.T) {
+	if _, err := hostMemoryBytes(); err != nil {
+		t.Skipf("host memory unavailable: %v", err)
+	}
+
+	if err := CheckJobMemory(JobOptions{MemoryInGB: 1 << 20}); err == nil {
+		t.Fatalf("expected an error for a 1PB memory request")
+	}
+	if err := CheckJobMemory(JobOptions{MemoryInGB: 1 << 20, SkipMemoryCheck: true}); err != nil {
+		t.Fatalf("expected the check to be skipped, got %v", err)
+	}
+	if err := CheckJobMemory(JobOptions{}); err != nil {
+		t.Fatalf("expected no error when no memory is requested, got %v", err)
+	}
+}
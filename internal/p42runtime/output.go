@@ -0,0 +1,118 @@
+package p42runtime
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// TailBuffer is an io.Writer that keeps the last size bytes written to it. It is safe for concurrent use, so a job's
+// stdout and stderr can share one.
+type TailBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func NewTailBuffer(size int) *TailBuffer {
+	return &TailBuffer{size: size}
+}
+
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(p)
+	if n >= t.size {
+		t.buf = append(t.buf[:0], p[n-t.size:]...)
+		return n, nil
+	}
+	if excess := len(t.buf) + n - t.size; excess > 0 {
+		t.buf = append(t.buf[:0], t.buf[excess:]...)
+	}
+	t.buf = append(t.buf, p...)
+	return n, nil
+}
+
+// String returns the bytes retained by the buffer.
+func (t *TailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
+
+// PrefixWriter is an io.Writer that copies each line written to it to an underlying writer, preceded by a prefix, so
+// the output of several jobs can share a terminal. Partial lines are held until they are completed or Flush is called.
+// Errors writing to the underlying writer are ignored, since the copy mustn't interrupt the job. It is safe for
+// concurrent use, so a job's stdout and stderr can share one.
+type PrefixWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	prefix  []byte
+	partial []byte
+}
+
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+func (pw *PrefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.partial = append(pw.partial, p...)
+	rest := pw.partial
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		pw.writeLine(rest[:i+1])
+		rest = rest[i+1:]
+	}
+	pw.partial = append(pw.partial[:0], rest...)
+	return len(p), nil
+}
+
+// Flush writes any partial line, terminated by a newline.
+func (pw *PrefixWriter) Flush() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if len(pw.partial) == 0 {
+		return
+	}
+	pw.writeLine(append(pw.partial, '\n'))
+	pw.partial = pw.partial[:0]
+}
+
+// writeLine writes line with a single Write, so lines from concurrent writers sharing w don't interleave.
+func (pw *PrefixWriter) writeLine(line []byte) {
+	buf := make([]byte, 0, len(pw.prefix)+len(line))
+	buf = append(buf, pw.prefix...)
+	buf = append(buf, line...)
+	_, _ = pw.w.Write(buf)
+}
+
+// teeOutput returns a writer that writes to both the job's log file and w. w may be nil.
+func teeOutput(logFile io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return logFile
+	}
+	return io.MultiWriter(logFile, w)
+}
+
+// TeeJobOutput returns the writers a provider should use for a job's stdout and stderr when its output is also
+// written to logFile.
+func TeeJobOutput(logFile io.Writer, opts JobOptions) (stdout io.Writer, stderr io.Writer) {
+	return teeOutput(logFile, opts.Stdout), teeOutput(logFile, opts.Stderr)
+}
+
+// ExitCode returns the exit code of the process that produced err, if err shows that the process exited with a
+// non-zero status.
+func ExitCode(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
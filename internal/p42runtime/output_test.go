@@ -0,0 +1,56 @@
+package p42runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTailBuffer(t *testing.T) {
+	testCases := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{name: "empty", expected: ""},
+		{name: "under limit", writes: []string{"ab", "cd"}, expected: "abcd"},
+		{name: "exactly limit", writes: []string{"abcdefgh"}, expected: "abcdefgh"},
+		{name: "trims oldest", writes: []string{"abcdef", "ghij"}, expected: "cdefghij"},
+		{name: "single large write", writes: []string{"0123456789abc"}, expected: "56789abc"},
+		{name: "many small writes", writes: strings.Split("abcdefghijklmnop", ""), expected: "ijklmnop"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := NewTailBuffer(8)
+			for _, w := range tc.writes {
+				n, err := buf.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if got := buf.String(); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewPrefixWriter(&out, "[job-1] ")
+	for _, s := range []string{"first line\nsec", "ond line\n", "\nthird", " line"} {
+		if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	expected := "[job-1] first line\n[job-1] second line\n[job-1] \n"
+	if got := out.String(); got != expected {
+		t.Fatalf("expected %q before flush, got %q", expected, got)
+	}
+
+	w.Flush()
+	expected += "[job-1] third line\n"
+	if got := out.String(); got != expected {
+		t.Fatalf("expected %q after flush, got %q", expected, got)
+	}
+}
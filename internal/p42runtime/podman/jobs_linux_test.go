@@ -0,0 +1,48 @@
+package podman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	runningJobID   = "plan42-6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab-1"
+	completedJobID = "plan42-6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab-0"
+)
+
+// On Linux podman runs containers natively, so job listing works without a podman machine.
+func TestJobListingOnLinux(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "podman")
+	script := `#!/bin/sh
+if [ "$1" = "ps" ]; then
+	echo "` + runningJobID + `"
+	echo "unrelated-container"
+	exit 0
+fi
+echo "unexpected command: $*" >&2
+exit 1
+`
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+
+	logDir := filepath.Join(dir, "logs")
+	require.NoError(t, os.MkdirAll(logDir, 0o755))
+	for _, id := range []string{runningJobID, completedJobID, "not-a-job"} {
+		require.NoError(t, os.WriteFile(filepath.Join(logDir, id), nil, 0o600))
+	}
+
+	provider := NewProvider(binPath, logDir)
+
+	running, err := provider.GetRunningJobIDs(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []string{runningJobID}, running)
+
+	completed, err := p42runtime.GetCompletedJobIDs(t.Context(), provider)
+	require.NoError(t, err)
+	require.Equal(t, []string{completedJobID}, completed)
+}
@@ -0,0 +1,121 @@
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+)
+
+// ErrMachineNotRunning is returned when podman runs containers in a VM (the podman machine) and the VM isn't running.
+var ErrMachineNotRunning = fmt.Errorf(
+	"%w: the podman machine is not running; run 'podman machine start'",
+	p42runtime.ErrRuntimeUnavailable,
+)
+
+// checkMachine verifies that the podman machine is running on platforms where podman runs containers in one. Once the
+// machine has been seen running it isn't checked again.
+func (p *Provider) checkMachine(ctx context.Context) error {
+	if !p.usesMachine {
+		return nil
+	}
+
+	p.machineMu.Lock()
+	defer p.machineMu.Unlock()
+	if p.machineRunning {
+		return nil
+	}
+
+	state, err := p.machineState(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(state, "running") {
+		if state == "" {
+			state = "unknown"
+		}
+		return fmt.Errorf("%w (machine state: %s)", ErrMachineNotRunning, state)
+	}
+	p.machineRunning = true
+	return nil
+}
+
+// machineState returns the state of the default podman machine. It asks `podman machine info` first, and falls back
+// to `podman machine list`, whose json output is stable across versions, if the info template isn't supported.
+func (p *Provider) machineState(ctx context.Context) (string, error) {
+	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
+	//     podmanPath is user-configurable and the remaining arguments are constant.
+	output, err := exec.CommandContext(ctx, p.podmanPath, "machine", "info", "--format", "{{.Host.MachineState}}").Output()
+	if err == nil {
+		return strings.TrimSpace(string(output)), nil
+	}
+	err = p42runtime.WrapExecError(p.podmanPath, err)
+	if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+		return "", err
+	}
+
+	state, listErr := p.listMachineState(ctx)
+	if listErr != nil {
+		return "", fmt.Errorf("failed to get podman machine state: %w", errors.Join(err, listErr))
+	}
+	return state, nil
+}
+
+// machineListEntry is an entry of `podman machine list --format json`. Older podman releases mark the default machine
+// with a "*" suffix on its name and report a running machine through LastUp instead of Running.
+type machineListEntry struct {
+	Name     string
+	Default  bool
+	Running  bool
+	Starting bool
+	LastUp   string
+}
+
+func (e machineListEntry) isDefault() bool {
+	return e.Default || strings.HasSuffix(e.Name, "*")
+}
+
+func (e machineListEntry) state() string {
+	switch {
+	case e.Running || strings.EqualFold(e.LastUp, "Currently running"):
+		return "running"
+	case e.Starting:
+		return "starting"
+	default:
+		return "stopped"
+	}
+}
+
+func (p *Provider) listMachineState(ctx context.Context) (string, error) {
+	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
+	//     podmanPath is user-configurable and the remaining arguments are constant.
+	output, err := exec.CommandContext(ctx, p.podmanPath, "machine", "list", "--format", "json").Output()
+	if err != nil {
+		return "", fmt.Errorf("podman machine list failed: %w", p42runtime.WrapExecError(p.podmanPath, err))
+	}
+	return parseMachineList(output)
+}
+
+// parseMachineList returns the state of the default machine in the output of `podman machine list --format json`.
+func parseMachineList(output []byte) (string, error) {
+	var entries []machineListEntry
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return "", fmt.Errorf("unable to parse podman machine list: %w", err)
+	}
+	if len(entries) == 0 {
+		return "not created", nil
+	}
+	for _, entry := range entries {
+		if entry.isDefault() {
+			return entry.state(), nil
+		}
+	}
+	if len(entries) == 1 {
+		return entries[0].state(), nil
+	}
+	return "no default machine", nil
+}
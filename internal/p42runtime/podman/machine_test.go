@@ -0,0 +1,157 @@
+package podman
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMachineBinary writes a stand-in for podman that reports state for "podman machine info" and counts the calls
+// in a file next to it.
+func fakeMachineBinary(t *testing.T, state string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "podman")
+	countPath := filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+if [ "$1" = "machine" ] && [ "$2" = "info" ]; then
+	echo x >> "` + countPath + `"
+	echo "` + state + `"
+	exit 0
+fi
+exit 0
+`
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+	return binPath, countPath
+}
+
+func machineInfoCalls(t *testing.T, countPath string) int {
+	t.Helper()
+	data, err := os.ReadFile(countPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	}
+	require.NoError(t, err)
+	return len(data) / 2
+}
+
+func TestCheckMachineRunning(t *testing.T) {
+	binPath, countPath := fakeMachineBinary(t, "Running")
+	provider := NewProvider(binPath, "", WithMachineCheck(true))
+
+	require.NoError(t, provider.PullImage(t.Context(), "agent:latest"))
+	require.NoError(t, provider.RunJob(t.Context(), p42runtime.JobOptions{Image: "agent:latest"}))
+
+	// the running state is cached after the first check.
+	require.Equal(t, 1, machineInfoCalls(t, countPath))
+}
+
+func TestCheckMachineStopped(t *testing.T) {
+	binPath, countPath := fakeMachineBinary(t, "Stopped")
+	provider := NewProvider(binPath, "", WithMachineCheck(true))
+
+	err := provider.RunJob(t.Context(), p42runtime.JobOptions{Image: "agent:latest"})
+	require.ErrorIs(t, err, ErrMachineNotRunning)
+	require.ErrorIs(t, err, p42runtime.ErrRuntimeUnavailable)
+	require.Contains(t, err.Error(), "podman machine start")
+	require.Contains(t, err.Error(), "Stopped")
+
+	// a stopped machine is checked again, since it may have been started since.
+	require.ErrorIs(t, provider.PullImage(t.Context(), "agent:latest"), ErrMachineNotRunning)
+	require.Equal(t, 2, machineInfoCalls(t, countPath))
+}
+
+func TestCheckMachineSkippedWithoutMachine(t *testing.T) {
+	binPath, countPath := fakeMachineBinary(t, "Stopped")
+	provider := NewProvider(binPath, "", WithMachineCheck(false))
+
+	require.NoError(t, provider.PullImage(t.Context(), "agent:latest"))
+	require.Zero(t, machineInfoCalls(t, countPath))
+}
+
+// fakeMachineListBinary writes a stand-in for a podman whose "podman machine info" doesn't support the MachineState
+// template, and that prints list for "podman machine list".
+func fakeMachineListBinary(t *testing.T, list string) string {
+	t.Helper()
+	binPath := filepath.Join(t.TempDir(), "podman")
+	script := `#!/bin/sh
+if [ "$1" = "machine" ] && [ "$2" = "info" ]; then
+	echo "Error: template: info:1:7: executing \"info\" at <.Host.MachineState>: can't evaluate field MachineState" >&2
+	exit 125
+fi
+if [ "$1" = "machine" ] && [ "$2" = "list" ]; then
+	cat <<'LIST'
+` + list + `
+LIST
+	exit 0
+fi
+exit 0
+`
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+	return binPath
+}
+
+func TestCheckMachineListFallback(t *testing.T) {
+	testCases := []struct {
+		name      string
+		list      string
+		wantState string
+	}{
+		{
+			name:      "running",
+			list:      `[{"Name":"podman-machine-default","Default":true,"Running":true,"Starting":false,"LastUp":"2024-01-02T03:04:05Z"}]`,
+			wantState: "running",
+		},
+		{
+			name:      "stopped",
+			list:      `[{"Name":"other","Default":false,"Running":true},{"Name":"podman-machine-default","Default":true,"Running":false}]`,
+			wantState: "stopped",
+		},
+		{
+			name:      "starting",
+			list:      `[{"Name":"podman-machine-default","Default":true,"Running":false,"Starting":true}]`,
+			wantState: "starting",
+		},
+		{
+			name:      "old format running",
+			list:      `[{"Name":"podman-machine-default*","LastUp":"Currently running"}]`,
+			wantState: "running",
+		},
+		{
+			name:      "old format stopped",
+			list:      `[{"Name":"podman-machine-default*","LastUp":"3 hours ago"}]`,
+			wantState: "stopped",
+		},
+		{
+			name:      "no machines",
+			list:      `[]`,
+			wantState: "not created",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := NewProvider(fakeMachineListBinary(t, tc.list), "", WithMachineCheck(true))
+			err := provider.PullImage(t.Context(), "agent:latest")
+			if tc.wantState == "running" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrMachineNotRunning)
+			require.Contains(t, err.Error(), "machine state: "+tc.wantState)
+		})
+	}
+}
+
+func TestCheckMachineListFallbackFails(t *testing.T) {
+	provider := NewProvider(fakeMachineListBinary(t, "not json"), "", WithMachineCheck(true))
+	err := provider.PullImage(t.Context(), "agent:latest")
+	require.ErrorContains(t, err, "failed to get podman machine state")
+	require.ErrorContains(t, err, "unable to parse podman machine list")
+	require.NotErrorIs(t, err, ErrMachineNotRunning)
+}
@@ -10,8 +10,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/util"
@@ -20,18 +23,46 @@ import (
 const jobPrefix = "plan42-"
 
 type Provider struct {
-	podmanPath string
-	logDir     string
+	podmanPath   string
+	logDir       string
+	registryAuth p42runtime.RegistryAuth
+
+	// usesMachine is set where podman runs containers in a VM that must be started first (macOS).
+	usesMachine    bool
+	machineMu      sync.Mutex
+	machineRunning bool
 }
 
-func NewProvider(podmanPath string, logDir string) *Provider {
+type Option func(p *Provider)
+
+// WithRegistryAuth sets the credentials used to log in to private registries before pulling images.
+func WithRegistryAuth(auth p42runtime.RegistryAuth) Option {
+	return func(p *Provider) {
+		p.registryAuth = auth
+	}
+}
+
+// WithMachineCheck sets whether the provider checks that the podman machine is running before pulling images and
+// running jobs. It defaults to enabled on macOS, where podman runs containers in a VM, and disabled elsewhere.
+func WithMachineCheck(enabled bool) Option {
+	return func(p *Provider) {
+		p.usesMachine = enabled
+	}
+}
+
+func NewProvider(podmanPath string, logDir string, options ...Option) *Provider {
 	if podmanPath == "" {
 		podmanPath = "podman"
 	}
-	return &Provider{
-		podmanPath: podmanPath,
-		logDir:     logDir,
+	ret := &Provider{
+		podmanPath:  podmanPath,
+		logDir:      logDir,
+		usesMachine: runtime.GOOS == "darwin",
 	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
 }
 
 func (p *Provider) Name() string {
@@ -44,18 +75,74 @@ func (p *Provider) IsInstalled() bool {
 }
 
 func (p *Provider) PullImage(ctx context.Context, image string) error {
+	if err := p.checkMachine(ctx); err != nil {
+		return err
+	}
+	if err := p.login(ctx, image); err != nil {
+		return err
+	}
+
 	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
 	//     podmanPath is user-configurable. image is validated before reaching this method.
 	cmd := exec.CommandContext(ctx, p.podmanPath, "pull", image)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		err = p42runtime.WrapExecError(p.podmanPath, err)
+		if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+			return err
+		}
 		return fmt.Errorf("failed to pull image %s: %w\n%s", image, err, string(output))
 	}
 	return nil
 }
 
+// login logs in to the registry of image if credentials are configured for it. The password is passed on stdin so
+// it doesn't show up in the process list.
+func (p *Provider) login(ctx context.Context, image string) error {
+	if len(p.registryAuth) == 0 {
+		return nil
+	}
+
+	host, creds, ok, err := p.registryAuth.Lookup(image)
+	if err != nil || !ok {
+		return err
+	}
+
+	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
+	//     podmanPath is user-configurable. host is validated by Lookup.
+	cmd := exec.CommandContext(ctx, p.podmanPath, loginArgs(host, creds)...)
+	cmd.Stdin = strings.NewReader(creds.Password)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = p42runtime.WrapExecError(p.podmanPath, err)
+		if errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+			return err
+		}
+		return fmt.Errorf("failed to log in to registry %s: %w\n%s", host, err, string(output))
+	}
+	return nil
+}
+
+// loginArgs returns the podman arguments that log in to host with creds. The password is read from stdin.
+func loginArgs(host string, creds p42runtime.RegistryCredentials) []string {
+	return []string{"login", "--username", creds.Username, "--password-stdin", host}
+}
+
 func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error {
-	args := []string{"run", "--rm"}
+	if err := p42runtime.ValidateJobArgs(opts); err != nil {
+		return err
+	}
+	if err := p42runtime.CheckJobMemory(opts); err != nil {
+		return err
+	}
+	if err := p.checkMachine(ctx); err != nil {
+		return err
+	}
+
+	args := []string{"run"}
+	if !opts.KeepOnExit {
+		args = append(args, "--rm")
+	}
 
 	if opts.CPUs > 0 {
 		args = append(args, "--cpus", strconv.Itoa(opts.CPUs))
@@ -72,12 +159,16 @@ func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error
 	if opts.Entrypoint != "" {
 		args = append(args, "--entrypoint", opts.Entrypoint)
 	}
+	args = append(args, p42runtime.EnvArgs(opts.Env)...)
+	args = append(args, p42runtime.MountArgs(opts.Mounts)...)
+	args = append(args, p42runtime.NetworkArgs(opts.Network)...)
 
 	args = append(args, opts.Image)
 	args = append(args, opts.Args...)
 
 	// #nosec G204: Subprocess launched with a potential tainted input or cmd arguments
-	//     podmanPath is user-configurable and opts are validated before invocation.
+	//     podmanPath is user-configurable. The image is validated before invocation, and the rest of the job
+	//     options are validated by ValidateJobArgs above.
 	cmd := exec.CommandContext(ctx, p.podmanPath, args...)
 	cmd.Stdin = opts.Stdin
 
@@ -86,19 +177,21 @@ func (p *Provider) RunJob(ctx context.Context, opts p42runtime.JobOptions) error
 		if err := os.MkdirAll(p.logDir, 0o755); err != nil {
 			return fmt.Errorf("failed to create log directory: %w", err)
 		}
-		logFile, err := os.Create(logPath)
+		if err := p42runtime.CheckLogDiskSpace(p.logDir, opts.MinFreeDiskBytes); err != nil {
+			return err
+		}
+		logFile, err := p42runtime.CreateJobLog(logPath, opts)
 		if err != nil {
 			return fmt.Errorf("failed to create log file: %w", err)
 		}
 		defer logFile.Close()
-		cmd.Stdout = logFile
-		cmd.Stderr = logFile
+		cmd.Stdout, cmd.Stderr = p42runtime.TeeJobOutput(logFile, opts)
 	} else {
 		cmd.Stdout = opts.Stdout
 		cmd.Stderr = opts.Stderr
 	}
 
-	return cmd.Run()
+	return p42runtime.WrapExecError(p.podmanPath, cmd.Run())
 }
 
 func (p *Provider) KillJob(ctx context.Context, jobID string) error {
@@ -110,9 +203,10 @@ func (p *Provider) KillJob(ctx context.Context, jobID string) error {
 
 	err := cmd.Run()
 	if err != nil {
+		err = fmt.Errorf("failed to kill job %s: %w", jobID, err)
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			panic(util.ExitCode(exitErr.ExitCode()))
+			return util.WithExitCode(util.ExitCode(exitErr.ExitCode()), err)
 		}
 		return err
 	}
@@ -168,7 +262,7 @@ func (p *Provider) GetAllJobIDs(ctx context.Context) ([]string, error) {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasPrefix(name, jobPrefix) {
+		if !strings.HasPrefix(name, jobPrefix) || p42runtime.IsRotatedLog(name) {
 			continue
 		}
 		ids = append(ids, name)
@@ -178,22 +272,7 @@ func (p *Provider) GetAllJobIDs(ctx context.Context) ([]string, error) {
 }
 
 func (p *Provider) ValidateJobID(jobID string) error {
-	if !strings.HasPrefix(jobID, jobPrefix) {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	trimmed := strings.TrimPrefix(jobID, jobPrefix)
-	idx := strings.LastIndex(trimmed, "-")
-	if idx == -1 {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	_, err := strconv.Atoi(trimmed[idx+1:])
-	if err != nil {
-		return fmt.Errorf("invalid job id: %s", jobID)
-	}
-
-	return nil
+	return p42runtime.ValidateJobID(jobID)
 }
 
 func (p *Provider) DeleteJobLog(jobID string) error {
@@ -205,12 +284,21 @@ func (p *Provider) DeleteJobLog(jobID string) error {
 		return nil
 	}
 
-	logPath := filepath.Join(p.logDir, jobID)
+	return p42runtime.RemoveJobLog(filepath.Join(p.logDir, jobID))
+}
 
-	err := os.Remove(logPath)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return err
+func (p *Provider) JobLogModTime(jobID string) (time.Time, error) {
+	if err := p.ValidateJobID(jobID); err != nil {
+		return time.Time{}, err
 	}
 
-	return nil
+	if p.logDir == "" {
+		return time.Time{}, fmt.Errorf("no log directory configured")
+	}
+
+	info, err := os.Stat(filepath.Join(p.logDir, jobID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
 }
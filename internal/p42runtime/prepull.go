@@ -0,0 +1,33 @@
+package p42runtime
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PrePullImages pulls images in parallel so they're cached before the first job that uses them arrives. Failures are
+// logged as warnings, since the image is pulled again when a job needs it. It returns once every pull has finished.
+func PrePullImages(ctx context.Context, provider Provider, images []string) {
+	if len(images) == 0 {
+		return
+	}
+
+	slog.InfoContext(ctx, "pre-pulling images", "count", len(images))
+	var wg sync.WaitGroup
+	for _, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := provider.PullImage(ctx, image)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to pre-pull image", "image", image, "error", err)
+				return
+			}
+			slog.InfoContext(ctx, "pre-pulled image", "image", image, "duration", time.Since(start))
+		}()
+	}
+	wg.Wait()
+}
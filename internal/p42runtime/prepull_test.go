@@ -0,0 +1,62 @@
+package p42runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pullRecorder is a Provider that records the images it's asked to pull.
+type pullRecorder struct {
+	stubProvider
+	mu       sync.Mutex
+	pulled   []string
+	failures map[string]error
+	// release, if set, blocks every pull until it is closed.
+	release chan struct{}
+}
+
+func (p *pullRecorder) PullImage(_ context.Context, image string) error {
+	p.mu.Lock()
+	p.pulled = append(p.pulled, image)
+	p.mu.Unlock()
+	if p.release != nil {
+		<-p.release
+	}
+	return p.failures[image]
+}
+
+func TestPrePullImages(t *testing.T) {
+	provider := &pullRecorder{
+		failures: map[string]error{"ghcr.io/plan42-ai/missing:1": errors.New("not found")},
+		release:  make(chan struct{}),
+	}
+	images := []string{"ghcr.io/plan42-ai/agent:1", "ghcr.io/plan42-ai/missing:1", "docker.io/library/alpine:3"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		PrePullImages(context.Background(), provider, images)
+	}()
+
+	// every pull starts before any finishes.
+	require.Eventually(t, func() bool {
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		return len(provider.pulled) == len(images)
+	}, 5*time.Second, time.Millisecond)
+	close(provider.release)
+	<-done
+
+	require.ElementsMatch(t, images, provider.pulled)
+}
+
+func TestPrePullImagesEmpty(t *testing.T) {
+	provider := &pullRecorder{}
+	PrePullImages(context.Background(), provider, nil)
+	require.Empty(t, provider.pulled)
+}
@@ -6,6 +6,7 @@ package p42runtime
 import (
 	"context"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -14,6 +15,15 @@ const (
 	RuntimePodman = "podman"
 )
 
+// NormalizeRuntime returns the runtime name configured as runtimeName, lower cased, or DefaultRuntime if it isn't set.
+func NormalizeRuntime(runtimeName string) string {
+	runtimeName = strings.ToLower(strings.TrimSpace(runtimeName))
+	if runtimeName == "" {
+		return DefaultRuntime
+	}
+	return runtimeName
+}
+
 // Provider defines the interface for job runtime implementations.
 // Each supported runtime (Apple container, Podman) must implement this interface.
 type Provider interface {
@@ -39,8 +49,11 @@ type Provider interface {
 	// ValidateJobID checks if the given job ID is valid for this runtime.
 	ValidateJobID(jobID string) error
 
-	// DeleteJobLog removes the log file for the specified job.
+	// DeleteJobLog removes the log file for the specified job, along with any rolled over files.
 	DeleteJobLog(jobID string) error
+
+	// JobLogModTime returns the last modification time of the log file for the specified job.
+	JobLogModTime(jobID string) (time.Time, error)
 }
 
 // JobOptions specifies the configuration for running a job.
@@ -54,6 +67,39 @@ type JobOptions struct {
 	Stdin      io.Reader
 	Stdout     io.Writer
 	Stderr     io.Writer
+
+	// Env holds environment variables to set in the container. Names must be valid identifiers.
+	Env map[string]string
+
+	// Mounts are host paths mounted into the container.
+	Mounts []Mount
+
+	// Network is the network the container is attached to: NetworkNone, NetworkHost, NetworkBridge, or the name of
+	// a user defined network. Empty uses the runtime default.
+	Network string
+
+	// KeepOnExit keeps the container after it exits, rather than removing it, so it can be inspected. Kept
+	// containers must be removed manually, e.g. with `podman rm` or `container rm`.
+	KeepOnExit bool
+
+	// SkipMemoryCheck disables the check that MemoryInGB doesn't exceed host memory.
+	SkipMemoryCheck bool
+
+	// MinFreeDiskBytes is the space that must be free on the filesystem holding the job's log for the job to start.
+	// 0 disables the check.
+	MinFreeDiskBytes uint64
+
+	// MaxLogSize is the size, in bytes, at which the job's log file is rolled over. 0 keeps the whole output in one
+	// file. MaxLogFiles is the number of rolled over files kept, and defaults to DefaultMaxLogFiles.
+	MaxLogSize  int64
+	MaxLogFiles int
+}
+
+// Mount mounts the host path Source at Target in a job's container. Both paths must be absolute.
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
 }
 
 // Job represents a container job managed by a runtime.
@@ -63,4 +109,19 @@ type Job struct {
 	Running     bool
 	TaskTitle   string
 	CreatedDate time.Time
+
+	// FetchErr holds the errors encountered fetching the job's task and turn from the P42 API, if any. TaskTitle or
+	// CreatedDate is unset when it couldn't be fetched.
+	FetchErr error
+}
+
+// unavailableTitle is shown in place of the title of a job whose task couldn't be fetched.
+const unavailableTitle = "[unavailable]"
+
+// DisplayTitle returns the job's task title, or "[unavailable]" if the task couldn't be fetched.
+func (j *Job) DisplayTitle() string {
+	if j.TaskTitle == "" && j.FetchErr != nil {
+		return unavailableTitle
+	}
+	return j.TaskTitle
 }
@@ -0,0 +1,15 @@
+package p42runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeRuntime(t *testing.T) {
+	require.Equal(t, DefaultRuntime, NormalizeRuntime(""))
+	require.Equal(t, DefaultRuntime, NormalizeRuntime("  "))
+	require.Equal(t, RuntimePodman, NormalizeRuntime(" Podman\n"))
+	require.Equal(t, RuntimeApple, NormalizeRuntime("APPLE"))
+	require.Equal(t, "docker", NormalizeRuntime("docker"))
+}
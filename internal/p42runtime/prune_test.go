@@ -0,0 +1,79 @@
+package p42runtime_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/p42runtime/apple"
+)
+
+func TestPruneJobLogs(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatalf("failed to create log dir: %v", err)
+	}
+
+	// the fake container binary reports plan42-running-1 as the only running job.
+	binPath := filepath.Join(dir, "container")
+	script := "#!/bin/sh\necho 'ID IMAGE'\necho 'plan42-running-1 example/agent:latest'\n"
+	if err := os.WriteFile(binPath, []byte(script), 0o755); err != nil { // #nosec G306: test binary must be executable.
+		t.Fatalf("failed to write fake container binary: %v", err)
+	}
+
+	now := time.Now()
+	logs := map[string]time.Duration{
+		"plan42-old-1":     30 * 24 * time.Hour,
+		"plan42-old-2":     8 * 24 * time.Hour,
+		"plan42-recent-1":  time.Hour,
+		"plan42-running-1": 30 * 24 * time.Hour,
+		"plan42-invalid":   30 * 24 * time.Hour,
+	}
+	for name, age := range logs {
+		path := filepath.Join(logDir, name)
+		if err := os.WriteFile(path, []byte("log"), 0o600); err != nil {
+			t.Fatalf("failed to write log: %v", err)
+		}
+		mtime := now.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("failed to set log mtime: %v", err)
+		}
+	}
+
+	provider := apple.NewProvider(binPath, logDir)
+	expected := []string{"plan42-old-1", "plan42-old-2"}
+
+	pruned, err := p42runtime.PruneJobLogs(context.Background(), provider, 7*24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("PruneJobLogs dry run returned error: %v", err)
+	}
+	if fmt.Sprint(pruned) != fmt.Sprint(expected) {
+		t.Fatalf("expected dry run to report %v, got %v", expected, pruned)
+	}
+	for name := range logs {
+		if _, err := os.Stat(filepath.Join(logDir, name)); err != nil {
+			t.Fatalf("dry run removed %s", name)
+		}
+	}
+
+	pruned, err = p42runtime.PruneJobLogs(context.Background(), provider, 7*24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("PruneJobLogs returned error: %v", err)
+	}
+	if fmt.Sprint(pruned) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v to be pruned, got %v", expected, pruned)
+	}
+	for name := range logs {
+		_, err := os.Stat(filepath.Join(logDir, name))
+		removed := os.IsNotExist(err)
+		shouldRemove := name == "plan42-old-1" || name == "plan42-old-2"
+		if removed != shouldRemove {
+			t.Errorf("%s removed = %t, expected %t", name, removed, shouldRemove)
+		}
+	}
+}
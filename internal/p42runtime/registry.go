@@ -0,0 +1,63 @@
+package p42runtime
+
+import (
+	"fmt"
+
+	"github.com/plan42-ai/cli/internal/docker"
+)
+
+// DefaultRegistry is the registry images without an explicit registry are pulled from.
+const DefaultRegistry = "docker.io"
+
+// RegistryCredentials are the credentials used to log in to a container registry before pulling an image.
+type RegistryCredentials struct {
+	Username string
+	Password string
+}
+
+// RegistryAuth maps registry hosts, with an optional ":port", to credentials.
+type RegistryAuth map[string]RegistryCredentials
+
+// Validate checks that every key is a valid registry host.
+func (a RegistryAuth) Validate() error {
+	for host := range a {
+		if _, err := ParseRegistryHost(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseRegistryHost parses a registry host with an optional ":port", such as "ghcr.io" or "registry.local:5000". The
+// returned ImageURI has only its Registry and RegistryPort set.
+func ParseRegistryHost(host string) (*docker.ImageURI, error) {
+	uri, err := docker.ParseImageURI(host + "/image")
+	if err != nil || uri.Registry == nil || registryHost(uri) != host {
+		return nil, fmt.Errorf("invalid registry host %q", host)
+	}
+	return &docker.ImageURI{Registry: uri.Registry, RegistryPort: uri.RegistryPort}, nil
+}
+
+// Lookup returns the registry host for image and the credentials configured for it. ok is false if no credentials
+// are configured for the registry.
+func (a RegistryAuth) Lookup(image string) (host string, creds RegistryCredentials, ok bool, err error) {
+	uri, err := docker.ParseImageURI(image)
+	if err != nil {
+		return "", RegistryCredentials{}, false, fmt.Errorf("invalid image %q: %w", image, err)
+	}
+
+	host = DefaultRegistry
+	if uri.Registry != nil {
+		host = registryHost(uri)
+	}
+	creds, ok = a[host]
+	return host, creds, ok, nil
+}
+
+func registryHost(uri *docker.ImageURI) string {
+	host := *uri.Registry
+	if uri.RegistryPort != nil {
+		host += ":" + *uri.RegistryPort
+	}
+	return host
+}
@@ -0,0 +1,147 @@
+package p42runtime_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/p42runtime/apple"
+	"github.com/plan42-ai/cli/internal/p42runtime/podman"
+)
+
+func TestRegistryAuthLookup(t *testing.T) {
+	auth := p42runtime.RegistryAuth{
+		"ghcr.io":             {Username: "ghcr-user", Password: "ghcr-pass"},
+		"registry.local:5000": {Username: "local-user", Password: "local-pass"},
+		"docker.io":           {Username: "hub-user", Password: "hub-pass"},
+	}
+
+	testCases := []struct {
+		image        string
+		expectedHost string
+		expectedUser string
+		found        bool
+	}{
+		{image: "ghcr.io/plan42-ai/agent:latest", expectedHost: "ghcr.io", expectedUser: "ghcr-user", found: true},
+		{image: "registry.local:5000/agent", expectedHost: "registry.local:5000", expectedUser: "local-user", found: true},
+		{image: "registry.local/agent", expectedHost: "registry.local", found: false},
+		{image: "plan42/agent:v1", expectedHost: "docker.io", expectedUser: "hub-user", found: true},
+		{image: "quay.io/plan42/agent", expectedHost: "quay.io", found: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			host, creds, ok, err := auth.Lookup(tc.image)
+			if err != nil {
+				t.Fatalf("Lookup returned error: %v", err)
+			}
+			if host != tc.expectedHost || ok != tc.found || creds.Username != tc.expectedUser {
+				t.Fatalf("Lookup(%q) = (%q, %q, %t), expected (%q, %q, %t)",
+					tc.image, host, creds.Username, ok, tc.expectedHost, tc.expectedUser, tc.found)
+			}
+		})
+	}
+
+	if _, _, _, err := auth.Lookup("Invalid Image"); err == nil {
+		t.Fatalf("expected an error for an invalid image")
+	}
+}
+
+func TestRegistryAuthValidate(t *testing.T) {
+	valid := p42runtime.RegistryAuth{"ghcr.io": {}, "registry.local:5000": {}, "docker.io": {}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	for _, host := range []string{"ghcr.io/plan42", "bad host", "registry.local:99999", ""} {
+		if err := (p42runtime.RegistryAuth{host: {}}).Validate(); err == nil {
+			t.Errorf("expected Validate to reject %q", host)
+		}
+	}
+}
+
+// recordingBinary writes a stand-in container binary that appends each invocation's arguments, and for logins its
+// stdin, to the returned file.
+func recordingBinary(t *testing.T) (binPath string, recordPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	binPath = filepath.Join(dir, "runtime")
+	recordPath = filepath.Join(dir, "record")
+	script := `#!/bin/sh
+echo "$*" >> "` + recordPath + `"
+case "$*" in
+*--password-stdin*) echo "stdin=$(cat)" >> "` + recordPath + `" ;;
+esac
+`
+	if err := os.WriteFile(binPath, []byte(script), 0o755); err != nil { // #nosec G306: test binary must be executable.
+		t.Fatalf("failed to write fake runtime binary: %v", err)
+	}
+	return binPath, recordPath
+}
+
+func TestPullImageLogsInToRegistry(t *testing.T) {
+	auth := p42runtime.RegistryAuth{"ghcr.io": {Username: "octocat", Password: "s3cret"}}
+
+	testCases := []struct {
+		name     string
+		provider func(binPath string) p42runtime.Provider
+		image    string
+		expected []string
+	}{
+		{
+			name: "apple",
+			provider: func(binPath string) p42runtime.Provider {
+				return apple.NewProvider(binPath, "", apple.WithRegistryAuth(auth))
+			},
+			image: "ghcr.io/plan42-ai/agent:latest",
+			expected: []string{
+				"registry login --username octocat --password-stdin ghcr.io",
+				"stdin=s3cret",
+				"image pull ghcr.io/plan42-ai/agent:latest",
+			},
+		},
+		{
+			name: "podman",
+			provider: func(binPath string) p42runtime.Provider {
+				return podman.NewProvider(binPath, "", podman.WithRegistryAuth(auth), podman.WithMachineCheck(false))
+			},
+			image: "ghcr.io/plan42-ai/agent:latest",
+			expected: []string{
+				"login --username octocat --password-stdin ghcr.io",
+				"stdin=s3cret",
+				"pull ghcr.io/plan42-ai/agent:latest",
+			},
+		},
+		{
+			name: "no credentials for registry",
+			provider: func(binPath string) p42runtime.Provider {
+				return podman.NewProvider(binPath, "", podman.WithRegistryAuth(auth), podman.WithMachineCheck(false))
+			},
+			image:    "quay.io/plan42/agent:latest",
+			expected: []string{"pull quay.io/plan42/agent:latest"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			binPath, recordPath := recordingBinary(t)
+
+			err := tc.provider(binPath).PullImage(context.Background(), tc.image)
+			if err != nil {
+				t.Fatalf("PullImage returned error: %v", err)
+			}
+
+			record, err := os.ReadFile(recordPath)
+			if err != nil {
+				t.Fatalf("failed to read invocation record: %v", err)
+			}
+			got := strings.Split(strings.TrimSpace(string(record)), "\n")
+			if strings.Join(got, "\n") != strings.Join(tc.expected, "\n") {
+				t.Fatalf("unexpected invocations:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tc.expected, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package p42runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// killTimeout bounds how long RunJobWithTimeout waits for the runtime to kill a job that exceeded its deadline.
+const killTimeout = 30 * time.Second
+
+// ErrJobTimeout is returned by RunJobWithTimeout when a job runs past its deadline.
+var ErrJobTimeout = errors.New("job exceeded its maximum runtime")
+
+// RunJobWithTimeout runs a job via provider.RunJob, limiting it to timeout. Cancelling the context only stops the
+// runtime's client process, so when the deadline expires the job is also killed by name via provider.KillJob.
+// A timeout <= 0 means no limit.
+func RunJobWithTimeout(ctx context.Context, provider Provider, opts JobOptions, timeout time.Duration) error {
+	if timeout <= 0 {
+		return provider.RunJob(ctx, opts)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := provider.RunJob(runCtx, opts)
+	if !errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	// The parent context may be done too, but the job still needs to be killed.
+	killCtx, cancelKill := context.WithTimeout(context.WithoutCancel(ctx), killTimeout)
+	defer cancelKill()
+
+	killErr := provider.KillJob(killCtx, opts.JobID)
+	if killErr != nil {
+		return fmt.Errorf("%w after %v; %w", ErrJobTimeout, timeout, killErr)
+	}
+	return fmt.Errorf("%w after %v", ErrJobTimeout, timeout)
+}
@@ -0,0 +1,159 @@
+package p42runtime_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/p42runtime/apple"
+	"github.com/plan42-ai/cli/internal/p42runtime/podman"
+)
+
+// fakeContainerBinary writes a stand-in for the container binary whose "run" sleeps and whose "kill" records the
+// killed job ID in the returned file.
+func fakeContainerBinary(t *testing.T) (binPath string, killedPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	binPath = filepath.Join(dir, "container")
+	killedPath = filepath.Join(dir, "killed")
+	script := `#!/bin/sh
+case "$1" in
+run) exec sleep 30 ;;
+kill) echo "$2" >> "` + killedPath + `" ;;
+esac
+`
+	if err := os.WriteFile(binPath, []byte(script), 0o755); err != nil { // #nosec G306: test binary must be executable.
+		t.Fatalf("failed to write fake container binary: %v", err)
+	}
+	return binPath, killedPath
+}
+
+func TestRunJobWithTimeoutKillsJobAtDeadline(t *testing.T) {
+	binPath, killedPath := fakeContainerBinary(t)
+	provider := apple.NewProvider(binPath, "")
+
+	const timeout = 200 * time.Millisecond
+	start := time.Now()
+	err := p42runtime.RunJobWithTimeout(
+		context.Background(),
+		provider,
+		p42runtime.JobOptions{JobID: "plan42-alpha-1", Image: "example/agent:latest"},
+		timeout,
+	)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, p42runtime.ErrJobTimeout) {
+		t.Fatalf("expected ErrJobTimeout, got %v", err)
+	}
+	if elapsed < timeout || elapsed > 10*time.Second {
+		t.Fatalf("expected run to stop shortly after %v, took %v", timeout, elapsed)
+	}
+
+	killed, err := os.ReadFile(killedPath)
+	if err != nil {
+		t.Fatalf("expected the job to be killed: %v", err)
+	}
+	if strings.TrimSpace(string(killed)) != "plan42-alpha-1" {
+		t.Fatalf("expected plan42-alpha-1 to be killed, got %q", killed)
+	}
+}
+
+func TestRunJobWithTimeoutNoLimit(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "container")
+	if err := os.WriteFile(binPath, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil { // #nosec G306: test binary must be executable.
+		t.Fatalf("failed to write fake container binary: %v", err)
+	}
+
+	err := p42runtime.RunJobWithTimeout(
+		context.Background(),
+		apple.NewProvider(binPath, ""),
+		p42runtime.JobOptions{JobID: "plan42-alpha-1", Image: "example/agent:latest"},
+		0,
+	)
+	if err != nil {
+		t.Fatalf("RunJobWithTimeout returned error: %v", err)
+	}
+}
+
+func TestMissingRuntimeBinary(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "no-such-binary")
+	providers := map[string]p42runtime.Provider{
+		"apple":  apple.NewProvider(missing, ""),
+		"podman": podman.NewProvider(missing, ""),
+	}
+
+	for name, provider := range providers {
+		t.Run(name, func(t *testing.T) {
+			err := provider.RunJob(context.Background(), p42runtime.JobOptions{JobID: "plan42-alpha-1", Image: "example/agent:latest"})
+			if !errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+				t.Fatalf("expected RunJob to return ErrRuntimeUnavailable, got %v", err)
+			}
+
+			err = provider.PullImage(context.Background(), "example/agent:latest")
+			if !errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+				t.Fatalf("expected PullImage to return ErrRuntimeUnavailable, got %v", err)
+			}
+
+			err = p42runtime.CheckAvailable(provider)
+			if !errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+				t.Fatalf("expected CheckAvailable to return ErrRuntimeUnavailable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRuntimeExitErrorIsNotUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "container")
+	if err := os.WriteFile(binPath, []byte("#!/bin/sh\nexit 3\n"), 0o755); err != nil { // #nosec G306: test binary must be executable.
+		t.Fatalf("failed to write fake container binary: %v", err)
+	}
+
+	err := apple.NewProvider(binPath, "").RunJob(context.Background(), p42runtime.JobOptions{JobID: "plan42-alpha-1", Image: "example/agent:latest"})
+	if err == nil || errors.Is(err, p42runtime.ErrRuntimeUnavailable) {
+		t.Fatalf("expected a plain exit error, got %v", err)
+	}
+}
+
+func TestRunJobKeepOnExit(t *testing.T) {
+	providers := map[string]func(binPath string) p42runtime.Provider{
+		"apple": func(binPath string) p42runtime.Provider {
+			return apple.NewProvider(binPath, "")
+		},
+		"podman": func(binPath string) p42runtime.Provider {
+			return podman.NewProvider(binPath, "", podman.WithMachineCheck(false))
+		},
+	}
+
+	for name, newProvider := range providers {
+		for _, keep := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s keep=%t", name, keep), func(t *testing.T) {
+				binPath, recordPath := recordingBinary(t)
+				err := newProvider(binPath).RunJob(
+					context.Background(),
+					p42runtime.JobOptions{JobID: "plan42-alpha-1", Image: "example/agent:latest", KeepOnExit: keep},
+				)
+				if err != nil {
+					t.Fatalf("RunJob returned error: %v", err)
+				}
+
+				record, err := os.ReadFile(recordPath)
+				if err != nil {
+					t.Fatalf("failed to read invocation record: %v", err)
+				}
+				args := strings.Fields(string(record))
+				if slices.Contains(args, "--rm") == keep {
+					t.Fatalf("expected --rm present=%t, got args %q", !keep, args)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+// agentOutputTailSize bounds how much of an agent's output is kept to explain a failed run.
+const agentOutputTailSize = 4096
+
+// agentRunResult is the outcome of running an agent container.
+type agentRunResult struct {
+	// ExitCode is the exit code of the container, or -1 if it didn't exit on its own.
+	ExitCode int
+	// TimedOut is set if the agent was killed for running past its timeout.
+	TimedOut bool
+	// Output is the tail of the container's combined stdout and stderr.
+	Output string
+	// Err is the error returned by the runtime, or nil if the agent succeeded.
+	Err error
+}
+
+func (r agentRunResult) Failed() bool {
+	return r.Err != nil
+}
+
+// ErrorMessage describes why the agent failed, followed by the tail of its output. Tokens in the message are masked,
+// since it's sent to the server.
+func (r agentRunResult) ErrorMessage() string {
+	var b strings.Builder
+	switch {
+	case r.TimedOut:
+		b.WriteString(r.Err.Error())
+	case r.ExitCode >= 0:
+		fmt.Fprintf(&b, "agent exited with code %d", r.ExitCode)
+	default:
+		fmt.Fprintf(&b, "agent failed to run: %v", r.Err)
+	}
+	if output := strings.TrimSpace(r.Output); output != "" {
+		b.WriteString("\n")
+		b.WriteString(output)
+	}
+	return util.RedactToken(b.String())
+}
+
+// runAgentJob runs an agent container, capturing the tail of its output alongside the job's log file. Output is also
+// copied to opts.Stdout and opts.Stderr if they are set.
+func runAgentJob(ctx context.Context, provider p42runtime.Provider, opts p42runtime.JobOptions, timeout time.Duration) agentRunResult {
+	output := p42runtime.NewTailBuffer(agentOutputTailSize)
+	opts.Stdout = teeWriter(output, opts.Stdout)
+	opts.Stderr = teeWriter(output, opts.Stderr)
+
+	err := p42runtime.RunJobWithTimeout(ctx, provider, opts, timeout)
+	result := agentRunResult{
+		Output:   output.String(),
+		Err:      err,
+		TimedOut: errors.Is(err, p42runtime.ErrJobTimeout),
+	}
+	if err != nil {
+		code, ok := p42runtime.ExitCode(err)
+		if !ok || result.TimedOut {
+			code = -1
+		}
+		result.ExitCode = code
+	}
+	return result
+}
+
+func teeWriter(w io.Writer, extra io.Writer) io.Writer {
+	if extra == nil {
+		return w
+	}
+	return io.MultiWriter(w, extra)
+}
@@ -0,0 +1,41 @@
+package poller
+
+import "time"
+
+// Clock is the source of time for the poller's time based logic: autoscaling, key rotation, and draining. Tests
+// replace it with a fake clock (see WithClock) so that logic can be driven deterministically.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the subset of *time.Ticker used by the poller.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// WithClock sets the clock used by the poller. It defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(p *Poller) {
+		p.clock = clock
+	}
+}
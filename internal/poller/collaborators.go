@@ -0,0 +1,187 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/github"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+)
+
+// The collaborator messages aren't part of the sdk yet, so they are defined here.
+const (
+	ListCollaboratorsRequestMessage  messages.MessageType = "ListCollaboratorsRequest"
+	ListCollaboratorsResponseMessage messages.MessageType = "ListCollaboratorsResponse"
+)
+
+func init() {
+	registerHandler(ListCollaboratorsRequestMessage, func() pollerMessage { return &pollerListCollaboratorsRequest{} })
+}
+
+type ListCollaboratorsRequest struct {
+	TenantID     string
+	ConnectionID string
+	OrgName      string
+	RepoName     string
+	Affiliation  *string // "outside", "direct", or "all". Defaults to "all".
+	Search       *string // only logins containing Search, ignoring case, are returned.
+	MaxResults   *int
+	Token        *string
+}
+
+func (r *ListCollaboratorsRequest) Type() messages.MessageType {
+	return ListCollaboratorsRequestMessage
+}
+
+func (r ListCollaboratorsRequest) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		TenantID     string
+		ConnectionID string
+		OrgName      string
+		RepoName     string
+		Affiliation  *string
+		Search       *string
+		MaxResults   *int
+		Token        *string
+	}
+
+	tmp.Type = ListCollaboratorsRequestMessage
+	tmp.TenantID = r.TenantID
+	tmp.ConnectionID = r.ConnectionID
+	tmp.OrgName = r.OrgName
+	tmp.RepoName = r.RepoName
+	tmp.Affiliation = r.Affiliation
+	tmp.Search = r.Search
+	tmp.MaxResults = r.MaxResults
+	tmp.Token = r.Token
+
+	return json.Marshal(tmp)
+}
+
+// ListCollaboratorsResponse lists the logins of the users with access to a repo.
+type ListCollaboratorsResponse struct {
+	Items        []string
+	NextToken    *string
+	ErrorMessage *string
+	ErrorCode    *ErrorCode
+}
+
+func (r *ListCollaboratorsResponse) Type() messages.MessageType {
+	return ListCollaboratorsResponseMessage
+}
+
+func (r ListCollaboratorsResponse) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		Items        []string
+		NextToken    *string
+		ErrorMessage *string
+		ErrorCode    *ErrorCode
+	}
+
+	tmp.Type = ListCollaboratorsResponseMessage
+	tmp.Items = r.Items
+	tmp.NextToken = r.NextToken
+	tmp.ErrorMessage = r.ErrorMessage
+	tmp.ErrorCode = r.ErrorCode
+
+	return json.Marshal(tmp)
+}
+
+type pollerListCollaboratorsRequest struct {
+	ListCollaboratorsRequest
+	client  *github.Client
+	limiter *rateLimiter
+	err     error
+}
+
+func (req *pollerListCollaboratorsRequest) Init(p *Poller) {
+	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+	req.limiter = p.githubRateLimiter(req.ConnectionID)
+}
+
+type ListCollaboratorsPaginationKey struct {
+	Page int
+}
+
+func (req *pollerListCollaboratorsRequest) Process(ctx context.Context) messages.Message {
+	slog.InfoContext(
+		ctx,
+		"received ListCollaboratorsRequest message",
+		"connection_id",
+		req.ConnectionID,
+		"org_name",
+		req.OrgName,
+		"repo_name",
+		req.RepoName,
+		"pagination_token",
+		req.Token,
+	)
+	if req.err != nil {
+		slog.ErrorContext(ctx, "unable to initialize github client", "error", req.err, "connection_id", req.ConnectionID)
+		msg, code := githubErrorFields(req.err)
+		return &ListCollaboratorsResponse{ErrorMessage: msg, ErrorCode: code}
+	}
+	if req.OrgName == "" {
+		slog.ErrorContext(ctx, "missing org name for collaborator listing", "connection_id", req.ConnectionID)
+		return &ListCollaboratorsResponse{
+			ErrorMessage: util.Pointer("org name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
+	}
+	if req.RepoName == "" {
+		slog.ErrorContext(ctx, "missing repo name for collaborator listing", "connection_id", req.ConnectionID)
+		return &ListCollaboratorsResponse{
+			ErrorMessage: util.Pointer("repo name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
+	}
+	logins, nextToken, err := paginate(
+		ctx,
+		req.MaxResults,
+		req.Token,
+		ListCollaboratorsPaginationKey{Page: 1},
+		func(key ListCollaboratorsPaginationKey, limit int) ([]string, *ListCollaboratorsPaginationKey, error) {
+			if err := req.limiter.Wait(ctx); err != nil {
+				return nil, nil, err
+			}
+			users, resp, err := req.client.ListCollaborators(
+				ctx,
+				req.OrgName,
+				req.RepoName,
+				&ghapi.ListCollaboratorsOptions{
+					Affiliation: util.Deref(req.Affiliation),
+					ListOptions: ghapi.ListOptions{Page: key.Page, PerPage: limit},
+				},
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "github collaborator listing failed", "error", err)
+				return nil, nil, err
+			}
+			var logins []string
+			for _, user := range users {
+				login := user.GetLogin()
+				if login == "" {
+					continue
+				}
+				if req.Search != nil && !strings.Contains(strings.ToLower(login), strings.ToLower(*req.Search)) {
+					continue
+				}
+				logins = append(logins, login)
+			}
+			return logins, nextPage(resp, func(page int) ListCollaboratorsPaginationKey {
+				return ListCollaboratorsPaginationKey{Page: page}
+			}), nil
+		},
+	)
+	if err != nil {
+		msg, code := githubErrorFields(err)
+		return &ListCollaboratorsResponse{ErrorMessage: msg, ErrorCode: code}
+	}
+	return &ListCollaboratorsResponse{Items: logins, NextToken: nextToken}
+}
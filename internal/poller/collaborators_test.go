@@ -0,0 +1,125 @@
+package poller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCollaboratorsPagination(t *testing.T) {
+	const nPages = 2
+	var requestedPages []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/repos/plan42-ai/cli/collaborators", r.URL.Path)
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		assert.NoError(t, err)
+		requestedPages = append(requestedPages, page)
+		if page < nPages {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `[{"login": "user-%d"}]`, page)
+	}))
+	defer server.Close()
+	p := newGithubTestPoller(server)
+
+	var logins []string
+	var tokens []string
+	var token *string
+	for {
+		req := &pollerListCollaboratorsRequest{
+			ListCollaboratorsRequest: ListCollaboratorsRequest{
+				ConnectionID: testConnectionID,
+				OrgName:      "plan42-ai",
+				RepoName:     "cli",
+				MaxResults:   util.Pointer(1),
+				Token:        token,
+			},
+		}
+		req.Init(p)
+		resp := req.Process(t.Context()).(*ListCollaboratorsResponse)
+		require.Nil(t, resp.ErrorMessage)
+		logins = append(logins, resp.Items...)
+		if resp.NextToken == nil {
+			break
+		}
+		tokens = append(tokens, *resp.NextToken)
+		token = resp.NextToken
+	}
+
+	require.Equal(t, []string{"user-1", "user-2"}, logins)
+	require.Equal(t, []int{1, 2}, requestedPages)
+	require.Equal(t, []string{base64.RawURLEncoding.EncodeToString([]byte(`{"Page":2}`))}, tokens)
+}
+
+func TestListCollaboratorsSearchIgnoresCase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"login": "OctoCat"}, {"login": "octodog"}, {"login": "hubot"}]`))
+	}))
+	defer server.Close()
+
+	req := &pollerListCollaboratorsRequest{
+		ListCollaboratorsRequest: ListCollaboratorsRequest{
+			ConnectionID: testConnectionID,
+			OrgName:      "plan42-ai",
+			RepoName:     "cli",
+			Search:       util.Pointer("OCTO"),
+		},
+	}
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListCollaboratorsResponse)
+	require.Nil(t, resp.ErrorMessage)
+	require.Equal(t, []string{"OctoCat", "octodog"}, resp.Items)
+}
+
+func TestListCollaboratorsInvalidToken(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	req := &pollerListCollaboratorsRequest{
+		ListCollaboratorsRequest: ListCollaboratorsRequest{
+			ConnectionID: testConnectionID,
+			OrgName:      "plan42-ai",
+			RepoName:     "cli",
+			Token:        util.Pointer("not-a-token!"),
+		},
+	}
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListCollaboratorsResponse)
+	require.Equal(t, errInvalidPaginationToken.Error(), *resp.ErrorMessage)
+	require.Equal(t, ErrorCodeInvalidRequest, *resp.ErrorCode)
+}
+
+func TestListCollaboratorsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`))
+	}))
+	defer server.Close()
+
+	req := &pollerListCollaboratorsRequest{}
+	req.ConnectionID = testConnectionID
+	req.OrgName = "plan42-ai"
+	req.RepoName = "missing"
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListCollaboratorsResponse)
+	require.Equal(t, ErrorCodeNotFound, *resp.ErrorCode)
+	require.NotContains(t, *resp.ErrorMessage, server.URL)
+}
+
+func TestListCollaboratorsRequiresRepo(t *testing.T) {
+	req := &pollerListCollaboratorsRequest{}
+	req.OrgName = "plan42-ai"
+	resp := req.Process(t.Context()).(*ListCollaboratorsResponse)
+	require.Equal(t, "repo name is required", *resp.ErrorMessage)
+	require.Equal(t, ErrorCodeInvalidRequest, *resp.ErrorCode)
+}
@@ -0,0 +1,113 @@
+package poller
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProcessedMessageCacheSize = 1024
+	defaultProcessedMessageCacheTTL  = 10 * time.Minute
+)
+
+// processedMessages is an LRU set of recently processed message IDs, bounded by size and TTL. The server delivers
+// messages at least once, so it is used to avoid processing a redelivered message (and launching its agent) twice.
+type processedMessages struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// processedMessage records a message that has been (or is being) processed. done is closed once resp is set.
+type processedMessage struct {
+	messageID string
+	added     time.Time
+	done      chan struct{}
+	resp      []byte
+}
+
+func newProcessedMessages(size int, ttl time.Duration) *processedMessages {
+	return &processedMessages{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// begin records messageID as being processed. If the message has already been seen, it returns the existing entry
+// and false, otherwise it returns a new entry and true. The caller must call finish on a new entry.
+func (pm *processedMessages) begin(messageID string) (*processedMessage, bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	now := time.Now()
+	pm.evictExpired(now)
+
+	if elem, ok := pm.entries[messageID]; ok {
+		entry := elem.Value.(*processedMessage)
+		if now.Sub(entry.added) < pm.ttl {
+			pm.order.MoveToBack(elem)
+			return entry, false
+		}
+		pm.remove(elem)
+	}
+
+	entry := &processedMessage{
+		messageID: messageID,
+		added:     now,
+		done:      make(chan struct{}),
+	}
+	pm.entries[messageID] = pm.order.PushBack(entry)
+	for pm.order.Len() > pm.size {
+		pm.remove(pm.order.Front())
+	}
+	return entry, true
+}
+
+// evictExpired removes entries older than the TTL. Entries are ordered by use rather than age, so this only trims
+// expired entries from the front of the list; anything missed is caught by the size bound.
+func (pm *processedMessages) evictExpired(now time.Time) {
+	for elem := pm.order.Front(); elem != nil; elem = pm.order.Front() {
+		if now.Sub(elem.Value.(*processedMessage).added) < pm.ttl {
+			return
+		}
+		pm.remove(elem)
+	}
+}
+
+func (pm *processedMessages) remove(elem *list.Element) {
+	pm.order.Remove(elem)
+	delete(pm.entries, elem.Value.(*processedMessage).messageID)
+}
+
+// finish records the marshaled response for entry and wakes any duplicates waiting on it. resp is nil if processing
+// failed, in which case the message is forgotten, so a redelivery is processed again rather than dropped.
+func (pm *processedMessages) finish(entry *processedMessage, resp []byte) {
+	entry.resp = resp
+	close(entry.done)
+	if resp != nil {
+		return
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if elem, ok := pm.entries[entry.messageID]; ok && elem.Value == entry {
+		pm.remove(elem)
+	}
+}
+
+// wait blocks until the original delivery of the message has been processed and returns its response. It returns
+// nil if processing failed or ctx is done first.
+func (e *processedMessage) wait(ctx context.Context) []byte {
+	select {
+	case <-e.done:
+		return e.resp
+	case <-ctx.Done():
+		return nil
+	}
+}
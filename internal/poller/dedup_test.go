@@ -0,0 +1,60 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessedMessagesEvictsLeastRecentlyUsed(t *testing.T) {
+	pm := newProcessedMessages(2, time.Hour)
+
+	_, first := pm.begin("a")
+	require.True(t, first)
+	_, first = pm.begin("b")
+	require.True(t, first)
+
+	// touching "a" makes "b" the least recently used entry.
+	_, first = pm.begin("a")
+	require.False(t, first)
+
+	_, first = pm.begin("c")
+	require.True(t, first)
+
+	_, first = pm.begin("b")
+	require.True(t, first)
+	_, first = pm.begin("c")
+	require.False(t, first)
+}
+
+func TestProcessedMessagesExpires(t *testing.T) {
+	pm := newProcessedMessages(10, time.Millisecond)
+
+	_, first := pm.begin("a")
+	require.True(t, first)
+	time.Sleep(5 * time.Millisecond)
+	_, first = pm.begin("a")
+	require.True(t, first)
+}
+
+func TestProcessedMessagesForgetsFailures(t *testing.T) {
+	pm := newProcessedMessages(10, time.Hour)
+
+	entry, first := pm.begin("a")
+	require.True(t, first)
+	pm.finish(entry, []byte("{}"))
+	_, first = pm.begin("a")
+	require.False(t, first)
+
+	entry, first = pm.begin("b")
+	require.True(t, first)
+	duplicate, first := pm.begin("b")
+	require.False(t, first)
+	pm.finish(entry, nil)
+	require.Nil(t, duplicate.wait(t.Context()))
+
+	// a failed message is processed again when it's redelivered.
+	_, first = pm.begin("b")
+	require.True(t, first)
+}
@@ -2,9 +2,7 @@ package poller
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -15,146 +13,226 @@ import (
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
 
-const (
-	defaultPageSize = 10
-	maxPageSize     = 100
-)
+func init() {
+	registerHandler(
+		messages.ListOrgsForGithubConnectionRequestMessage,
+		func() pollerMessage { return &pollerListOrgsForGithubConnectionRequest{} },
+	)
+	registerHandler(messages.SearchRepoRequestMessage, func() pollerMessage { return &pollerSearchRepoRequest{} })
+	registerHandler(messages.ListRepoBranchesRequestMessage, func() pollerMessage { return &pollerListRepoBranchesRequest{} })
+}
 
-var (
-	errMaxResultInvalid       = errors.New("maxResults must be between 1 and 100")
-	errInvalidPaginationToken = errors.New("invalid pagination token")
-)
+// The sdk's github responses don't carry an ErrorCode yet, so these extend them with one. They're sent with the same
+// message types.
 
-type ListOrgsPaginationKey struct {
-	Page *int `json:"Page,omitempty"`
+type ListOrgsForGithubConnectionResponse struct {
+	Items        []string
+	NextToken    *string
+	ErrorMessage *string
+	ErrorCode    *ErrorCode
 }
 
-type pollerListOrgsForGithubConnectionRequest struct {
-	messages.ListOrgsForGithubConnectionRequest
-	client *github.Client
-	err    error
+func (r *ListOrgsForGithubConnectionResponse) Type() messages.MessageType {
+	return messages.ListOrgsForGithubConnectionResponseMessage
 }
 
-func (req *pollerListOrgsForGithubConnectionRequest) Init(p *Poller) {
-	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+func (r ListOrgsForGithubConnectionResponse) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		Items        []string
+		NextToken    *string
+		ErrorMessage *string
+		ErrorCode    *ErrorCode
+	}
+
+	tmp.Type = messages.ListOrgsForGithubConnectionResponseMessage
+	tmp.Items = r.Items
+	tmp.NextToken = r.NextToken
+	tmp.ErrorMessage = r.ErrorMessage
+	tmp.ErrorCode = r.ErrorCode
+
+	return json.Marshal(tmp)
 }
 
-// ParsePagination parses MaxResults and Token into a key structure.
-// key should be a pointer to the pagination key struct.
-func ParsePagination[T any](maxResults *int, token *string, key *T) (int, error) {
-	limit := defaultPageSize
-	if maxResults != nil {
-		limit = *maxResults
-	}
-	if limit <= 0 || limit > maxPageSize {
-		return 0, errMaxResultInvalid
-	}
-	if token != nil {
-		b, err := base64.RawURLEncoding.DecodeString(*token)
-		if err != nil {
-			return 0, errInvalidPaginationToken
-		}
-		if err := json.Unmarshal(b, key); err != nil {
-			return 0, errInvalidPaginationToken
-		}
-	}
-	return limit, nil
+type SearchRepoResponse struct {
+	Items        []string
+	NextToken    *string
+	ErrorMessage *string
+	ErrorCode    *ErrorCode
+}
+
+func (r *SearchRepoResponse) Type() messages.MessageType {
+	return messages.SearchRepoResponseMessage
 }
 
-func NextToken[T any](paginationKey *T) (*string, error) {
-	if paginationKey == nil {
-		return nil, nil
+func (r SearchRepoResponse) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		Items        []string
+		NextToken    *string
+		ErrorMessage *string
+		ErrorCode    *ErrorCode
 	}
-	jsonBytes, err := json.Marshal(paginationKey)
-	if err != nil {
-		return nil, err
+
+	tmp.Type = messages.SearchRepoResponseMessage
+	tmp.Items = r.Items
+	tmp.NextToken = r.NextToken
+	tmp.ErrorMessage = r.ErrorMessage
+	tmp.ErrorCode = r.ErrorCode
+
+	return json.Marshal(tmp)
+}
+
+type ListRepoBranchesResponse struct {
+	Items        []string
+	NextToken    *string
+	ErrorMessage *string
+	ErrorCode    *ErrorCode
+}
+
+func (r *ListRepoBranchesResponse) Type() messages.MessageType {
+	return messages.ListRepoBranchesResponseMessage
+}
+
+func (r ListRepoBranchesResponse) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		Items        []string
+		NextToken    *string
+		ErrorMessage *string
+		ErrorCode    *ErrorCode
 	}
-	return util.Pointer(base64.RawURLEncoding.EncodeToString(jsonBytes)), nil
+
+	tmp.Type = messages.ListRepoBranchesResponseMessage
+	tmp.Items = r.Items
+	tmp.NextToken = r.NextToken
+	tmp.ErrorMessage = r.ErrorMessage
+	tmp.ErrorCode = r.ErrorCode
+
+	return json.Marshal(tmp)
+}
+
+type ListOrgsPaginationKey struct {
+	Page *int `json:"Page,omitempty"`
+}
+
+type pollerListOrgsForGithubConnectionRequest struct {
+	messages.ListOrgsForGithubConnectionRequest
+	client  *github.Client
+	limiter *rateLimiter
+	err     error
+}
+
+func (req *pollerListOrgsForGithubConnectionRequest) Init(p *Poller) {
+	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+	req.limiter = p.githubRateLimiter(req.ConnectionID)
 }
 
 func (req *pollerListOrgsForGithubConnectionRequest) Process(ctx context.Context) messages.Message {
 	slog.InfoContext(ctx, "received ListOrgsForGithubConnectionRequest message", "connection_id", req.ConnectionID, "pagination_token", req.Token)
 	if req.err != nil {
 		slog.ErrorContext(ctx, "unable to initialize github client", "error", req.err, "connection_id", req.ConnectionID)
-		return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer(req.err.Error())}
+		msg, code := githubErrorFields(req.err)
+		return &ListOrgsForGithubConnectionResponse{ErrorMessage: msg, ErrorCode: code}
 	}
 
-	var paginationKey ListOrgsPaginationKey
-	maxResults, err := ParsePagination(req.MaxResults, req.Token, &paginationKey)
+	orgNames, nextToken, err := paginate(
+		ctx,
+		req.MaxResults,
+		req.Token,
+		ListOrgsPaginationKey{Page: util.Pointer(1)},
+		req.fetchPage(ctx),
+	)
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to parse pagination key", "error", err, "connection_id", req.ConnectionID)
-		return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer(err.Error())}
+		msg, code := githubErrorFields(err)
+		return &ListOrgsForGithubConnectionResponse{ErrorMessage: msg, ErrorCode: code}
 	}
-
-	if req.Token == nil {
-		paginationKey.Page = util.Pointer(1)
+	return &ListOrgsForGithubConnectionResponse{
+		Items:     orgNames,
+		NextToken: nextToken,
 	}
+}
 
-	if paginationKey.Page == nil {
-		user, _, err := req.client.GetCurrentUser(ctx)
-		if err != nil {
-			return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer("unable to fetch data for github user")}
+// fetchPage returns the fetcher for the pages of the org listing. The orgs are listed first, followed by the user's
+// own login, which is either appended to the last page of orgs when it has room, or served as a page of its own,
+// identified by a key without a Page.
+func (req *pollerListOrgsForGithubConnectionRequest) fetchPage(
+	ctx context.Context,
+) pageFetcher[ListOrgsPaginationKey, string] {
+	return func(key ListOrgsPaginationKey, limit int) ([]string, *ListOrgsPaginationKey, error) {
+		if key.Page == nil {
+			login, err := req.userLogin(ctx)
+			if err != nil || login == "" {
+				return nil, nil, err
+			}
+			return []string{login}, nil, nil
+		}
+
+		if err := req.limiter.Wait(ctx); err != nil {
+			return nil, nil, err
 		}
-		var items []string
-		if req.Search == nil || strings.Contains(*user.Login, *req.Search) {
-			items = append(items, *user.Login)
+		orgs, resp, err := req.client.ListOrganizations(ctx, *key.Page, limit)
+		if err != nil {
+			slog.ErrorContext(ctx, "call to organizations.List failed", "error", err)
+			return nil, nil, err
 		}
-		return &messages.ListOrgsForGithubConnectionResponse{
-			Items: items,
+		var orgNames []string
+		for _, org := range orgs {
+			if req.Search != nil && !strings.Contains(*org.Login, *req.Search) {
+				continue
+			}
+			orgNames = append(orgNames, *org.Login)
 		}
-	}
+		slog.InfoContext(ctx, "call to organizations.List succeeded", "n_orgs", len(orgNames))
 
-	orgs, resp, err := req.client.ListOrganizations(ctx, *paginationKey.Page, maxResults)
-	if err != nil {
-		slog.ErrorContext(ctx, "call to organizations.List failed", "error", err)
-		return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer(err.Error())}
-	}
-	var orgNames []string
-	for _, org := range orgs {
-		if req.Search != nil && !strings.Contains(*org.Login, *req.Search) {
-			continue
+		if next := nextPage(resp, func(page int) ListOrgsPaginationKey {
+			return ListOrgsPaginationKey{Page: util.Pointer(page)}
+		}); next != nil {
+			return orgNames, next, nil
 		}
-		orgNames = append(orgNames, *org.Login)
-	}
-	slog.InfoContext(ctx, "call to organizations.List succeeded", "n_orgs", len(orgNames))
-	var nextPaginationKey *ListOrgsPaginationKey
-
-	switch {
-	case resp != nil && resp.NextPage != 0:
-		nextPaginationKey = &ListOrgsPaginationKey{
-			Page: util.Pointer(resp.NextPage),
+		if len(orgNames) >= limit {
+			return orgNames, &ListOrgsPaginationKey{}, nil
 		}
-	case len(orgNames) < maxResults:
-		user, _, err := req.client.GetCurrentUser(ctx)
+		login, err := req.userLogin(ctx)
 		if err != nil {
-			slog.ErrorContext(ctx, "call to users.Get failed", "error", err)
-			return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer("unable to fetch data for github user")}
+			return nil, nil, err
 		}
-		if req.Search == nil || strings.Contains(*user.Login, *req.Search) {
-			orgNames = append(orgNames, *user.Login)
+		if login != "" {
+			orgNames = append(orgNames, login)
 		}
-	default:
-		nextPaginationKey = &ListOrgsPaginationKey{}
+		return orgNames, nil, nil
 	}
-	nextToken, err := NextToken(nextPaginationKey)
+}
+
+// userLogin returns the login of the connection's user, or "" if it doesn't match the search.
+func (req *pollerListOrgsForGithubConnectionRequest) userLogin(ctx context.Context) (string, error) {
+	if err := req.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	user, _, err := req.client.GetCurrentUser(ctx)
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to generate next pagination token", "error", err)
-		return &messages.ListOrgsForGithubConnectionResponse{ErrorMessage: util.Pointer("unable to generate pagination token")}
+		slog.ErrorContext(ctx, "call to users.Get failed", "error", err)
+		return "", fmt.Errorf("unable to fetch data for github user: %w", err)
 	}
-	return &messages.ListOrgsForGithubConnectionResponse{
-		Items:     orgNames,
-		NextToken: nextToken,
+	if req.Search != nil && !strings.Contains(*user.Login, *req.Search) {
+		return "", nil
 	}
+	return *user.Login, nil
 }
 
 type pollerSearchRepoRequest struct {
 	messages.SearchRepoRequest
-	client *github.Client
-	err    error
+	// IncludeArchived isn't part of the sdk's SearchRepoRequest yet, so it's decoded here. Archived repositories are
+	// included unless it's set to false.
+	IncludeArchived *bool
+	client          *github.Client
+	limiter         *rateLimiter
+	err             error
 }
 
 func (req *pollerSearchRepoRequest) Init(p *Poller) {
 	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+	req.limiter = p.githubRateLimiter(req.ConnectionID)
 }
 
 type SearchRepoPaginationKey struct {
@@ -174,65 +252,84 @@ func (req *pollerSearchRepoRequest) Process(ctx context.Context) messages.Messag
 	)
 	if req.err != nil {
 		slog.ErrorContext(ctx, "unable to initialize github client", "error", req.err, "connection_id", req.ConnectionID)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer(req.err.Error())}
-	}
-	if req.OrgName == "" {
-		slog.ErrorContext(ctx, "missing org name for search", "connection_id", req.ConnectionID)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer("org name is required")}
-	}
-	if req.Search == "" {
-		slog.ErrorContext(ctx, "missing search query", "connection_id", req.ConnectionID)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer("search query is required")}
+		msg, code := githubErrorFields(req.err)
+		return &SearchRepoResponse{ErrorMessage: msg, ErrorCode: code}
+	}
+	if req.Search == "" && !isSpecificOrg(req.OrgName) {
+		slog.ErrorContext(ctx, "missing search query and org name", "connection_id", req.ConnectionID)
+		return &SearchRepoResponse{
+			ErrorMessage: util.Pointer("search query or org name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
 	}
-	var paginationKey SearchRepoPaginationKey
-	limit, err := ParsePagination(req.MaxResults, req.Token, &paginationKey)
-
+	query := searchRepoQuery(req.Search, req.OrgName, req.IncludeArchived == nil || *req.IncludeArchived)
+	repos, nextToken, err := paginate(
+		ctx,
+		req.MaxResults,
+		req.Token,
+		SearchRepoPaginationKey{Page: 1},
+		func(key SearchRepoPaginationKey, limit int) ([]string, *SearchRepoPaginationKey, error) {
+			if err := req.limiter.Wait(ctx); err != nil {
+				return nil, nil, err
+			}
+			result, resp, err := req.client.SearchRepositories(
+				ctx,
+				query,
+				&ghapi.SearchOptions{ListOptions: ghapi.ListOptions{Page: key.Page, PerPage: limit}},
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "github repository search failed", "error", err)
+				return nil, nil, err
+			}
+			var repos []string
+			for _, repo := range result.Repositories {
+				repos = append(repos, *repo.FullName)
+			}
+			return repos, nextPage(resp, func(page int) SearchRepoPaginationKey {
+				return SearchRepoPaginationKey{Page: page}
+			}), nil
+		},
+	)
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to parse pagination key", "error", err, "connection_id", req.ConnectionID)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer(err.Error())}
+		msg, code := githubErrorFields(err)
+		return &SearchRepoResponse{ErrorMessage: msg, ErrorCode: code}
 	}
+	return &SearchRepoResponse{Items: repos, NextToken: nextToken}
+}
 
-	if req.Token == nil {
-		paginationKey.Page = 1
-	}
+// isSpecificOrg reports whether orgName scopes a repository search to one org. An empty name or "*" searches every
+// repository the connection's token can access.
+func isSpecificOrg(orgName string) bool {
+	return orgName != "" && orgName != "*"
+}
 
-	query := fmt.Sprintf("%s org:%s fork:true", req.Search, req.OrgName)
-	result, resp, searchErr := req.client.SearchRepositories(
-		ctx,
-		query,
-		&ghapi.SearchOptions{ListOptions: ghapi.ListOptions{Page: paginationKey.Page, PerPage: limit}},
-	)
-	if searchErr != nil {
-		slog.ErrorContext(ctx, "github repository search failed", "error", searchErr)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer(searchErr.Error())}
+// searchRepoQuery builds the GitHub repository search query for a SearchRepoRequest. Forks are always included, and
+// archived repositories are excluded when includeArchived is false.
+func searchRepoQuery(search string, orgName string, includeArchived bool) string {
+	var terms []string
+	if search != "" {
+		terms = append(terms, search)
 	}
-	var repos []string
-	for _, repo := range result.Repositories {
-		repos = append(repos, *repo.FullName)
+	if isSpecificOrg(orgName) {
+		terms = append(terms, "org:"+orgName)
 	}
-	var nextPaginationKey *SearchRepoPaginationKey
-	if resp != nil && resp.NextPage != 0 {
-		nextPaginationKey = &SearchRepoPaginationKey{
-			Page: resp.NextPage,
-		}
-	}
-
-	nextToken, err := NextToken(nextPaginationKey)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to generate next pagination token", "error", err)
-		return &messages.SearchRepoResponse{ErrorMessage: util.Pointer("unable to generate pagination token")}
+	terms = append(terms, "fork:true")
+	if !includeArchived {
+		terms = append(terms, "archived:false")
 	}
-	return &messages.SearchRepoResponse{Items: repos, NextToken: nextToken}
+	return strings.Join(terms, " ")
 }
 
 type pollerListRepoBranchesRequest struct {
 	messages.ListRepoBranchesRequest
-	client *github.Client
-	err    error
+	client  *github.Client
+	limiter *rateLimiter
+	err     error
 }
 
 func (req *pollerListRepoBranchesRequest) Init(p *Poller) {
 	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+	req.limiter = p.githubRateLimiter(req.ConnectionID)
 }
 
 type ListRepoBranchesPaginationKey struct {
@@ -254,54 +351,61 @@ func (req *pollerListRepoBranchesRequest) Process(ctx context.Context) messages.
 	)
 	if req.err != nil {
 		slog.ErrorContext(ctx, "unable to initialize github client", "error", req.err, "connection_id", req.ConnectionID)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer(req.err.Error())}
+		msg, code := githubErrorFields(req.err)
+		return &ListRepoBranchesResponse{ErrorMessage: msg, ErrorCode: code}
 	}
 	if req.OrgName == "" {
 		slog.ErrorContext(ctx, "missing org name for branch listing", "connection_id", req.ConnectionID)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer("org name is required")}
+		return &ListRepoBranchesResponse{
+			ErrorMessage: util.Pointer("org name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
 	}
 	if req.RepoName == "" {
 		slog.ErrorContext(ctx, "missing repo name for branch listing", "connection_id", req.ConnectionID)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer("repo name is required")}
-	}
-	var paginationKey ListRepoBranchesPaginationKey
-	limit, err := ParsePagination(req.MaxResults, req.Token, &paginationKey)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to parse pagination key", "error", err, "connection_id", req.ConnectionID)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer(err.Error())}
-	}
-	if req.Token == nil {
-		paginationKey.Page = 1
+		return &ListRepoBranchesResponse{
+			ErrorMessage: util.Pointer("repo name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
 	}
-	branches, resp, err := req.client.ListBranches(
+	branchNames, nextToken, err := paginate(
 		ctx,
-		req.OrgName,
-		req.RepoName,
-		&ghapi.BranchListOptions{ListOptions: ghapi.ListOptions{Page: paginationKey.Page, PerPage: limit}},
+		req.MaxResults,
+		req.Token,
+		ListRepoBranchesPaginationKey{Page: 1},
+		func(key ListRepoBranchesPaginationKey, limit int) ([]string, *ListRepoBranchesPaginationKey, error) {
+			if err := req.limiter.Wait(ctx); err != nil {
+				return nil, nil, err
+			}
+			branches, resp, err := req.client.ListBranches(
+				ctx,
+				req.OrgName,
+				req.RepoName,
+				&ghapi.BranchListOptions{ListOptions: ghapi.ListOptions{Page: key.Page, PerPage: limit}},
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "github branch listing failed", "error", err)
+				return nil, nil, err
+			}
+			var branchNames []string
+			for _, branch := range branches {
+				name := branch.GetName()
+				if name == "" {
+					continue
+				}
+				if req.Search != nil && !strings.Contains(name, *req.Search) {
+					continue
+				}
+				branchNames = append(branchNames, name)
+			}
+			return branchNames, nextPage(resp, func(page int) ListRepoBranchesPaginationKey {
+				return ListRepoBranchesPaginationKey{Page: page}
+			}), nil
+		},
 	)
 	if err != nil {
-		slog.ErrorContext(ctx, "github branch listing failed", "error", err)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer(err.Error())}
-	}
-	var branchNames []string
-	for _, branch := range branches {
-		name := branch.GetName()
-		if name == "" {
-			continue
-		}
-		if req.Search != nil && !strings.Contains(name, *req.Search) {
-			continue
-		}
-		branchNames = append(branchNames, name)
-	}
-	var nextPaginationKey *ListRepoBranchesPaginationKey
-	if resp != nil && resp.NextPage != 0 {
-		nextPaginationKey = &ListRepoBranchesPaginationKey{Page: resp.NextPage}
-	}
-	nextToken, err := NextToken(nextPaginationKey)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to generate next pagination token", "error", err)
-		return &messages.ListRepoBranchesResponse{ErrorMessage: util.Pointer("unable to generate pagination token")}
+		msg, code := githubErrorFields(err)
+		return &ListRepoBranchesResponse{ErrorMessage: msg, ErrorCode: code}
 	}
-	return &messages.ListRepoBranchesResponse{Items: branchNames, NextToken: nextToken}
+	return &ListRepoBranchesResponse{Items: branchNames, NextToken: nextToken}
 }
@@ -0,0 +1,120 @@
+package poller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRepoQuery(t *testing.T) {
+	testCases := []struct {
+		name            string
+		search          string
+		orgName         string
+		excludeArchived bool
+		expected        string
+	}{
+		{name: "org scoped", search: "cli", orgName: "plan42-ai", expected: "cli org:plan42-ai fork:true"},
+		{name: "no org", search: "cli", orgName: "", expected: "cli fork:true"},
+		{name: "wildcard org", search: "cli", orgName: "*", expected: "cli fork:true"},
+		{name: "org without search", search: "", orgName: "plan42-ai", expected: "org:plan42-ai fork:true"},
+		{
+			name:            "exclude archived",
+			search:          "cli",
+			orgName:         "plan42-ai",
+			excludeArchived: true,
+			expected:        "cli org:plan42-ai fork:true archived:false",
+		},
+		{name: "exclude archived without org", search: "cli", excludeArchived: true, expected: "cli fork:true archived:false"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, searchRepoQuery(tc.search, tc.orgName, !tc.excludeArchived))
+		})
+	}
+}
+
+func TestSearchRepoIncludeArchived(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"total_count": 0, "items": []}`))
+	}))
+	defer server.Close()
+	p := newGithubTestPoller(server)
+
+	for _, data := range []string{
+		`{"Type": "SearchRepoRequest", "ConnectionID": "` + testConnectionID + `", "Search": "cli"}`,
+		`{"Type": "SearchRepoRequest", "ConnectionID": "` + testConnectionID + `", "Search": "cli", "IncludeArchived": true}`,
+		`{"Type": "SearchRepoRequest", "ConnectionID": "` + testConnectionID + `", "Search": "cli", "IncludeArchived": false}`,
+	} {
+		msg, err := p.parseMessage([]byte(data))
+		require.NoError(t, err)
+		resp := msg.Process(t.Context()).(*SearchRepoResponse)
+		require.Nil(t, resp.ErrorMessage)
+	}
+	require.Equal(t, []string{"cli fork:true", "cli fork:true", "cli fork:true archived:false"}, queries)
+}
+
+func TestSearchRepoRequiresSearchOrOrg(t *testing.T) {
+	req := &pollerSearchRepoRequest{}
+	req.OrgName = "*"
+	resp := req.Process(t.Context())
+	require.Equal(t, "search query or org name is required", *resp.(*SearchRepoResponse).ErrorMessage)
+	require.Equal(t, ErrorCodeInvalidRequest, *resp.(*SearchRepoResponse).ErrorCode)
+}
+
+func TestListRepoBranchesNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`))
+	}))
+	defer server.Close()
+
+	req := &pollerListRepoBranchesRequest{}
+	req.ConnectionID = testConnectionID
+	req.OrgName = "plan42-ai"
+	req.RepoName = "missing"
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListRepoBranchesResponse)
+	require.Equal(t, ErrorCodeNotFound, *resp.ErrorCode)
+	require.NotContains(t, *resp.ErrorMessage, server.URL)
+}
+
+func TestClassifyGithubError(t *testing.T) {
+	status := func(code int) error {
+		return &ghapi.ErrorResponse{Response: &http.Response{StatusCode: code}}
+	}
+	testCases := []struct {
+		name     string
+		err      error
+		expected ErrorCode
+	}{
+		{name: "unauthorized", err: status(http.StatusUnauthorized), expected: ErrorCodeUnauthorized},
+		{name: "forbidden", err: status(http.StatusForbidden), expected: ErrorCodeUnauthorized},
+		{name: "not found", err: status(http.StatusNotFound), expected: ErrorCodeNotFound},
+		{name: "too many requests", err: status(http.StatusTooManyRequests), expected: ErrorCodeRateLimited},
+		{name: "rate limit", err: &ghapi.RateLimitError{}, expected: ErrorCodeRateLimited},
+		{name: "secondary rate limit", err: &ghapi.AbuseRateLimitError{}, expected: ErrorCodeRateLimited},
+		{
+			name:     "network",
+			err:      &url.Error{Op: "Get", URL: "https://api.github.com", Err: errors.New("connection refused")},
+			expected: ErrorCodeNetwork,
+		},
+		{name: "pagination token", err: errInvalidPaginationToken, expected: ErrorCodeInvalidRequest},
+		{name: "server error", err: status(http.StatusInternalServerError), expected: ErrorCodeInternal},
+		{name: "other", err: errors.New("boom"), expected: ErrorCodeInternal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, classifyGithubError(tc.err))
+		})
+	}
+}
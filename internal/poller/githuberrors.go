@@ -0,0 +1,74 @@
+package poller
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+// ErrorCode classifies the error in a github handler response, so the server can act on it without parsing the
+// message.
+type ErrorCode string
+
+const (
+	ErrorCodeUnauthorized   ErrorCode = "unauthorized"
+	ErrorCodeNotFound       ErrorCode = "not_found"
+	ErrorCodeRateLimited    ErrorCode = "rate_limited"
+	ErrorCodeNetwork        ErrorCode = "network"
+	ErrorCodeInvalidRequest ErrorCode = "invalid_request"
+	ErrorCodeInternal       ErrorCode = "internal"
+)
+
+// githubErrorMessages are the messages returned for errors from github, in place of the raw error, which can include
+// request urls and response bodies.
+var githubErrorMessages = map[ErrorCode]string{
+	ErrorCodeUnauthorized: "github rejected the connection's credentials",
+	ErrorCodeNotFound:     "not found on github, or the connection doesn't have access to it",
+	ErrorCodeRateLimited:  "github rate limit exceeded, try again later",
+	ErrorCodeNetwork:      "unable to reach github",
+}
+
+// classifyGithubError returns the error code for err.
+func classifyGithubError(err error) ErrorCode {
+	var rateLimitErr *ghapi.RateLimitError
+	var abuseErr *ghapi.AbuseRateLimitError
+	var respErr *ghapi.ErrorResponse
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseErr):
+		return ErrorCodeRateLimited
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		switch respErr.Response.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorCodeUnauthorized
+		case http.StatusNotFound:
+			return ErrorCodeNotFound
+		case http.StatusTooManyRequests:
+			return ErrorCodeRateLimited
+		}
+	case errors.As(err, &netErr):
+		return ErrorCodeNetwork
+	case errors.Is(err, errInvalidPaginationToken), errors.Is(err, errMaxResultInvalid):
+		return ErrorCodeInvalidRequest
+	}
+	return ErrorCodeInternal
+}
+
+// githubErrorFields returns the ErrorMessage and ErrorCode of the response for err. Errors from github get a stable
+// message for their class. Anything else keeps its own message, since it doesn't come from github.
+func githubErrorFields(err error) (*string, *ErrorCode) {
+	code := classifyGithubError(err)
+	msg, ok := githubErrorMessages[code]
+	if !ok {
+		var respErr *ghapi.ErrorResponse
+		if errors.As(err, &respErr) {
+			msg = "github request failed"
+		} else {
+			msg = err.Error()
+		}
+	}
+	return util.Pointer(msg), util.Pointer(code)
+}
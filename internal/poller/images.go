@@ -0,0 +1,68 @@
+package poller
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/plan42-ai/cli/internal/docker"
+	"github.com/plan42-ai/cli/internal/p42runtime"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+// imageAllowed reports whether image may be run by an agent. An empty allowlist allows every image. Otherwise image
+// must exactly match an entry, or be pinned to a digest ("repo@sha256:...") listed in the allowlist.
+func imageAllowed(allowed []string, image string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if slices.Contains(allowed, image) {
+		return true
+	}
+	_, digest, ok := strings.Cut(image, "@")
+	return ok && slices.Contains(allowed, digest)
+}
+
+// checkImageAllowed returns an error if image isn't in the allowlist.
+func checkImageAllowed(allowed []string, image string) error {
+	if !imageAllowed(allowed, image) {
+		return fmt.Errorf("image %s is not in the runner's allowed_images", image)
+	}
+	return nil
+}
+
+// defaultImageTag is the tag of agent images that don't specify one.
+const defaultImageTag = "latest"
+
+// normalizeImage fully qualifies an agent image, so every runtime pulls the same one. An image that doesn't name a
+// registry is qualified with defaultRegistry, a registry host with an optional ":port"; an empty defaultRegistry uses
+// docker.io. An image without a tag is given the latest tag.
+func normalizeImage(image string, defaultRegistry string) (string, error) {
+	uri, err := docker.ParseImageURI(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %q: %w", image, err)
+	}
+	if uri.Registry == nil {
+		registry, err := p42runtime.ParseRegistryHost(cmp.Or(defaultRegistry, p42runtime.DefaultRegistry))
+		if err != nil {
+			return "", err
+		}
+		uri = uri.WithDefaultRegistry(registry.Registry)
+		uri.RegistryPort = registry.RegistryPort
+	}
+	return uri.WithDefaultTag(util.Pointer(defaultImageTag)).String(), nil
+}
+
+// ResolveAgentImage checks image against the allowed list and returns its fully qualified form, as normalized with
+// defaultRegistry, which is the image an agent runs. The allowlist may name the image as given or fully qualified.
+func ResolveAgentImage(image string, allowed []string, defaultRegistry string) (string, error) {
+	resolved, err := normalizeImage(image, defaultRegistry)
+	if err != nil {
+		return "", err
+	}
+	if !imageAllowed(allowed, image) && !imageAllowed(allowed, resolved) {
+		return "", checkImageAllowed(allowed, image)
+	}
+	return resolved, nil
+}
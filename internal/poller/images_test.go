@@ -0,0 +1,89 @@
+package poller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDigest = "sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"
+
+func TestImageAllowed(t *testing.T) {
+	const digest = testDigest
+	allowed := []string{"ghcr.io/plan42-ai/agent:1.2.3", digest}
+
+	testCases := []struct {
+		name    string
+		allowed []string
+		image   string
+		want    bool
+	}{
+		{name: "empty allowlist", image: "docker.io/library/alpine:latest", want: true},
+		{name: "listed image", allowed: allowed, image: "ghcr.io/plan42-ai/agent:1.2.3", want: true},
+		{name: "other tag", allowed: allowed, image: "ghcr.io/plan42-ai/agent:latest", want: false},
+		{name: "unlisted image", allowed: allowed, image: "docker.io/library/alpine:latest", want: false},
+		{name: "listed digest", allowed: allowed, image: "ghcr.io/plan42-ai/agent@" + digest, want: true},
+		{name: "unlisted digest", allowed: allowed, image: "ghcr.io/plan42-ai/agent@sha256:0000", want: false},
+		{name: "bare digest", allowed: allowed, image: digest, want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, imageAllowed(tc.allowed, tc.image))
+			if tc.want {
+				require.NoError(t, checkImageAllowed(tc.allowed, tc.image))
+			} else {
+				require.ErrorContains(t, checkImageAllowed(tc.allowed, tc.image), "allowed_images")
+			}
+		})
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	testCases := []struct {
+		name            string
+		image           string
+		defaultRegistry string
+		want            string
+	}{
+		{name: "bare image", image: "ubuntu", want: "docker.io/ubuntu:latest"},
+		{name: "namespaced image", image: "plan42-ai/agent:1.2.3", want: "docker.io/plan42-ai/agent:1.2.3"},
+		{name: "configured registry", image: "ubuntu", defaultRegistry: "ghcr.io", want: "ghcr.io/ubuntu:latest"},
+		{name: "registry without tag", image: "ghcr.io/plan42-ai/agent", want: "ghcr.io/plan42-ai/agent:latest"},
+		{name: "digest", image: "plan42-ai/agent@" + testDigest, want: "docker.io/plan42-ai/agent@" + testDigest},
+		{name: "tag and digest", image: "ghcr.io/plan42-ai/agent:1.2.3@" + testDigest, want: "ghcr.io/plan42-ai/agent:1.2.3@" + testDigest},
+		{name: "registry with port", image: "ubuntu:24.04", defaultRegistry: "registry.local:5000", want: "registry.local:5000/ubuntu:24.04"},
+		{name: "explicit registry", image: "ghcr.io/plan42-ai/agent:1.2.3", defaultRegistry: "registry.local", want: "ghcr.io/plan42-ai/agent:1.2.3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := normalizeImage(tc.image, tc.defaultRegistry)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := normalizeImage("ubuntu", "not a registry")
+	require.ErrorContains(t, err, "invalid registry host")
+}
+
+func TestResolveAgentImage(t *testing.T) {
+	allowed := []string{"alpine", "docker.io/busybox:latest", testDigest}
+
+	image, err := ResolveAgentImage("alpine", allowed, "")
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/alpine:latest", image)
+
+	// the allowlist may name the fully qualified image.
+	image, err = ResolveAgentImage("busybox", allowed, "")
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/busybox:latest", image)
+
+	image, err = ResolveAgentImage("plan42-ai/agent@"+testDigest, allowed, "registry.example.com:5000")
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com:5000/plan42-ai/agent@"+testDigest, image)
+
+	_, err = ResolveAgentImage("ubuntu", allowed, "")
+	require.ErrorContains(t, err, "allowed_images")
+}
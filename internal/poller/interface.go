@@ -2,6 +2,8 @@ package poller
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
@@ -11,3 +13,30 @@ type pollerMessage interface {
 	Init(p *Poller)
 	Process(ctx context.Context) messages.Message
 }
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = make(map[messages.MessageType]func() pollerMessage)
+)
+
+// registerHandler registers the constructor for messages of the given type. Handlers register themselves in init.
+// It panics if a handler is already registered for the type.
+func registerHandler(messageType messages.MessageType, newMessage func() pollerMessage) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if _, ok := handlers[messageType]; ok {
+		panic(fmt.Sprintf("poller: handler already registered for message type %v", messageType))
+	}
+	handlers[messageType] = newMessage
+}
+
+// newMessage returns a new, empty message of the given type for parseMessage to unmarshal into.
+func newMessage(messageType messages.MessageType) (pollerMessage, error) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	ctor, ok := handlers[messageType]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type: %v", messageType)
+	}
+	return ctor(), nil
+}
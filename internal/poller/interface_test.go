@@ -0,0 +1,64 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeRequestMessage messages.MessageType = "FakeRequest"
+
+// fakeRequest is a handler registered only in tests. The values of processed requests are sent to fakeProcessed.
+type fakeRequest struct {
+	Value string
+}
+
+var fakeProcessed = make(chan string, 10)
+
+func init() {
+	registerHandler(fakeRequestMessage, func() pollerMessage { return &fakeRequest{} })
+}
+
+func (req *fakeRequest) Type() messages.MessageType {
+	return fakeRequestMessage
+}
+
+func (req *fakeRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  messages.MessageType
+		Value string
+	}{Type: fakeRequestMessage, Value: req.Value})
+}
+
+func (req *fakeRequest) Init(_ *Poller) {}
+
+func (req *fakeRequest) Process(_ context.Context) messages.Message {
+	fakeProcessed <- req.Value
+	return &messages.PingResponse{}
+}
+
+func TestRegisteredHandlerReceivesMessages(t *testing.T) {
+	fs := newFakeServer(t)
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	id := fs.enqueue(&fakeRequest{Value: "hello"})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+	require.Equal(t, "hello", <-fakeProcessed)
+}
+
+func TestParseMessageUnknownType(t *testing.T) {
+	p := &Poller{}
+	_, err := p.parseMessage([]byte(`{"Type":"NoSuchRequest"}`))
+	require.ErrorContains(t, err, "unknown message type: NoSuchRequest")
+}
+
+func TestRegisterHandlerRejectsDuplicates(t *testing.T) {
+	require.Panics(t, func() {
+		registerHandler(messages.PingRequestMessage, func() pollerMessage { return &pollerPingRequest{} })
+	})
+}
@@ -1,12 +1,49 @@
 package poller
 
 import (
+	"encoding/json"
+
 	"github.com/plan42-ai/sdk-go/p42"
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
 
+func init() {
+	registerHandler(messages.InvokeAgentRequestMessage, func() pollerMessage { return &pollerInvokeAgentRequest{} })
+}
+
+// PRHead is the head of a PR at the time its feedback was fetched.
+type PRHead struct {
+	HeadRef string
+	HeadSHA string
+}
+
 type pollerInvokeAgentRequest struct {
 	InvokePlatformFields
 	messages.InvokeAgentRequest
-	client *p42.Client
+	// PRHeads holds the head of each PR whose feedback the runner fetched, keyed by org/repo, so the agent knows which
+	// revision the feedback was left on.
+	PRHeads map[string]PRHead
+	// IncludeMinimized sets whether minimized (e.g. outdated or resolved) comments are included in the PR feedback the
+	// runner fetches. They're included when it isn't set.
+	IncludeMinimized *bool
+	client           *p42.Client
+}
+
+// MarshalJSON marshals the request passed to the agent: the InvokeAgentRequest, as the sdk marshals it, with the PR
+// heads added. Starting from the sdk's encoding means fields it adds reach the agent without changes here.
+func (req *pollerInvokeAgentRequest) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(req.InvokeAgentRequest)
+	if err != nil || len(req.PRHeads) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["PRHeads"], err = json.Marshal(req.PRHeads)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
 }
@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/plan42-ai/cli/internal/docker"
+	"github.com/plan42-ai/cli/internal/github"
 	"github.com/plan42-ai/cli/internal/p42runtime"
 	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/concurrency"
 	"github.com/plan42-ai/log"
 	"github.com/plan42-ai/sdk-go/p42"
 	"github.com/plan42-ai/sdk-go/p42/messages"
@@ -40,12 +44,25 @@ func (req *pollerInvokeAgentRequest) Process(ctx context.Context) messages.Messa
 		return agentResponse(err)
 	}
 
-	err = req.validateDockerImage()
+	err = req.resolveImage()
+	if err != nil {
+		return agentResponse(err)
+	}
 
+	// Report a missing runtime now, while the caller is waiting for a response. Failures after this point are only
+	// logged.
+	err = p42runtime.CheckAvailable(req.Provider)
 	if err != nil {
 		return agentResponse(err)
 	}
 	containerID := fmt.Sprintf("plan42-%v-%v", req.Turn.TaskID, req.Turn.TurnIndex)
+
+	// The container ID is used as the container name, so check it fits the runtime's naming rules before we accept
+	// the job.
+	err = req.Provider.ValidateJobID(containerID)
+	if err != nil {
+		return agentResponse(fmt.Errorf("unable to name agent container: %w", err))
+	}
 	ctx = log.WithContextAttrs(
 		ctx,
 		slog.String("task_id", req.Turn.TaskID),
@@ -54,7 +71,8 @@ func (req *pollerInvokeAgentRequest) Process(ctx context.Context) messages.Messa
 	)
 	slog.InfoContext(ctx, "received invoke request")
 
-	go req.invokeAsync(ctx, containerID)
+	// The agent runs on after the response is sent, so it mustn't be tied to the queue's context.
+	req.startAgent(ctx, func(ctx context.Context) { req.invokeAsync(ctx, containerID) })
 	return &messages.InvokeAgentResponse{}
 }
 
@@ -92,7 +110,7 @@ func (req *pollerInvokeAgentRequest) runContainer(ctx context.Context, container
 		return
 	}
 
-	err = req.Provider.RunJob(ctx, p42runtime.JobOptions{
+	opts := p42runtime.JobOptions{
 		JobID:      containerID,
 		Image:      req.Environment.DockerImage,
 		CPUs:       4,
@@ -103,13 +121,62 @@ func (req *pollerInvokeAgentRequest) runContainer(ctx context.Context, container
 			"--plan42-proxy",
 			"--log-agent-output",
 		},
-		Stdin: bytes.NewReader(jsonBytes),
-	})
+		Stdin:            bytes.NewReader(jsonBytes),
+		KeepOnExit:       req.keepContainers,
+		MinFreeDiskBytes: req.minFreeDiskBytes,
+		MaxLogSize:       req.maxLogSize,
+		MaxLogFiles:      req.maxLogFiles,
+	}
+	if req.foregroundLogs != nil {
+		foreground := p42runtime.NewPrefixWriter(req.foregroundLogs, "["+containerID+"] ")
+		defer foreground.Flush()
+		opts.Stdout = foreground
+		opts.Stderr = foreground
+	}
 
-	if err != nil {
-		slog.ErrorContext(ctx, "container run failed", "error", err)
+	result := runAgentJob(ctx, req.Provider, opts, req.agentTimeout)
+
+	if !result.Failed() {
 		return
 	}
+	if result.TimedOut {
+		slog.ErrorContext(ctx, "agent timed out", "timeout", req.agentTimeout, "error", result.Err)
+	} else {
+		slog.ErrorContext(ctx, "container run failed", "exit_code", result.ExitCode, "error", result.Err)
+	}
+	if err := req.reportAgentFailure(ctx, result); err != nil {
+		slog.ErrorContext(ctx, "failed to report agent failure", "error", err)
+	}
+}
+
+// reportAgentFailure records a failed agent run on the turn, so the server learns why the agent stopped even if the
+// agent itself never reported back.
+func (req *pollerInvokeAgentRequest) reportAgentFailure(ctx context.Context, result agentRunResult) error {
+	update := &p42.UpdateTurnRequest{
+		TenantID:     req.Turn.TenantID,
+		TaskID:       req.Turn.TaskID,
+		TurnIndex:    req.Turn.TurnIndex,
+		Version:      req.Turn.Version,
+		Status:       util.Pointer("Agent Failed on Local Runner"),
+		ErrorMessage: util.Pointer(result.ErrorMessage()),
+	}
+	updated, err := req.client.UpdateTurn(ctx, update)
+
+	// The agent may have updated the turn while it ran, so retry once against the current version.
+	var conflictErr *p42.ConflictError
+	if errors.As(err, &conflictErr) {
+		current, ok := conflictErr.Current.(*p42.Turn)
+		if !ok {
+			return err
+		}
+		update.Version = current.Version
+		updated, err = req.client.UpdateTurn(ctx, update)
+	}
+	if err != nil {
+		return err
+	}
+	req.Turn = updated
+	return nil
 }
 
 func (req *pollerInvokeAgentRequest) shouldFetchPRFeedback() bool {
@@ -137,6 +204,22 @@ func (req *pollerInvokeAgentRequest) updateTurnStatus(ctx context.Context, statu
 	return nil
 }
 
+// resolveImage validates the agent image, checks it against the allowlist, and replaces it with its fully qualified
+// form. Digest-pinned images keep their digest.
+func (req *pollerInvokeAgentRequest) resolveImage() error {
+	err := req.validateDockerImage()
+	if err != nil {
+		return err
+	}
+
+	image, err := ResolveAgentImage(req.Environment.DockerImage, req.allowedImages, req.defaultRegistry)
+	if err != nil {
+		return err
+	}
+	req.Environment.DockerImage = image
+	return nil
+}
+
 func (req *pollerInvokeAgentRequest) validateDockerImage() error {
 	_, err := docker.ParseImageURI(req.Environment.DockerImage)
 	if err != nil {
@@ -145,6 +228,17 @@ func (req *pollerInvokeAgentRequest) validateDockerImage() error {
 	return nil
 }
 
+const (
+	// prFeedbackAttempts is the number of times collection of a PR's feedback is attempted. Retries resume from where
+	// the previous attempt failed.
+	prFeedbackAttempts = 3
+
+	// prFeedbackBackoffMin and prFeedbackBackoffMax bound the wait between attempts, so a rate limit or brief outage
+	// at github doesn't use up every attempt at once.
+	prFeedbackBackoffMin = 1 * time.Second
+	prFeedbackBackoffMax = 10 * time.Second
+)
+
 func (req *pollerInvokeAgentRequest) fetchPRFeedbackIfNeeded(ctx context.Context) error {
 	if req.FeedBack != nil || req.PrivateGithubConnectionID == nil {
 		return nil
@@ -155,12 +249,18 @@ func (req *pollerInvokeAgentRequest) fetchPRFeedbackIfNeeded(ctx context.Context
 	}
 
 	feedback := make(map[string][]messages.PRFeedback)
+	heads := make(map[string]PRHead)
 
 	repoInfo := map[string]*p42.RepoInfo{}
 	if req.Task != nil && req.Task.RepoInfo != nil {
 		repoInfo = req.Task.RepoInfo
 	}
 
+	var options []github.FeedbackOption
+	if req.IncludeMinimized != nil {
+		options = append(options, github.WithIncludeMinimized(*req.IncludeMinimized))
+	}
+
 	for orgRepo, info := range repoInfo {
 		if info == nil || info.PRNumber == nil {
 			continue
@@ -169,13 +269,25 @@ func (req *pollerInvokeAgentRequest) fetchPRFeedbackIfNeeded(ctx context.Context
 		if err != nil {
 			return err
 		}
-		fb, err := req.githubClient.GetPRFeedBack(ctx, org, repo, *info.PRNumber)
+		fb, err := req.githubClient.GetPRFeedBack(ctx, org, repo, *info.PRNumber, options...)
+		backoff := concurrency.NewBackoff(prFeedbackBackoffMin, prFeedbackBackoffMax)
+		for attempt := 1; err != nil && attempt < prFeedbackAttempts; attempt++ {
+			slog.WarnContext(ctx, "failed to fetch pr feedback, resuming", "repo", orgRepo, "attempt", attempt, "error", err)
+			backoff.Backoff()
+			if waitErr := backoff.WaitContext(ctx); waitErr != nil {
+				return errors.Join(err, waitErr)
+			}
+			fb, err = req.githubClient.ResumePRFeedBack(ctx, org, repo, *info.PRNumber, fb, options...)
+		}
 		if err != nil {
 			return err
 		}
-		feedback[orgRepo] = fb
+		slog.DebugContext(ctx, "fetched pr feedback", "repo", orgRepo, "headRef", fb.HeadRef, "headSHA", fb.HeadSHA)
+		feedback[orgRepo] = fb.Feedback
+		heads[orgRepo] = PRHead{HeadRef: fb.HeadRef, HeadSHA: fb.HeadSHA}
 	}
 
+	req.PRHeads = heads
 	return setFeedback(&req.FeedBack, feedback)
 }
 
@@ -213,6 +325,15 @@ func (req *pollerInvokeAgentRequest) Init(p *Poller) {
 	req.ContainerPath = p.ContainerPath
 	req.PodmanPath = p.PodmanPath
 	req.Provider = p.Provider
+	req.agentTimeout = p.agentTimeout
+	req.keepContainers = p.keepContainers
+	req.minFreeDiskBytes = p.minFreeDiskBytes
+	req.maxLogSize = p.maxLogSize
+	req.maxLogFiles = p.maxLogFiles
+	req.allowedImages = p.allowedImages
+	req.defaultRegistry = p.defaultRegistry
+	req.foregroundLogs = p.foregroundLogs
+	req.startAgent = p.startAgent
 	req.client = p.client.WithAPIToken(req.AgentToken)
 	if req.PrivateGithubConnectionID != nil {
 		cnn := p.connectionIdx[*req.PrivateGithubConnectionID]
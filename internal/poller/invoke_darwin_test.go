@@ -0,0 +1,208 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/github"
+	"github.com/plan42-ai/cli/internal/p42runtime/podman"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeDigestPinnedImage(t *testing.T) {
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	binPath := filepath.Join(dir, "podman")
+	script := "#!/bin/sh\necho \"$@\" >> \"" + argsPath + "\"\n"
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+
+	const image = "ghcr.io/plan42-ai/agent@" + testDigest
+	req := &pollerInvokeAgentRequest{
+		InvokePlatformFields: InvokePlatformFields{
+			Provider:      podman.NewProvider(binPath, "", podman.WithMachineCheck(false)),
+			allowedImages: []string{testDigest},
+		},
+		InvokeAgentRequest: messages.InvokeAgentRequest{
+			Turn:        &p42.Turn{TaskID: "6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab", TurnIndex: 1},
+			Environment: &p42.Environment{DockerImage: image},
+		},
+	}
+
+	require.NoError(t, req.resolveImage())
+	require.Equal(t, image, req.Environment.DockerImage)
+
+	// the digest-pinned reference reaches the provider unchanged.
+	req.runContainer(t.Context(), "plan42-6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab-1")
+	args, err := os.ReadFile(argsPath)
+	require.NoError(t, err)
+	require.Contains(t, string(args), " "+image+" ")
+}
+
+// newFeedbackGithubClient returns a github client backed by a fake GraphQL endpoint for a PR whose head is feature at
+// abc123, with one active comment and one that has been minimized.
+func newFeedbackGithubClient(t *testing.T) *github.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query := string(body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(query, "reviewThreads"):
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {
+				"headRefName": "feature",
+				"headRefOid": "abc123",
+				"reviewThreads": {"pageInfo": {"hasNextPage": false}, "nodes": []}
+			}}}}`))
+		case strings.Contains(query, "reviews("):
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {}}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"id": "comment-1", "author": {"login": "reviewer"}, "body": "active comment"},
+				{"id": "comment-2", "author": {"login": "reviewer"}, "body": "outdated comment", "isMinimized": true, "minimizedReason": "outdated"}
+			]}}}}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := github.NewClient("test-token", server.URL)
+	require.NoError(t, err)
+	return client
+}
+
+// agentPayload is the part of the request passed to the agent on stdin that the tests check.
+type agentPayload struct {
+	FeedBack map[string][]messages.PRFeedback
+	PRHeads  map[string]PRHead
+}
+
+// invokeWithFeedback fetches the feedback for req's PR and runs the agent with a fake podman, returning the request
+// the agent received on stdin.
+func invokeWithFeedback(t *testing.T, req *pollerInvokeAgentRequest) agentPayload {
+	dir := t.TempDir()
+	stdinPath := filepath.Join(dir, "stdin")
+	binPath := filepath.Join(dir, "podman")
+	script := "#!/bin/sh\nif [ \"$1\" = run ]; then cat > \"" + stdinPath + "\"; fi\n"
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte(script), 0o755))
+
+	req.Provider = podman.NewProvider(binPath, "", podman.WithMachineCheck(false))
+	req.githubClient = newFeedbackGithubClient(t)
+	req.PrivateGithubConnectionID = util.Pointer(testConnectionID)
+	req.Task = &p42.Task{RepoInfo: map[string]*p42.RepoInfo{"plan42-ai/cli": {PRNumber: util.Pointer(42)}}}
+	req.Turn = &p42.Turn{TaskID: "6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab", TurnIndex: 2}
+	req.Environment = &p42.Environment{DockerImage: "ghcr.io/plan42-ai/agent:latest"}
+
+	require.NoError(t, req.fetchPRFeedbackIfNeeded(t.Context()))
+	req.runContainer(t.Context(), "plan42-6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab-2")
+
+	data, err := os.ReadFile(stdinPath)
+	require.NoError(t, err)
+	var payload agentPayload
+	require.NoError(t, json.Unmarshal(data, &payload))
+	return payload
+}
+
+func TestInvokeSendsPRHeadToAgent(t *testing.T) {
+	payload := invokeWithFeedback(t, &pollerInvokeAgentRequest{})
+	require.Equal(t, map[string]PRHead{"plan42-ai/cli": {HeadRef: "feature", HeadSHA: "abc123"}}, payload.PRHeads)
+	require.Len(t, payload.FeedBack["plan42-ai/cli"], 2)
+}
+
+func TestInvokeExcludesMinimizedFeedback(t *testing.T) {
+	var req pollerInvokeAgentRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"Type": "InvokeAgentRequest", "IncludeMinimized": false}`), &req))
+	require.NotNil(t, req.IncludeMinimized)
+
+	payload := invokeWithFeedback(t, &req)
+	feedback := payload.FeedBack["plan42-ai/cli"]
+	require.Len(t, feedback, 1)
+	require.Len(t, feedback[0].Comments, 1)
+	require.Equal(t, "active comment", feedback[0].Comments[0].Body)
+}
+
+func TestPRFeedbackRetryWaitsForBackoff(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client, err := github.NewClient("test-token", server.URL)
+	require.NoError(t, err)
+
+	req := &pollerInvokeAgentRequest{}
+	req.githubClient = client
+	req.PrivateGithubConnectionID = util.Pointer(testConnectionID)
+	req.Task = &p42.Task{RepoInfo: map[string]*p42.RepoInfo{"plan42-ai/cli": {PRNumber: util.Pointer(42)}}}
+
+	// the retries wait out a backoff of at least a second, so they can't all be made before the context expires, and
+	// the wait gives up when it does.
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	err = req.fetchPRFeedbackIfNeeded(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, requests.Load(), int64(prFeedbackAttempts))
+}
+
+func TestInvokeRejectsUnnameableContainer(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "podman")
+	// #nosec G306: test binary must be executable.
+	require.NoError(t, os.WriteFile(binPath, []byte("#!/bin/sh\n"), 0o755))
+
+	testCases := []struct {
+		name      string
+		taskID    string
+		turnIndex int
+	}{
+		{
+			name:      "urn",
+			taskID:    "urn:uuid:6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab",
+			turnIndex: 1,
+		},
+		{
+			name:      "braces",
+			taskID:    "{6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab}",
+			turnIndex: 1,
+		},
+		{
+			name:      "turn index",
+			taskID:    "6f1c2d3e-0a1b-4c5d-8e9f-0123456789ab",
+			turnIndex: 12345678901,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &pollerInvokeAgentRequest{
+				InvokePlatformFields: InvokePlatformFields{
+					Provider: podman.NewProvider(binPath, "", podman.WithMachineCheck(false)),
+				},
+				InvokeAgentRequest: messages.InvokeAgentRequest{
+					Turn:        &p42.Turn{TaskID: tc.taskID, TurnIndex: tc.turnIndex},
+					Environment: &p42.Environment{DockerImage: "ghcr.io/plan42-ai/agent:latest"},
+				},
+			}
+
+			// the task ID parses as a UUID, so the request is only rejected when naming the container.
+			resp, ok := req.Process(t.Context()).(*messages.InvokeAgentResponse)
+			require.True(t, ok)
+			require.NotNil(t, resp.ErrorMessage)
+			require.Contains(t, *resp.ErrorMessage, "unable to name agent container")
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !darwin
+
+package poller
+
+import (
+	"testing"
+
+	"github.com/plan42-ai/sdk-go/p42"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeNotSupported(t *testing.T) {
+	p := &Poller{client: p42.NewClient("https://plan42.example.com")}
+	msg, err := p.parseMessage([]byte(`{"Type": "InvokeAgentRequest", "AgentToken": "agent-token"}`))
+	require.NoError(t, err)
+
+	resp, ok := msg.Process(t.Context()).(*messages.InvokeAgentResponse)
+	require.True(t, ok)
+	require.NotNil(t, resp.ErrorMessage)
+}
@@ -0,0 +1,36 @@
+package poller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeAgentRequestMarshalJSON(t *testing.T) {
+	req := &pollerInvokeAgentRequest{}
+	req.GithubURL = util.Pointer("https://github.com")
+	req.AgentToken = "agent-token"
+	req.FeedBack = &map[string][]messages.PRFeedback{"plan42-ai/cli": {{ID: "1"}}}
+
+	sdkData, err := json.Marshal(req.InvokeAgentRequest)
+	require.NoError(t, err)
+
+	// without PR heads, the request is marshaled exactly as the sdk marshals it.
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	require.JSONEq(t, string(sdkData), string(data))
+
+	// with them, every field the sdk marshals is kept alongside the PR heads.
+	req.PRHeads = map[string]PRHead{"plan42-ai/cli": {HeadRef: "feature", HeadSHA: "abc123"}}
+	data, err = json.Marshal(req)
+	require.NoError(t, err)
+
+	var expected, actual map[string]any
+	require.NoError(t, json.Unmarshal(sdkData, &expected))
+	require.NoError(t, json.Unmarshal(data, &actual))
+	expected["PRHeads"] = map[string]any{"plan42-ai/cli": map[string]any{"HeadRef": "feature", "HeadSHA": "abc123"}}
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,18 @@
+package poller
+
+import (
+	"context"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+)
+
+func (req *pollerInvokeAgentRequest) Process(_ context.Context) messages.Message {
+	return &messages.InvokeAgentResponse{
+		ErrorMessage: util.Pointer("Windows runner not yet supported for agent invocation"),
+	}
+}
+
+func (req *pollerInvokeAgentRequest) Init(p *Poller) {
+	req.client = p.client.WithAPIToken(req.AgentToken)
+}
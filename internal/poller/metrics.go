@@ -0,0 +1,182 @@
+package poller
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plan42-ai/sdk-go/p42/messages"
+)
+
+// MessageOutcome is the result of processing a message, as recorded in MessageMetrics.
+type MessageOutcome string
+
+const (
+	OutcomeSuccess        MessageOutcome = "success"
+	OutcomeDecryptFailure MessageOutcome = "decrypt_failure"
+	OutcomeParseFailure   MessageOutcome = "parse_failure"
+	OutcomeWriteFailure   MessageOutcome = "write_failure"
+	// OutcomeCallerGone is a message whose caller went away before its response could be written.
+	OutcomeCallerGone MessageOutcome = "caller_gone"
+	// OutcomeFailure covers every other failure, such as an oversized payload or a panic.
+	OutcomeFailure MessageOutcome = "failure"
+)
+
+// defaultMetricsLogInterval is how often the message metrics are logged, unless changed with WithMetricsLogInterval.
+const defaultMetricsLogInterval = 15 * time.Minute
+
+// UnknownMessageType labels messages that failed before their type was known, e.g. because they couldn't be
+// decrypted.
+const UnknownMessageType messages.MessageType = "Unknown"
+
+// LatencyBuckets are the upper bounds of the buckets of the message processing latency histogram.
+var LatencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// LatencyHistogram is a histogram of message processing durations. Counts[i] is the number of messages that took at
+// most LatencyBuckets[i], and not longer than the previous bucket; the last count is for messages that took longer
+// than every bucket.
+type LatencyHistogram struct {
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+}
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]int64, len(LatencyBuckets)+1)
+	}
+	idx, _ := slices.BinarySearch(LatencyBuckets, d)
+	h.Counts[idx]++
+	h.Count++
+	h.Sum += d
+}
+
+// MessageMetrics is a snapshot of the message processing metrics, labelled by message type.
+type MessageMetrics struct {
+	Outcomes map[messages.MessageType]map[MessageOutcome]int64
+	Latency  map[messages.MessageType]LatencyHistogram
+	// Duplicates is the number of redelivered messages that were skipped because they had already been processed.
+	Duplicates int64
+}
+
+// messageMetrics records the outcome and end-to-end duration of every message processed by the poller. Redelivered
+// duplicates are only counted, since they aren't processed again.
+type messageMetrics struct {
+	mu         sync.Mutex
+	outcomes   map[messages.MessageType]map[MessageOutcome]int64
+	latency    map[messages.MessageType]*LatencyHistogram
+	duplicates int64
+}
+
+func newMessageMetrics() *messageMetrics {
+	return &messageMetrics{
+		outcomes: make(map[messages.MessageType]map[MessageOutcome]int64),
+		latency:  make(map[messages.MessageType]*LatencyHistogram),
+	}
+}
+
+func (m *messageMetrics) record(msgType messages.MessageType, outcome MessageOutcome, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.outcomes[msgType] == nil {
+		m.outcomes[msgType] = make(map[MessageOutcome]int64)
+		m.latency[msgType] = &LatencyHistogram{}
+	}
+	m.outcomes[msgType][outcome]++
+	m.latency[msgType].observe(d)
+}
+
+func (m *messageMetrics) recordDuplicate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duplicates++
+}
+
+func (m *messageMetrics) snapshot() MessageMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := MessageMetrics{
+		Outcomes:   make(map[messages.MessageType]map[MessageOutcome]int64, len(m.outcomes)),
+		Latency:    make(map[messages.MessageType]LatencyHistogram, len(m.latency)),
+		Duplicates: m.duplicates,
+	}
+	for msgType, outcomes := range m.outcomes {
+		ret.Outcomes[msgType] = make(map[MessageOutcome]int64, len(outcomes))
+		for outcome, n := range outcomes {
+			ret.Outcomes[msgType][outcome] = n
+		}
+		h := *m.latency[msgType]
+		h.Counts = slices.Clone(h.Counts)
+		ret.Latency[msgType] = h
+	}
+	return ret
+}
+
+// LogValue logs the metrics as a group per message type, holding the count of each outcome along with the number of
+// messages and their mean processing latency, followed by the number of duplicates skipped, if any.
+func (m MessageMetrics) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(m.Outcomes)+1)
+	for _, msgType := range slices.Sorted(maps.Keys(m.Outcomes)) {
+		outcomes := m.Outcomes[msgType]
+		group := make([]any, 0, 2*len(outcomes)+4)
+		for _, outcome := range slices.Sorted(maps.Keys(outcomes)) {
+			group = append(group, string(outcome), outcomes[outcome])
+		}
+		latency := m.Latency[msgType]
+		group = append(group, "count", latency.Count)
+		if latency.Count > 0 {
+			group = append(group, "mean_latency", latency.Sum/time.Duration(latency.Count))
+		}
+		attrs = append(attrs, slog.Group(string(msgType), group...))
+	}
+	if m.Duplicates > 0 {
+		attrs = append(attrs, slog.Int64("duplicates", m.Duplicates))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// MessageMetrics returns a snapshot of the outcomes and processing latency of the messages processed so far.
+func (p *Poller) MessageMetrics() MessageMetrics {
+	return p.metrics.snapshot()
+}
+
+// logMessageMetrics logs the message metrics, if any messages have been processed.
+func (p *Poller) logMessageMetrics() {
+	metrics := p.MessageMetrics()
+	if len(metrics.Outcomes) == 0 {
+		return
+	}
+	slog.InfoContext(p.ctx, "message metrics", "metrics", metrics)
+}
+
+// logMessageMetricsPeriodically logs the message metrics on every tick until shutdown starts. The totals are logged
+// once more when shutdown completes.
+func (p *Poller) logMessageMetricsPeriodically(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.scaleCtx.Done():
+			return
+		case <-ticker.C():
+			p.logMessageMetrics()
+		}
+	}
+}
+
+// messageResult collects the labels of a message's metrics as it is processed.
+type messageResult struct {
+	msgType messages.MessageType
+	outcome MessageOutcome
+}
@@ -0,0 +1,126 @@
+package poller
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/log"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+// unregisteredRequest marshals to a message type with no registered handler, so it fails to parse.
+type unregisteredRequest struct{}
+
+func (unregisteredRequest) Type() messages.MessageType {
+	return "NoSuchRequest"
+}
+
+func (unregisteredRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct{ Type string }{Type: "NoSuchRequest"})
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	h.observe(50 * time.Millisecond)
+	h.observe(100 * time.Millisecond)
+	h.observe(2 * time.Second)
+	h.observe(time.Hour)
+
+	require.Equal(t, []int64{2, 0, 0, 1, 0, 0, 0, 1}, h.Counts)
+	require.Equal(t, int64(4), h.Count)
+	require.Equal(t, time.Hour+2*time.Second+150*time.Millisecond, h.Sum)
+}
+
+func TestMessageMetrics(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.undecryptable = map[string]bool{"message-undecryptable": true}
+	fs.writeResponse = func(w http.ResponseWriter, messageID string) bool {
+		if messageID != "message-unwritable" {
+			return true
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"ResponseCode":400,"Message":"injected write failure","ErrorType":"BadRequest"}`))
+		return false
+	}
+
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueueWithID("message-ok", &messages.PingRequest{})
+	fs.enqueueWithID("message-undecryptable", &messages.PingRequest{})
+	fs.enqueueWithID("message-unparseable", unregisteredRequest{})
+	fs.enqueueWithID("message-unwritable", &messages.PingRequest{})
+	require.Equal(t, []string{"message-ok"}, fs.waitForResponses(1))
+
+	var metrics MessageMetrics
+	require.Eventually(t, func() bool {
+		metrics = p.MessageMetrics()
+		return metrics.Latency[messages.PingRequestMessage].Count+metrics.Latency[UnknownMessageType].Count == 4
+	}, 5*time.Second, time.Millisecond)
+
+	require.Equal(
+		t,
+		map[messages.MessageType]map[MessageOutcome]int64{
+			messages.PingRequestMessage: {OutcomeSuccess: 1, OutcomeWriteFailure: 1},
+			UnknownMessageType:          {OutcomeDecryptFailure: 1, OutcomeParseFailure: 1},
+		},
+		metrics.Outcomes,
+	)
+	require.Len(t, metrics.Latency[messages.PingRequestMessage].Counts, len(LatencyBuckets)+1)
+
+	// redelivered duplicates aren't recorded again, only counted.
+	fs.enqueueWithID("message-ok", &messages.PingRequest{})
+	require.Equal(t, []string{"message-ok"}, fs.waitForResponses(1))
+	require.Eventually(t, func() bool {
+		return p.MessageMetrics().Duplicates == 1
+	}, 5*time.Second, time.Millisecond)
+	require.Equal(t, int64(1), p.MessageMetrics().Outcomes[messages.PingRequestMessage][OutcomeSuccess])
+}
+
+func TestMessageMetricsLogValue(t *testing.T) {
+	m := newMessageMetrics()
+	m.record(messages.PingRequestMessage, OutcomeSuccess, time.Second)
+	m.record(messages.PingRequestMessage, OutcomeWriteFailure, 3*time.Second)
+	m.record(UnknownMessageType, OutcomeDecryptFailure, time.Millisecond)
+	m.recordDuplicate()
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("message metrics", "metrics", m.snapshot())
+	require.Contains(
+		t,
+		buf.String(),
+		"metrics.PingRequest.success=1 metrics.PingRequest.write_failure=1 metrics.PingRequest.count=2 "+
+			"metrics.PingRequest.mean_latency=2s metrics.Unknown.decrypt_failure=1 metrics.Unknown.count=1 "+
+			"metrics.Unknown.mean_latency=1ms metrics.duplicates=1",
+	)
+}
+
+func TestMessageMetricsLoggedPeriodically(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	clock := newFakeClock()
+	p := New(fs.client(), testTenantID, testRunnerID, WithClock(clock), WithMetricsLogInterval(time.Minute))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueue(&messages.PingRequest{})
+	fs.waitForResponses(1)
+	require.Eventually(t, func() bool {
+		return p.MessageMetrics().Outcomes[messages.PingRequestMessage][OutcomeSuccess] == 1
+	}, 5*time.Second, time.Millisecond)
+
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool {
+		return handler.find("message metrics", "tenantID", testTenantID) != nil
+	}, 5*time.Second, time.Millisecond)
+}
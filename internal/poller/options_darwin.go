@@ -1,8 +1,11 @@
 package poller
 
 import (
+	"context"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/plan42-ai/cli/internal/github"
 	"github.com/plan42-ai/cli/internal/p42runtime"
@@ -21,10 +24,19 @@ type PlatformFields struct {
 }
 
 type InvokePlatformFields struct {
-	ContainerPath string
-	PodmanPath    string
-	Provider      p42runtime.Provider
-	githubClient  *github.Client
+	ContainerPath    string
+	PodmanPath       string
+	Provider         p42runtime.Provider
+	githubClient     *github.Client
+	agentTimeout     time.Duration
+	keepContainers   bool
+	minFreeDiskBytes uint64
+	maxLogSize       int64
+	maxLogFiles      int
+	allowedImages    []string
+	defaultRegistry  string
+	foregroundLogs   io.Writer
+	startAgent       func(ctx context.Context, fn func(ctx context.Context))
 }
 
 func WithContainerPath(path string) Option {
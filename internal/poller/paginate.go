@@ -0,0 +1,100 @@
+package poller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/util"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+var (
+	errMaxResultInvalid       = errors.New("maxResults must be between 1 and 100")
+	errInvalidPaginationToken = errors.New("invalid pagination token")
+	errNextPaginationToken    = errors.New("unable to generate pagination token")
+)
+
+// ParsePagination parses MaxResults and Token into a key structure.
+// key should be a pointer to the pagination key struct.
+func ParsePagination[T any](maxResults *int, token *string, key *T) (int, error) {
+	limit := defaultPageSize
+	if maxResults != nil {
+		limit = *maxResults
+	}
+	if limit <= 0 || limit > maxPageSize {
+		return 0, errMaxResultInvalid
+	}
+	if token != nil {
+		b, err := base64.RawURLEncoding.DecodeString(*token)
+		if err != nil {
+			return 0, errInvalidPaginationToken
+		}
+		if err := json.Unmarshal(b, key); err != nil {
+			return 0, errInvalidPaginationToken
+		}
+	}
+	return limit, nil
+}
+
+func NextToken[T any](paginationKey *T) (*string, error) {
+	if paginationKey == nil {
+		return nil, nil
+	}
+	jsonBytes, err := json.Marshal(paginationKey)
+	if err != nil {
+		return nil, err
+	}
+	return util.Pointer(base64.RawURLEncoding.EncodeToString(jsonBytes)), nil
+}
+
+// pageFetcher fetches the page of at most limit items identified by key. It returns the key of the following page,
+// or nil if this is the last one.
+type pageFetcher[K any, T any] func(key K, limit int) ([]T, *K, error)
+
+// paginate serves one page of a list request. It decodes maxResults and token into a key of type K, starting at
+// first when there is no token, fetches that page and encodes the key of the following page as the opaque token
+// returned to the caller. Errors returned by paginate are suitable for the response's ErrorMessage.
+func paginate[K any, T any](
+	ctx context.Context,
+	maxResults *int,
+	token *string,
+	first K,
+	fetch pageFetcher[K, T],
+) ([]T, *string, error) {
+	var key K
+	if token == nil {
+		key = first
+	}
+	limit, err := ParsePagination(maxResults, token, &key)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to parse pagination key", "error", err)
+		return nil, nil, err
+	}
+	items, next, err := fetch(key, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	nextToken, err := NextToken(next)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to generate next pagination token", "error", err)
+		return nil, nil, errNextPaginationToken
+	}
+	return items, nextToken, nil
+}
+
+// nextPage returns the key of the page following a github REST response, built by newKey, or nil if resp is the last
+// page.
+func nextPage[K any](resp *ghapi.Response, newKey func(page int) K) *K {
+	if resp == nil || resp.NextPage == 0 {
+		return nil
+	}
+	return util.Pointer(newKey(resp.NextPage))
+}
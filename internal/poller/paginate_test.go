@@ -0,0 +1,141 @@
+package poller
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPageKey struct {
+	Page int
+}
+
+// pagedFetcher serves items in pages of at most limit items, recording the pages requested.
+type pagedFetcher struct {
+	items     []string
+	requested []int
+}
+
+func (f *pagedFetcher) fetch(key testPageKey, limit int) ([]string, *testPageKey, error) {
+	f.requested = append(f.requested, key.Page)
+	start := min((key.Page-1)*limit, len(f.items))
+	end := min(start+limit, len(f.items))
+	if end == len(f.items) {
+		return f.items[start:end], nil, nil
+	}
+	return f.items[start:end], &testPageKey{Page: key.Page + 1}, nil
+}
+
+// walkPages calls paginate until it returns no token, returning every item and the tokens it returned.
+func walkPages(t *testing.T, maxResults int, fetch pageFetcher[testPageKey, string]) ([]string, []string) {
+	t.Helper()
+	var items []string
+	var tokens []string
+	var token *string
+	for {
+		page, next, err := paginate(t.Context(), util.Pointer(maxResults), token, testPageKey{Page: 1}, fetch)
+		require.NoError(t, err)
+		items = append(items, page...)
+		if next == nil {
+			return items, tokens
+		}
+		tokens = append(tokens, *next)
+		token = next
+	}
+}
+
+func TestPaginateSinglePage(t *testing.T) {
+	f := &pagedFetcher{items: []string{"a", "b"}}
+	items, tokens := walkPages(t, 10, f.fetch)
+	require.Equal(t, []string{"a", "b"}, items)
+	require.Empty(t, tokens)
+	require.Equal(t, []int{1}, f.requested)
+}
+
+func TestPaginateMultiplePages(t *testing.T) {
+	f := &pagedFetcher{items: []string{"a", "b", "c", "d", "e"}}
+	items, tokens := walkPages(t, 2, f.fetch)
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, items)
+	require.Equal(t, []int{1, 2, 3}, f.requested)
+
+	// tokens stay in the wire format clients already hold: base64url encoded JSON of the key.
+	require.Equal(t, []string{
+		base64.RawURLEncoding.EncodeToString([]byte(`{"Page":2}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"Page":3}`)),
+	}, tokens)
+}
+
+func TestPaginateErrors(t *testing.T) {
+	f := &pagedFetcher{items: []string{"a"}}
+
+	_, _, err := paginate(t.Context(), util.Pointer(0), nil, testPageKey{Page: 1}, f.fetch)
+	require.ErrorIs(t, err, errMaxResultInvalid)
+
+	_, _, err = paginate(t.Context(), nil, util.Pointer("not-a-token!"), testPageKey{Page: 1}, f.fetch)
+	require.ErrorIs(t, err, errInvalidPaginationToken)
+	require.Empty(t, f.requested)
+
+	fetchErr := errors.New("fetch failed")
+	_, _, err = paginate(
+		t.Context(),
+		nil,
+		nil,
+		testPageKey{Page: 1},
+		func(testPageKey, int) ([]string, *testPageKey, error) { return nil, nil, fetchErr },
+	)
+	require.ErrorIs(t, err, fetchErr)
+}
+
+func TestListOrgsPagination(t *testing.T) {
+	const nPages = 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/user/orgs"):
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			assert.NoError(t, err)
+			if page < nPages {
+				w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, page+1))
+			}
+			_, _ = fmt.Fprintf(w, `[{"login": "org-%d"}]`, page)
+		case strings.HasSuffix(r.URL.Path, "/user"):
+			_, _ = w.Write([]byte(`{"login": "octocat"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	p := newGithubTestPoller(server)
+
+	list := func(maxResults int) []string {
+		var logins []string
+		var token *string
+		for {
+			req := &pollerListOrgsForGithubConnectionRequest{}
+			req.ConnectionID = testConnectionID
+			req.MaxResults = util.Pointer(maxResults)
+			req.Token = token
+			req.Init(p)
+			resp := req.Process(t.Context()).(*ListOrgsForGithubConnectionResponse)
+			require.Nil(t, resp.ErrorMessage)
+			logins = append(logins, resp.Items...)
+			token = resp.NextToken
+			if token == nil {
+				return logins
+			}
+		}
+	}
+
+	// the user's login is appended to the last page of orgs when it has room, and served on its own page otherwise.
+	require.Equal(t, []string{"org-1", "org-2", "octocat"}, list(2))
+	require.Equal(t, []string{"org-1", "org-2", "octocat"}, list(1))
+}
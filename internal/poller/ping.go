@@ -6,6 +6,10 @@ import (
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
 
+func init() {
+	registerHandler(messages.PingRequestMessage, func() pollerMessage { return &pollerPingRequest{} })
+}
+
 type pollerPingRequest struct {
 	messages.PingRequest
 }
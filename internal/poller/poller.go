@@ -8,8 +8,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"slices"
 	"sync"
 	"time"
@@ -25,7 +27,28 @@ import (
 	"github.com/plan42-ai/sdk-go/p42/messages"
 )
 
-const maxRetries = 5
+const (
+	maxRetries = 5
+
+	// InitialQueueCount is the number of queues a poller starts with, before autoscaling.
+	InitialQueueCount = 1
+
+	// defaultPollBackoffMin and defaultPollBackoffMax bound the wait between polls of an idle queue.
+	defaultPollBackoffMin = 1 * time.Millisecond
+	defaultPollBackoffMax = 50 * time.Millisecond
+
+	// defaultMaxConcurrentMessages is the default limit on messages processed concurrently across all queues.
+	defaultMaxConcurrentMessages = 64
+
+	// defaultMaxPayloadSize is the default limit, in bytes, on the size of a decrypted message or its response.
+	defaultMaxPayloadSize = 16 << 20
+
+	// gcmTagSize is the size of the AES-GCM tag appended to an encrypted payload.
+	gcmTagSize = 16
+
+	// notReadyWarnInterval is how often a warning is logged while the poller hasn't registered any queue.
+	notReadyWarnInterval = 30 * time.Second
+)
 
 type queueInfo struct {
 	queueID    string
@@ -35,33 +58,68 @@ type queueInfo struct {
 	draining   bool
 	skipDelete bool
 	privateKey *ecdsa.PrivateKey
+	createdAt  time.Time
+
+	// Backoffs aren't goroutine safe, so each queue's goroutine has its own.
+	queueManagementBackoff *concurrency.Backoff
+	batchBackoff           *concurrency.Backoff
 }
 
 type Option func(p *Poller)
 
 type Poller struct {
 	PlatformFields
-	cg                     *concurrency.ContextGroup
-	ctx                    context.Context
-	queues                 []*queueInfo
-	nExpectedQueueCount    int64
-	nActualQueueCount      int64
-	lastScaleEvent         time.Time
-	sumBatchPct            float64
-	nBatches               int64
-	measureStart           time.Time
-	scaleTicker            *time.Ticker
-	scaleCtx               context.Context
-	cancelScale            context.CancelFunc
-	mux                    sync.Mutex
-	client                 *p42.Client
-	tenantID               string
-	runnerID               string
-	queueManagementBackoff *concurrency.Backoff
-	batchBackoff           *concurrency.Backoff
-	connectionIdx          map[string]*config.GithubInfo
-	githubClients          map[string]*github.Client
-	githubClientMu         sync.Mutex
+	cg                      *concurrency.ContextGroup
+	ctx                     context.Context
+	queues                  []*queueInfo
+	nExpectedQueueCount     int64
+	nActualQueueCount       int64
+	lastScaleEvent          time.Time
+	sumBatchPct             float64
+	nBatches                int64
+	measureStart            time.Time
+	scaleTicker             Ticker
+	scaleCtx                context.Context
+	cancelScale             context.CancelFunc
+	mux                     sync.Mutex
+	client                  *p42.Client
+	tenantID                string
+	runnerID                string
+	connectionIdx           map[string]*config.GithubInfo
+	githubClients           map[string]*github.Client
+	githubClientMu          sync.Mutex
+	githubLimiters          map[string]*rateLimiter
+	githubRequestsPerSecond float64
+	githubBurst             int
+	maxConcurrentMessages   int
+	maxPayloadSize          int
+	messageSlots            chan struct{}
+	inFlight                sync.WaitGroup
+	agents                  sync.WaitGroup
+	processedCacheSize      int
+	processedCacheTTL       time.Duration
+	processed               *processedMessages
+	metrics                 *messageMetrics
+	metricsLogInterval      time.Duration
+	process                 func(ctx context.Context, msg pollerMessage) messages.Message
+	agentTimeout            time.Duration
+	keepContainers          bool
+	minFreeDiskBytes        uint64
+	maxLogSize              int64
+	maxLogFiles             int
+	allowedImages           []string
+	defaultRegistry         string
+	foregroundLogs          io.Writer
+	keyRotationInterval     time.Duration
+	generateKey             func() (*ecdsa.PrivateKey, error)
+	pollBackoffMin          time.Duration
+	pollBackoffMax          time.Duration
+	state                   *stateFile
+	clock                   Clock
+	once                    bool
+	onceDone                chan struct{}
+	ready                   chan struct{}
+	readyOnce               sync.Once
 }
 
 func (p *Poller) scale() {
@@ -73,41 +131,54 @@ func (p *Poller) scale() {
 		select {
 		case <-p.scaleCtx.Done():
 			return
-		case <-p.scaleTicker.C:
+		case <-p.scaleTicker.C():
 		}
 
 		p.doScale()
+		p.rotateKeys()
 	}
 }
 
+const (
+	scaleDecisionUp      = "up"
+	scaleDecisionDown    = "down"
+	scaleDecisionSteady  = "steady"
+	scaleDecisionWaiting = "waiting"
+)
+
 func (p *Poller) doScale() {
 	p.mux.Lock()
 	defer p.mux.Unlock()
-	now := time.Now()
+	now := p.clock.Now()
 
 	// We are still waiting for the last scale operation to complete, return.
 	if p.nExpectedQueueCount != p.nActualQueueCount {
+		p.logScaleDecision(now, scaleDecisionWaiting, "previous scale operation in progress")
 		return
 	}
 
 	// We don't have at least one minute of utilization data yet, return.
 	if now.Sub(p.measureStart) < time.Minute {
+		p.logScaleDecision(now, scaleDecisionWaiting, "not enough utilization data")
 		return
 	}
 
 	// If it's been less than one min since the last scale event, return.
 	if now.Sub(p.lastScaleEvent) < time.Minute {
+		p.logScaleDecision(now, scaleDecisionWaiting, "scale up cooldown")
 		return
 	}
 
 	// quick sanity check to avoid divide by 0.
 	if p.nBatches == 0 {
+		p.logScaleDecision(now, scaleDecisionWaiting, "no batches measured")
 		return
 	}
 
 	if p.sumBatchPct/float64(p.nBatches) >= 0.8 {
 		// It's been at least 1 min since the last scale operation
 		// and our average batch size is >= 80% full over at least 1 min. Double the number of queues.
+		p.logScaleDecision(now, scaleDecisionUp, "average batch at least 80% full")
 		p.scaleUp()
 		return
 	}
@@ -115,12 +186,14 @@ func (p *Poller) doScale() {
 	// We don't have at least 2 mins of measurement data, so we can't make any scale down decisions.
 	// return.
 	if now.Sub(p.measureStart) < time.Minute*2 {
+		p.logScaleDecision(now, scaleDecisionWaiting, "not enough utilization data to scale down")
 		return
 	}
 
 	// We can only scale down every 2 mins, so if it's been less than 2 mins since the last scale event,
 	// or we are still waiting on a scale down event, return.
 	if now.Sub(p.lastScaleEvent) < time.Minute*2 {
+		p.logScaleDecision(now, scaleDecisionWaiting, "scale down cooldown")
 		// reset our stats window
 		p.resetStats()
 		return
@@ -130,6 +203,7 @@ func (p *Poller) doScale() {
 		// It's been at least 2 mins since the last scale operation
 		// and our average batch size is <= 40% full over at least 2 mins.
 		// Decrease the number of queues by 1.
+		p.logScaleDecision(now, scaleDecisionDown, "average batch at most 40% full")
 		p.scaleDown()
 		return
 	}
@@ -137,19 +211,74 @@ func (p *Poller) doScale() {
 	// The average batch has been > 40% full and < 80% full for the last 2 mins.
 	// So, we are in a "good" steady state. No need to scale anything. Just
 	// reset our stat window.
+	p.logScaleDecision(now, scaleDecisionSteady, "average batch between 40% and 80% full")
 	p.resetStats()
 }
 
+// logScaleDecision logs the autoscaler's decision along with the stats it was based on. It must be called with p.mux
+// held, before the stats are reset.
+func (p *Poller) logScaleDecision(now time.Time, decision string, reason string) {
+	var avgFillRatio float64
+	if p.nBatches > 0 {
+		avgFillRatio = p.sumBatchPct / float64(p.nBatches)
+	}
+	slog.DebugContext(
+		p.ctx,
+		"scale decision",
+		"decision", decision,
+		"reason", reason,
+		"avgFillRatio", avgFillRatio,
+		"batches", p.nBatches,
+		"sinceLastScale", now.Sub(p.lastScaleEvent),
+		"queues", len(p.queues),
+	)
+}
+
+// rotateKeys replaces queues older than keyRotationInterval with new queues, which have new IDs and keys. The old
+// queue drains rather than stopping immediately, so messages already encrypted to its key are still processed.
+func (p *Poller) rotateKeys() {
+	if p.keyRotationInterval <= 0 {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	// don't rotate during shutdown.
+	if p.nExpectedQueueCount == 0 {
+		return
+	}
+
+	for i, qi := range p.queues {
+		if p.clock.Now().Sub(qi.createdAt) < p.keyRotationInterval {
+			continue
+		}
+		replacement := p.createQueueInfo(p.cg.Context())
+		if replacement == nil {
+			continue
+		}
+		p.queues[i] = replacement
+		p.cg.Add(1)
+		go p.runQueue(replacement)
+		p.signalDrain(qi)
+		slog.InfoContext(qi.ctx, "rotated queue key", "oldQueue", qi.queueID, "newQueue", replacement.queueID)
+	}
+}
+
 func (p *Poller) resetStats() {
-	p.measureStart = time.Now()
+	p.measureStart = p.clock.Now()
 	p.nBatches = 0
 	p.sumBatchPct = 0.0
 }
 
-func createQueueInfo(ctx context.Context) *queueInfo {
-	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+func generateQueueKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func (p *Poller) createQueueInfo(ctx context.Context) *queueInfo {
+	key, err := p.generateKey()
 	if err != nil {
-		slog.ErrorContext(ctx, "ecdss.GenerateKey failed", "error", err)
+		slog.ErrorContext(ctx, "unable to generate queue key", "error", err)
 		return nil
 	}
 	qi := &queueInfo{
@@ -158,6 +287,10 @@ func createQueueInfo(ctx context.Context) *queueInfo {
 		cancel:     nil,
 		drain:      make(chan struct{}),
 		privateKey: key,
+		createdAt:  p.clock.Now(),
+
+		queueManagementBackoff: concurrency.NewBackoff(10*time.Millisecond, 5*time.Second),
+		batchBackoff:           concurrency.NewBackoff(p.pollBackoffMin, p.pollBackoffMax),
 	}
 	qi.ctx, qi.cancel = context.WithCancel(ctx)
 	qi.ctx = log.WithContextAttrs(qi.ctx, slog.String("queueID", qi.queueID))
@@ -169,25 +302,25 @@ func (p *Poller) scaleUp() {
 
 	nToAdd := len(p.queues)
 	for i := 0; i < nToAdd; i++ {
-		qi := createQueueInfo(p.cg.Context())
+		qi := p.createQueueInfo(p.cg.Context())
 		if qi == nil {
 			continue
 		}
 		p.nExpectedQueueCount++
 		p.queues = append(p.queues, qi)
 		p.cg.Add(1)
-		go p.poll(qi)
+		go p.runQueue(qi)
 	}
 
 	if p.nExpectedQueueCount == p.nActualQueueCount {
-		p.lastScaleEvent = time.Now()
+		p.lastScaleEvent = p.clock.Now()
 	}
 }
 
 func (p *Poller) scaleDown() {
 	p.resetStats()
 	if len(p.queues) == 1 {
-		p.lastScaleEvent = time.Now()
+		p.lastScaleEvent = p.clock.Now()
 		return
 	}
 	p.nExpectedQueueCount--
@@ -208,8 +341,80 @@ func (p *Poller) drainAll() {
 	p.queues = nil
 }
 
-func (p *Poller) poll(qi *queueInfo) {
+// runQueue polls qi until it stops. A queue that stops unexpectedly, rather than because it was drained or the poller
+// is shutting down, is replaced with a new queue after a backoff, so a failed queue doesn't permanently reduce the
+// number of queues being polled.
+func (p *Poller) runQueue(qi *queueInfo) {
 	defer p.cg.Done()
+
+	backoff := concurrency.NewBackoff(10*time.Millisecond, 5*time.Second)
+	defer backoff.StopTimer()
+	for qi != nil {
+		started := p.clock.Now()
+		p.poll(qi)
+		if !p.queueFailed(qi) {
+			return
+		}
+
+		// a queue that was healthy for a while before failing doesn't count towards the backoff.
+		if p.clock.Now().Sub(started) >= time.Minute {
+			backoff.Recover()
+		}
+		backoff.Backoff()
+		slog.WarnContext(qi.ctx, "queue stopped unexpectedly; replacing it", "queue", qi.queueID)
+		qi = p.replaceQueue(qi, backoff)
+	}
+}
+
+// queueFailed reports whether qi stopped for a reason other than being drained or the poller shutting down.
+func (p *Poller) queueFailed(qi *queueInfo) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return !qi.draining && !p.once && p.nExpectedQueueCount != 0 && p.ctx.Err() == nil
+}
+
+// replaceQueue waits for backoff and then swaps a new queue in for the failed queue old. It returns nil, without
+// replacing old, if the poller starts shutting down or old is no longer one of the poller's queues.
+func (p *Poller) replaceQueue(old *queueInfo, backoff *concurrency.Backoff) *queueInfo {
+	for {
+		err := backoff.WaitContext(p.ctx)
+		if err != nil {
+			return nil
+		}
+
+		replacement := p.createQueueInfo(p.cg.Context())
+		if replacement == nil {
+			backoff.Backoff()
+			continue
+		}
+
+		if !p.swapQueue(old, replacement) {
+			replacement.cancel()
+			slog.InfoContext(old.ctx, "failed queue no longer needed; skipping replacement", "queue", old.queueID)
+			return nil
+		}
+		slog.InfoContext(old.ctx, "replaced failed queue", "oldQueue", old.queueID, "newQueue", replacement.queueID)
+		return replacement
+	}
+}
+
+// swapQueue replaces old with replacement in the poller's queues. It returns false if old is no longer one of the
+// poller's queues, e.g. because it was scaled down while it was failing, or the poller is shutting down.
+func (p *Poller) swapQueue(old *queueInfo, replacement *queueInfo) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	idx := slices.Index(p.queues, old)
+	if idx == -1 || p.nExpectedQueueCount == 0 {
+		return false
+	}
+	p.queues[idx] = replacement
+	return true
+}
+
+func (p *Poller) poll(qi *queueInfo) {
+	// deferred first so it runs last, after the queue has been cleaned up. runQueue then replaces the queue.
+	defer recoverPanic(qi.ctx, "queue")
 	defer qi.cancel()
 
 	err := p.createQueue(qi)
@@ -234,16 +439,22 @@ loop:
 			break loop
 		default:
 		}
-		_, stop := p.doPoll(qi, &req)
+		n, stop := p.doPoll(qi, &req)
 		if stop {
 			return
 		}
+		if p.once && n > 0 {
+			p.inFlight.Wait()
+			// invoke requests return before their agents finish, so wait for those too before the queue is deleted.
+			p.agents.Wait()
+			return
+		}
 	}
 
 	p.markAsDraining(qi)
 	p.signalDrain(qi)
 
-	startDrain := time.Now()
+	startDrain := p.clock.Now()
 	for {
 		select {
 		case <-qi.ctx.Done():
@@ -254,14 +465,14 @@ loop:
 		if stop {
 			return
 		}
-		if n == 0 && time.Since(startDrain) >= 30*time.Second {
+		if n == 0 && p.clock.Now().Sub(startDrain) >= 30*time.Second {
 			return
 		}
 	}
 }
 
 func (p *Poller) doPoll(qi *queueInfo, req *p42.GetMessagesBatchRequest) (n int, stop bool) {
-	err := p.batchBackoff.WaitContext(qi.ctx)
+	err := qi.batchBackoff.WaitContext(qi.ctx)
 	if err != nil {
 		stop = true
 		return
@@ -276,31 +487,52 @@ func (p *Poller) doPoll(qi *queueInfo, req *p42.GetMessagesBatchRequest) (n int,
 			return
 		}
 		slog.ErrorContext(p.ctx, "unable to get messages batch", "error", err)
-		p.batchBackoff.Backoff()
+		qi.batchBackoff.Backoff()
 		return
 	}
 
 	if len(batch.Messages) == 0 {
-		p.batchBackoff.Backoff()
+		qi.batchBackoff.Backoff()
 	} else {
-		p.batchBackoff.Recover()
+		qi.batchBackoff.Recover()
 	}
 
 	p.addStats(float64(len(batch.Messages)) / 10.0)
-	for _, msg := range batch.Messages {
+	n = len(batch.Messages)
+	for i, msg := range batch.Messages {
+		if !p.acquireMessageSlot(qi.ctx) {
+			slog.WarnContext(qi.ctx, "queue stopped before the batch was processed; skipping remaining messages", "skipped", n-i)
+			stop = true
+			return
+		}
 		p.cg.Add(1)
+		p.inFlight.Add(1)
 		go p.processMessage(msg, qi)
 	}
-	n = len(batch.Messages)
 	return
 }
 
+// acquireMessageSlot blocks until fewer than maxConcurrentMessages messages are being processed, or ctx is done.
+// It returns false if ctx is done before a slot frees up.
+func (p *Poller) acquireMessageSlot(ctx context.Context) bool {
+	select {
+	case p.messageSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (p *Poller) releaseMessageSlot() {
+	<-p.messageSlots
+}
+
 func (p *Poller) decreaseActualQueueCount() {
 	p.mux.Lock()
 	defer p.mux.Unlock()
 	p.nActualQueueCount--
 	if p.nActualQueueCount == p.nExpectedQueueCount {
-		p.lastScaleEvent = time.Now()
+		p.lastScaleEvent = p.clock.Now()
 	}
 }
 
@@ -309,14 +541,21 @@ func (p *Poller) increaseActualQueueCount() {
 	defer p.mux.Unlock()
 	p.nActualQueueCount++
 	if p.nActualQueueCount == p.nExpectedQueueCount {
-		p.lastScaleEvent = time.Now()
+		p.lastScaleEvent = p.clock.Now()
 	}
 }
 
 func (p *Poller) createQueue(qi *queueInfo) error {
 	defer p.increaseActualQueueCount()
 
-	for {
+	// serializing the key fails the same way every time, so there's no point retrying it.
+	pubPem, err := ecies.PubKeyToPem(&qi.privateKey.PublicKey)
+	if err != nil {
+		slog.ErrorContext(qi.ctx, "unable to serialize queue public key", "error", err)
+		return fmt.Errorf("unable to serialize queue public key: %w", err)
+	}
+
+	for i := 0; i < maxRetries; i++ {
 		select {
 		case <-qi.ctx.Done():
 			return qi.ctx.Err()
@@ -325,17 +564,11 @@ func (p *Poller) createQueue(qi *queueInfo) error {
 		default:
 		}
 
-		err := p.queueManagementBackoff.WaitContext(qi.ctx)
+		err = qi.queueManagementBackoff.WaitContext(qi.ctx)
 		if err != nil {
 			return err
 		}
 
-		pubPem, err := ecies.PubKeyToPem(&qi.privateKey.PublicKey)
-
-		if err != nil {
-			panic(err)
-		}
-
 		_, err = p.client.RegisterRunnerQueue(
 			qi.ctx,
 			&p42.RegisterRunnerQueueRequest{
@@ -349,18 +582,22 @@ func (p *Poller) createQueue(qi *queueInfo) error {
 		var conflictErr *p42.ConflictError
 		if errors.As(err, &conflictErr) {
 			// if we get a conflict error, the queue already exists. return
+			p.markReady()
 			return nil
 		}
 
 		if err != nil {
-			p.queueManagementBackoff.Backoff()
+			qi.queueManagementBackoff.Backoff()
 			slog.ErrorContext(p.ctx, "RegisterRunnerQueue failed", "error", err)
 			continue
 		}
 		slog.InfoContext(qi.ctx, "successfully created queue")
-		p.queueManagementBackoff.Recover()
+		qi.queueManagementBackoff.Recover()
+		p.markReady()
 		return nil
 	}
+	slog.ErrorContext(qi.ctx, "Unable to create queue: exhausted retries", "error", err)
+	return fmt.Errorf("unable to create queue: %w", err)
 }
 
 func (p *Poller) addStats(pct float64) {
@@ -370,67 +607,45 @@ func (p *Poller) addStats(pct float64) {
 	p.nBatches++
 }
 
+// handleQueueNotFound stops polling a queue that was removed on the server. Unless the queue was draining, runQueue
+// replaces it.
 func (p *Poller) handleQueueNotFound(qi *queueInfo) {
 	qi.skipDelete = true
-
-	p.mux.Lock()
-	defer p.mux.Unlock()
-
-	if qi.draining || qi.ctx.Err() != nil || p.nExpectedQueueCount == 0 {
-		slog.InfoContext(qi.ctx, "queue removed during shutdown; skipping replacement", "queue", qi.queueID)
-		return
-	}
-
-	idx := slices.Index(p.queues, qi)
-
-	if idx == -1 {
-		slog.WarnContext(qi.ctx, "unable to replace missing queue", "queue", qi.queueID)
-		return
-	}
-
-	p.nExpectedQueueCount--
-	p.queues = append(p.queues[:idx], p.queues[idx+1:]...)
-
-	replacement := createQueueInfo(p.cg.Context())
-	if replacement == nil {
-		slog.ErrorContext(qi.ctx, "unable to create replacement queue")
-		return
-	}
-
-	p.nExpectedQueueCount++
-	p.queues = append(p.queues, replacement)
-	p.cg.Add(1)
-	go p.poll(replacement)
-	slog.InfoContext(qi.ctx, "replaced missing queue", "oldQueue", qi.queueID, "newQueue", replacement.queueID)
+	slog.WarnContext(qi.ctx, "queue not found on server", "queue", qi.queueID)
 }
 
 func (p *Poller) processMessage(msg *p42.RunnerMessage, qi *queueInfo) {
 	defer p.cg.Done()
+	defer p.inFlight.Done()
+	defer p.releaseMessageSlot()
 	ctx := log.WithContextAttrs(
 		qi.ctx,
 		slog.String("messageID", msg.MessageID),
 		slog.String("callerID", msg.CallerID),
 	)
+	defer recoverPanic(ctx, "message")
+
+	start := p.clock.Now()
 	callerPub, err := ecies.PemToPubKey(msg.CallerPublicKey)
 	if err != nil {
+		p.metrics.record(UnknownMessageType, OutcomeFailure, p.clock.Now().Sub(start))
 		slog.ErrorContext(ctx, "unable to parse caller public key", "error", err)
 		return
 	}
 
-	decrypted, err := ecies.Unwrap(msg.Payload.(*ecies.WrappedSecret), qi.privateKey)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to decrypt ECIES message", "error", err)
-		return
-	}
-	parsedMsg, err := p.parseMessage(decrypted)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to parse message", "error", err)
-		return
+	var respJSON []byte
+	result := &messageResult{msgType: UnknownMessageType, outcome: OutcomeFailure}
+	entry, first := p.processed.begin(msg.MessageID)
+	if first {
+		// deferred so that a message that panics is recorded too.
+		defer func() { p.metrics.record(result.msgType, result.outcome, p.clock.Now().Sub(start)) }()
+		respJSON = p.handleFirstDelivery(ctx, msg, qi, entry, result)
+	} else {
+		p.metrics.recordDuplicate()
+		slog.InfoContext(ctx, "skipping duplicate message")
+		respJSON = entry.wait(ctx)
 	}
-	resp := parsedMsg.Process(ctx)
-	respJSON, err := json.Marshal(resp)
-	if err != nil {
-		slog.ErrorContext(ctx, "unable to marshal response", "error", err)
+	if respJSON == nil {
 		return
 	}
 
@@ -440,8 +655,8 @@ func (p *Poller) processMessage(msg *p42.RunnerMessage, qi *queueInfo) {
 		return
 	}
 
-	err = p.client.WriteResponse(
-		qi.ctx,
+	err = p.writeResponse(
+		ctx,
 		&p42.WriteResponseRequest{
 			TenantID:  p.tenantID,
 			RunnerID:  p.runnerID,
@@ -452,9 +667,138 @@ func (p *Poller) processMessage(msg *p42.RunnerMessage, qi *queueInfo) {
 		},
 	)
 
+	if errors.Is(err, errCallerGone) {
+		result.outcome = OutcomeCallerGone
+		slog.InfoContext(ctx, "caller no longer waiting for response; dropping it", "error", err)
+		return
+	}
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to write response", "error", err)
+		result.outcome = OutcomeWriteFailure
+		slog.ErrorContext(ctx, "unable to write response", "messageID", msg.MessageID, "error", err)
+		return
+	}
+	result.outcome = OutcomeSuccess
+}
+
+// handleFirstDelivery handles the first delivery of msg and finishes entry with the response. The entry is finished
+// even if handling panics, so duplicates waiting on it aren't stuck.
+func (p *Poller) handleFirstDelivery(
+	ctx context.Context,
+	msg *p42.RunnerMessage,
+	qi *queueInfo,
+	entry *processedMessage,
+	result *messageResult,
+) (respJSON []byte) {
+	defer func() { p.processed.finish(entry, respJSON) }()
+	return p.handleMessage(ctx, msg, qi, result)
+}
+
+// recoverPanic recovers from a panic while processing a message or polling a queue, so one bad message doesn't take
+// down the runner. It must be called directly by defer.
+func recoverPanic(ctx context.Context, what string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	slog.ErrorContext(ctx, "recovered from panic in "+what, "panic", r, "stack", string(debug.Stack()))
+}
+
+// handleMessage decrypts, parses, and processes msg, returning the marshaled response, or nil if processing failed.
+// The message type and the reason processing failed, if it's one tracked by the metrics, are set on result.
+func (p *Poller) handleMessage(ctx context.Context, msg *p42.RunnerMessage, qi *queueInfo, result *messageResult) []byte {
+	wrapped := msg.Payload.(*ecies.WrappedSecret)
+	// The decrypted payload is the size of the encrypted data less the GCM tag, so oversized messages can be rejected
+	// without decrypting them.
+	if size := len(wrapped.EncryptedData) - gcmTagSize; size > p.maxPayloadSize {
+		slog.ErrorContext(ctx, "skipping message: payload too large", "size", size, "maxSize", p.maxPayloadSize)
+		return nil
+	}
+	decrypted, err := ecies.Unwrap(wrapped, qi.privateKey)
+	if err != nil {
+		result.outcome = OutcomeDecryptFailure
+		slog.ErrorContext(ctx, "unable to decrypt ECIES message", "error", err)
+		return nil
+	}
+	if len(decrypted) > p.maxPayloadSize {
+		slog.ErrorContext(ctx, "skipping message: payload too large", "size", len(decrypted), "maxSize", p.maxPayloadSize)
+		return nil
+	}
+	parsedMsg, err := p.parseMessage(decrypted)
+	if err != nil {
+		result.outcome = OutcomeParseFailure
+		slog.ErrorContext(ctx, "unable to parse message", "error", err)
+		return nil
+	}
+	result.msgType = parsedMsg.Type()
+	resp := p.process(ctx, parsedMsg)
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to marshal response", "error", err)
+		return nil
+	}
+	if len(respJSON) > p.maxPayloadSize {
+		slog.ErrorContext(ctx, "unable to send response: payload too large", "size", len(respJSON), "maxSize", p.maxPayloadSize)
+		return nil
+	}
+	return respJSON
+}
+
+func processPollerMessage(ctx context.Context, msg pollerMessage) messages.Message {
+	return msg.Process(ctx)
+}
+
+// errCallerGone is returned by writeResponse when the caller stopped waiting for the response, e.g. because it
+// disconnected while the message was processed.
+var errCallerGone = errors.New("caller no longer exists")
+
+// writeResponse calls WriteResponse, retrying with backoff on network errors and 5xx responses.
+// 4xx responses are permanent and are returned immediately.
+func (p *Poller) writeResponse(ctx context.Context, req *p42.WriteResponseRequest) error {
+	backoff := concurrency.NewBackoff(10*time.Millisecond, 2*time.Second)
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		waitErr := backoff.WaitContext(ctx)
+		if waitErr != nil {
+			return errors.Join(err, waitErr)
+		}
+
+		err = p.client.WriteResponse(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if isCallerGone(err) {
+			return fmt.Errorf("%w: %w", errCallerGone, err)
+		}
+		if !isRetryable(ctx, err) {
+			return err
+		}
+		slog.WarnContext(ctx, "WriteResponse failed, retrying", "attempt", i+1, "error", err)
+		backoff.Backoff()
 	}
+	return fmt.Errorf("exhausted retries: %w", err)
+}
+
+// isCallerGone reports whether err from WriteResponse means the caller no longer exists: a 404 or 410 response.
+func isCallerGone(err error) bool {
+	var httpErr p42.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	return httpErr.Code() == http.StatusNotFound || httpErr.Code() == http.StatusGone
+}
+
+// isRetryable reports whether err from a p42 API call is transient: a network error, a 5xx or a 429 response.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr p42.HTTPError
+	if errors.As(err, &httpErr) {
+		code := httpErr.Code()
+		// a zero code means the error body could not be decoded, so we don't know if it's permanent.
+		return code == 0 || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	return true
 }
 
 func (p *Poller) parseMessage(data []byte) (pollerMessage, error) {
@@ -467,21 +811,9 @@ func (p *Poller) parseMessage(data []byte) (pollerMessage, error) {
 		return nil, err
 	}
 
-	var target pollerMessage
-
-	switch tmp.Type {
-	case messages.PingRequestMessage:
-		target = &pollerPingRequest{}
-	case messages.InvokeAgentRequestMessage:
-		target = &pollerInvokeAgentRequest{}
-	case messages.ListOrgsForGithubConnectionRequestMessage:
-		target = &pollerListOrgsForGithubConnectionRequest{}
-	case messages.SearchRepoRequestMessage:
-		target = &pollerSearchRepoRequest{}
-	case messages.ListRepoBranchesRequestMessage:
-		target = &pollerListRepoBranchesRequest{}
-	default:
-		return nil, fmt.Errorf("unknown message type: %v", tmp.Type)
+	target, err := newMessage(tmp.Type)
+	if err != nil {
+		return nil, err
 	}
 
 	err = json.Unmarshal(data, target)
@@ -495,7 +827,11 @@ func (p *Poller) parseMessage(data []byte) (pollerMessage, error) {
 func (p *Poller) ShutdownContext(ctx context.Context) error {
 	p.drainAll()
 	p.cancelScale()
-	return p.cg.WaitContext(ctx)
+	err := p.cg.WaitContext(ctx)
+	if p.metricsLogInterval > 0 {
+		p.logMessageMetrics()
+	}
+	return err
 }
 
 func (p *Poller) ShutdownTimeout(timeout time.Duration) error {
@@ -520,9 +856,10 @@ func (p *Poller) deleteQueue(qi *queueInfo) {
 	var err error
 
 	for i := 0; i < maxRetries; i++ {
-		err = p.queueManagementBackoff.WaitContext(qi.ctx)
+		err = qi.queueManagementBackoff.WaitContext(qi.ctx)
 		if err != nil {
 			slog.ErrorContext(qi.ctx, "Unable to delete queue: backoff wait failed", "error", err)
+			p.recordOrphanedQueue(qi)
 			return
 		}
 
@@ -538,7 +875,7 @@ func (p *Poller) deleteQueue(qi *queueInfo) {
 
 			if err != nil {
 				slog.ErrorContext(qi.ctx, "Unable to delete queue: GetRunnerQueue failed", "error", err)
-				p.queueManagementBackoff.Backoff()
+				qi.queueManagementBackoff.Backoff()
 				continue
 			}
 		}
@@ -560,14 +897,93 @@ func (p *Poller) deleteQueue(qi *queueInfo) {
 
 		if err != nil {
 			slog.ErrorContext(qi.ctx, "Unable to delete queue: DeleteRunnerQueue failed", "error", err)
-			p.queueManagementBackoff.Backoff()
+			qi.queueManagementBackoff.Backoff()
 			continue
 		}
 		slog.InfoContext(qi.ctx, "Deleted queue")
-		p.queueManagementBackoff.Recover()
+		qi.queueManagementBackoff.Recover()
 		return
 	}
 	slog.ErrorContext(qi.ctx, "Unable to delete queue: exhausted retries", "error", err)
+	p.recordOrphanedQueue(qi)
+}
+
+// recordOrphanedQueue records a queue that couldn't be deleted in the state file, so it can be cleaned up on the
+// next startup instead of leaking on the server.
+func (p *Poller) recordOrphanedQueue(qi *queueInfo) {
+	if !p.state.enabled() {
+		return
+	}
+	err := p.state.addOrphanedQueue(qi.queueID)
+	if err != nil {
+		slog.ErrorContext(qi.ctx, "unable to record orphaned queue", "error", err)
+		return
+	}
+	slog.WarnContext(qi.ctx, "recorded orphaned queue for cleanup on next startup")
+}
+
+// cleanupOrphanedQueues deletes queues recorded as orphaned by a previous run. Queues that are no longer listed for
+// this runner are assumed to be gone. Queues that still can't be deleted stay recorded for the next startup.
+func (p *Poller) cleanupOrphanedQueues() {
+	defer p.cg.Done()
+
+	orphaned, err := p.state.orphanedQueues()
+	if err != nil {
+		slog.ErrorContext(p.ctx, "unable to load orphaned queues", "error", err)
+		return
+	}
+	if len(orphaned) == 0 {
+		return
+	}
+
+	existing := make(map[string]*p42.RunnerQueue)
+	req := &p42.ListRunnerQueuesRequest{
+		TenantID:       &p.tenantID,
+		RunnerID:       &p.runnerID,
+		IncludeHealthy: util.Pointer(true),
+		IncludeDrained: util.Pointer(true),
+	}
+	for {
+		resp, err := p.client.ListRunnerQueues(p.ctx, req)
+		if err != nil {
+			slog.ErrorContext(p.ctx, "unable to clean up orphaned queues: ListRunnerQueues failed", "error", err)
+			return
+		}
+		for _, queue := range resp.Items {
+			existing[queue.QueueID] = queue
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		req.Token = resp.NextToken
+	}
+
+	var cleaned []string
+	for _, queueID := range orphaned {
+		queue, ok := existing[queueID]
+		if ok {
+			err = p.client.DeleteRunnerQueue(
+				p.ctx,
+				&p42.DeleteRunnerQueueRequest{
+					TenantID: p.tenantID,
+					RunnerID: p.runnerID,
+					QueueID:  queueID,
+					Version:  queue.Version,
+				},
+			)
+			if err != nil {
+				slog.ErrorContext(p.ctx, "unable to delete orphaned queue", "queueID", queueID, "error", err)
+				continue
+			}
+			slog.InfoContext(p.ctx, "deleted orphaned queue", "queueID", queueID)
+		}
+		cleaned = append(cleaned, queueID)
+	}
+
+	err = p.state.removeOrphanedQueues(cleaned)
+	if err != nil {
+		slog.ErrorContext(p.ctx, "unable to update state file", "error", err)
+	}
 }
 
 func (p *Poller) markAsDraining(qi *queueInfo) {
@@ -575,7 +991,7 @@ func (p *Poller) markAsDraining(qi *queueInfo) {
 	var err error
 
 	for i := 0; i < maxRetries; i++ {
-		err = p.queueManagementBackoff.WaitContext(qi.ctx)
+		err = qi.queueManagementBackoff.WaitContext(qi.ctx)
 		if err != nil {
 			slog.ErrorContext(qi.ctx, "Unable to mark queue as draining: backoff wait failed", "error", err)
 			return
@@ -593,7 +1009,7 @@ func (p *Poller) markAsDraining(qi *queueInfo) {
 
 			if err != nil {
 				slog.ErrorContext(qi.ctx, "Unable to mark queue as draining: GetRunnerQueue failed", "error", err)
-				p.queueManagementBackoff.Backoff()
+				qi.queueManagementBackoff.Backoff()
 				continue
 			}
 		}
@@ -617,10 +1033,10 @@ func (p *Poller) markAsDraining(qi *queueInfo) {
 
 		if err != nil {
 			slog.ErrorContext(qi.ctx, "Unable to mark queue as draining: UpdateRunnerQueue failed", "error", err)
-			p.queueManagementBackoff.Backoff()
+			qi.queueManagementBackoff.Backoff()
 			continue
 		}
-		p.queueManagementBackoff.Recover()
+		qi.queueManagementBackoff.Recover()
 		slog.InfoContext(qi.ctx, "Marked queue as draining", "queue", qi.queueID)
 		return
 	}
@@ -641,50 +1057,312 @@ func New(client *p42.Client, tenantID string, runnerID string, options ...Option
 		slog.String("tenantID", tenantID),
 		slog.String("runnerID", runnerID),
 	)
-	qi := createQueueInfo(ctx)
-	if qi == nil {
-		panic("failed to create queue info")
-	}
-
-	scaleTicker := time.NewTicker(1 * time.Second)
 	scaleCtx, cancelScale := context.WithCancel(ctx)
 
 	ret := &Poller{
-		cg:  cg,
-		ctx: ctx,
-		queues: []*queueInfo{
-			qi,
-		},
-		nExpectedQueueCount:    1,
-		nActualQueueCount:      0,
-		sumBatchPct:            0,
-		nBatches:               0,
-		measureStart:           time.Now(),
-		scaleTicker:            scaleTicker,
-		scaleCtx:               scaleCtx,
-		cancelScale:            cancelScale,
-		client:                 client,
-		tenantID:               tenantID,
-		runnerID:               runnerID,
-		queueManagementBackoff: concurrency.NewBackoff(10*time.Millisecond, 5*time.Second),
-		batchBackoff:           concurrency.NewBackoff(1*time.Millisecond, 50*time.Millisecond),
-		githubClients:          make(map[string]*github.Client),
+		cg:                      cg,
+		ctx:                     ctx,
+		nExpectedQueueCount:     InitialQueueCount,
+		nActualQueueCount:       0,
+		sumBatchPct:             0,
+		nBatches:                0,
+		scaleCtx:                scaleCtx,
+		cancelScale:             cancelScale,
+		client:                  client,
+		tenantID:                tenantID,
+		runnerID:                runnerID,
+		githubClients:           make(map[string]*github.Client),
+		maxConcurrentMessages:   defaultMaxConcurrentMessages,
+		maxPayloadSize:          defaultMaxPayloadSize,
+		processedCacheSize:      defaultProcessedMessageCacheSize,
+		processedCacheTTL:       defaultProcessedMessageCacheTTL,
+		process:                 processPollerMessage,
+		generateKey:             generateQueueKey,
+		pollBackoffMin:          defaultPollBackoffMin,
+		pollBackoffMax:          defaultPollBackoffMax,
+		githubRequestsPerSecond: DefaultGithubRequestsPerSecond,
+		githubBurst:             DefaultGithubBurst,
+		clock:                   realClock{},
+		metrics:                 newMessageMetrics(),
+		metricsLogInterval:      defaultMetricsLogInterval,
 	}
 	for _, opt := range options {
 		opt(ret)
 	}
-	ret.cg.Add(2)
-	go ret.scale()
-	go ret.poll(qi)
+	ret.measureStart = ret.clock.Now()
+	ret.messageSlots = make(chan struct{}, ret.maxConcurrentMessages)
+	ret.processed = newProcessedMessages(ret.processedCacheSize, ret.processedCacheTTL)
+	ret.onceDone = make(chan struct{})
+	ret.ready = make(chan struct{})
+	go ret.warnUntilReady(ret.clock.NewTicker(notReadyWarnInterval))
+	if ret.metricsLogInterval > 0 {
+		go ret.logMessageMetricsPeriodically(ret.clock.NewTicker(ret.metricsLogInterval))
+	}
+	// With once, a single queue is polled for a single batch, so there is nothing to scale.
+	if !ret.once {
+		ret.scaleTicker = ret.clock.NewTicker(1 * time.Second)
+		ret.cg.Add(1)
+		go ret.scale()
+	}
+	ret.cg.Add(1)
+	go ret.startInitialQueue()
+	if ret.state.enabled() {
+		ret.cg.Add(1)
+		go ret.cleanupOrphanedQueues()
+	}
 	return ret
 }
 
+// startInitialQueue creates the first queue and polls it, retrying with backoff if the queue's key can't be
+// generated.
+func (p *Poller) startInitialQueue() {
+	if p.once {
+		defer close(p.onceDone)
+	}
+	backoff := concurrency.NewBackoff(10*time.Millisecond, 5*time.Second)
+	for {
+		err := backoff.WaitContext(p.ctx)
+		if err != nil {
+			p.cg.Done()
+			return
+		}
+
+		qi := p.createQueueInfo(p.ctx)
+		if qi == nil {
+			backoff.Backoff()
+			continue
+		}
+
+		p.mux.Lock()
+		// Shutdown started before the queue was created.
+		if p.nExpectedQueueCount == 0 {
+			p.mux.Unlock()
+			qi.cancel()
+			p.cg.Done()
+			return
+		}
+		p.queues = append(p.queues, qi)
+		p.mux.Unlock()
+
+		p.runQueue(qi)
+		return
+	}
+}
+
+// WithOnce makes the poller process a single batch of messages on a single queue and then stop, rather than polling
+// until it is shut down. The autoscaler is not started. Use Done to wait for the batch to be processed, including any
+// agents it started.
+func WithOnce() Option {
+	return func(p *Poller) {
+		p.once = true
+	}
+}
+
+// startAgent runs fn in the background with a context that keeps ctx's values but not its cancellation, so an agent
+// outlives the queue that delivered its invoke request, e.g. when the queue is replaced on key rotation. The agent's
+// context is cancelled when the poller is closed. A poller created with WithOnce waits for its agents to finish.
+func (p *Poller) startAgent(ctx context.Context, fn func(ctx context.Context)) {
+	agentCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(p.ctx, cancel)
+	p.agents.Add(1)
+	go func() {
+		defer p.agents.Done()
+		defer cancel()
+		defer stop()
+		fn(agentCtx)
+	}()
+}
+
+// Done returns a channel that is closed once the batch processed by a poller created with WithOnce has been handled,
+// the agents it started have finished, and its queue deleted. It is never closed for other pollers.
+func (p *Poller) Done() <-chan struct{} {
+	return p.onceDone
+}
+
+// Ready returns a channel that's closed once the poller has registered a queue with the server, and so can receive
+// messages. It stays closed if the queue is later replaced or removed.
+func (p *Poller) Ready() <-chan struct{} {
+	return p.ready
+}
+
+func (p *Poller) markReady() {
+	p.readyOnce.Do(func() {
+		slog.InfoContext(p.ctx, "runner ready")
+		close(p.ready)
+	})
+}
+
+// warnUntilReady logs a warning every notReadyWarnInterval until the poller is ready, so a runner that can't reach
+// the server doesn't look like an idle one. It stops early when shutdown starts.
+func (p *Poller) warnUntilReady(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ready:
+			return
+		case <-p.scaleCtx.Done():
+			return
+		case <-ticker.C():
+			slog.WarnContext(p.ctx, "runner not yet connected; no queue has been registered with the server")
+		}
+	}
+}
+
 func WithConnectionIdx(idx map[string]*config.GithubInfo) Option {
 	return func(p *Poller) {
 		p.connectionIdx = idx
 	}
 }
 
+// WithMaxConcurrentMessages bounds the number of messages processed concurrently across all queues. When the limit
+// is reached, polling blocks until a message finishes processing. Values < 1 are ignored.
+func WithMaxConcurrentMessages(n int) Option {
+	return func(p *Poller) {
+		if n < 1 {
+			return
+		}
+		p.maxConcurrentMessages = n
+	}
+}
+
+// WithAgentTimeout limits how long an agent container may run before it is killed. Values <= 0 mean no limit.
+func WithAgentTimeout(timeout time.Duration) Option {
+	return func(p *Poller) {
+		p.agentTimeout = timeout
+	}
+}
+
+// WithKeepContainers keeps agent containers after they exit, rather than removing them, so a failed agent can be
+// inspected. Kept containers must be removed manually.
+func WithKeepContainers(keep bool) Option {
+	return func(p *Poller) {
+		p.keepContainers = keep
+	}
+}
+
+// WithMinFreeDiskBytes makes agent jobs fail before their container starts when less than n bytes are free where the
+// job logs are written. 0 disables the check.
+func WithMinFreeDiskBytes(n uint64) Option {
+	return func(p *Poller) {
+		p.minFreeDiskBytes = n
+	}
+}
+
+// WithLogRotation rolls an agent job's log over when it reaches maxSize bytes, keeping maxFiles rolled over files.
+// A maxSize of 0 keeps the whole output of a job in one file.
+func WithLogRotation(maxSize int64, maxFiles int) Option {
+	return func(p *Poller) {
+		p.maxLogSize = maxSize
+		p.maxLogFiles = maxFiles
+	}
+}
+
+// WithAllowedImages restricts the images agents may run to those in allowed, which may list image references or
+// image digests ("sha256:..."). An empty list allows every image.
+func WithAllowedImages(allowed []string) Option {
+	return func(p *Poller) {
+		p.allowedImages = allowed
+	}
+}
+
+// WithDefaultRegistry sets the registry host, with an optional ":port", that agent images without a registry are
+// qualified with before they are pulled. An empty registry uses docker.io.
+func WithDefaultRegistry(registry string) Option {
+	return func(p *Poller) {
+		p.defaultRegistry = registry
+	}
+}
+
+// WithForegroundLogs copies the output of agent containers to w, each line prefixed with the job ID, in addition to
+// the job's log file. It is meant for debugging a runner in a terminal. A nil w disables the copy.
+func WithForegroundLogs(w io.Writer) Option {
+	return func(p *Poller) {
+		p.foregroundLogs = w
+	}
+}
+
+// WithKeyRotationInterval periodically replaces each queue with a new queue that has a new key pair, bounding how
+// long a leaked queue key is useful. Replaced queues drain before being deleted. Values <= 0 disable rotation.
+func WithKeyRotationInterval(interval time.Duration) Option {
+	return func(p *Poller) {
+		p.keyRotationInterval = interval
+	}
+}
+
+// WithMetricsLogInterval sets how often the message metrics are logged. They're also logged when shutdown completes.
+// Values <= 0 disable logging them; they're still available from MessageMetrics.
+func WithMetricsLogInterval(interval time.Duration) Option {
+	return func(p *Poller) {
+		p.metricsLogInterval = interval
+	}
+}
+
+// WithGithubRateLimit limits the GitHub API calls made for each github connection to requestsPerSecond, with bursts of
+// up to burst calls. Each connection is limited independently. A requestsPerSecond <= 0 disables the limit, and a
+// burst < 1 keeps the default burst.
+func WithGithubRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(p *Poller) {
+		p.githubRequestsPerSecond = requestsPerSecond
+		if burst >= 1 {
+			p.githubBurst = burst
+		}
+	}
+}
+
+// WithStateFile sets the file the poller uses to persist state across restarts, such as queues that couldn't be
+// deleted. If unset, no state is persisted.
+func WithStateFile(path string) Option {
+	return func(p *Poller) {
+		p.state = &stateFile{path: path}
+	}
+}
+
+// WithPollBackoff sets the bounds of the backoff between polls of an idle queue. Widening them reduces request volume
+// at the cost of message latency. Invalid bounds (min <= 0 or min > max) are ignored with a warning.
+func WithPollBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(p *Poller) {
+		if minBackoff <= 0 || minBackoff > maxBackoff {
+			slog.Warn("ignoring invalid poll backoff", "min", minBackoff, "max", maxBackoff)
+			return
+		}
+		p.pollBackoffMin = minBackoff
+		p.pollBackoffMax = maxBackoff
+	}
+}
+
+// WithProcessedMessageCacheSize sets how many recently processed message IDs are remembered to detect redelivered
+// messages. Values < 1 are ignored.
+func WithProcessedMessageCacheSize(n int) Option {
+	return func(p *Poller) {
+		if n < 1 {
+			return
+		}
+		p.processedCacheSize = n
+	}
+}
+
+// WithMaxPayloadSize sets the limit, in bytes, on the size of a decrypted message and of the response to it. Messages
+// over the limit are skipped without being parsed, and responses over the limit are not sent. Values <= 0 are ignored.
+func WithMaxPayloadSize(n int) Option {
+	return func(p *Poller) {
+		if n <= 0 {
+			return
+		}
+		p.maxPayloadSize = n
+	}
+}
+
+// WithProcessedMessageCacheTTL sets how long a processed message ID is remembered to detect redelivered messages.
+// Values <= 0 are ignored.
+func WithProcessedMessageCacheTTL(ttl time.Duration) Option {
+	return func(p *Poller) {
+		if ttl <= 0 {
+			return
+		}
+		p.processedCacheTTL = ttl
+	}
+}
+
 func (p *Poller) GetClientForConnectionID(connectionID string) (*github.Client, error) {
 	p.githubClientMu.Lock()
 	defer p.githubClientMu.Unlock()
@@ -703,7 +1381,7 @@ func (p *Poller) GetClientForConnectionID(connectionID string) (*github.Client,
 	if cnn.Token == "" {
 		return nil, fmt.Errorf("missing github token for connection %s", connectionID)
 	}
-	client, err := github.NewClient(cnn.Token, cnn.URL)
+	client, err := github.NewClient(cnn.Token, cnn.URL, github.WithGraphQLURL(cnn.GraphQLURL))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,1018 @@
+package poller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/ecies"
+	"github.com/plan42-ai/log"
+	"github.com/plan42-ai/sdk-go/p42"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTenantID = "tenant-123"
+	testRunnerID = "runner-123"
+)
+
+// fakeServer implements just enough of the runner queue API to drive a Poller end to end.
+// Messages added with enqueue are delivered, encrypted to the queue's public key, on the next GetMessagesBatch call.
+type fakeServer struct {
+	t         *testing.T
+	server    *httptest.Server
+	callerKey *ecdsa.PrivateKey
+
+	mu            sync.Mutex
+	queueKeys     map[string]*ecdsa.PublicKey
+	deletedQueues map[string]bool
+	failDeletes   bool
+	// failRegisters is the number of RegisterRunnerQueue calls that fail before registrations succeed.
+	failRegisters int
+	registerCalls int
+	// blockPolls makes GetMessagesBatch calls hang until the caller gives up, so queues don't record batch stats.
+	blockPolls bool
+	// undecryptable holds the IDs of messages delivered encrypted to the wrong key, so they can't be decrypted.
+	undecryptable map[string]bool
+	// extraQueues are listed by ListRunnerQueues in addition to the queues registered by the poller.
+	extraQueues []string
+	pending     []messages.Message
+	pendingID   []string
+	nextID      int
+	pollTimes   []time.Time
+
+	// writeResponse handles WriteResponse calls. If nil, the call succeeds.
+	writeResponse func(w http.ResponseWriter, messageID string) bool
+	responses     chan string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	callerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	fs := &fakeServer{
+		t:             t,
+		callerKey:     callerKey,
+		queueKeys:     make(map[string]*ecdsa.PublicKey),
+		deletedQueues: make(map[string]bool),
+		responses:     make(chan string, 1000),
+	}
+	fs.server = httptest.NewServer(http.HandlerFunc(fs.handle))
+	t.Cleanup(fs.server.Close)
+	return fs
+}
+
+func (fs *fakeServer) client() *p42.Client {
+	return p42.NewClient(fs.server.URL)
+}
+
+func (fs *fakeServer) enqueue(msg messages.Message) string {
+	fs.mu.Lock()
+	fs.nextID++
+	id := fmt.Sprintf("message-%d", fs.nextID)
+	fs.mu.Unlock()
+	fs.enqueueWithID(id, msg)
+	return id
+}
+
+// enqueueWithID adds a message with a caller-chosen ID, which allows simulating redelivery of the same message.
+func (fs *fakeServer) enqueueWithID(id string, msg messages.Message) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.pending = append(fs.pending, msg)
+	fs.pendingID = append(fs.pendingID, id)
+}
+
+func (fs *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	// /v1/tenants/{tenant}/runners/{runner}/queues/{queue}[/messages[/{message}/response]]
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) == 2 && segments[1] == "runner-queues" {
+		fs.listQueues(w)
+		return
+	}
+	if len(segments) < 7 || segments[5] != "queues" {
+		http.NotFound(w, r)
+		return
+	}
+	queueID := segments[6]
+
+	switch {
+	case len(segments) == 7 && r.Method == http.MethodPut:
+		fs.registerQueue(w, r, queueID)
+	case len(segments) == 7:
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			fs.deleteQueue(w, queueID)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(p42.RunnerQueue{TenantID: testTenantID, RunnerID: testRunnerID, QueueID: queueID, Version: 1})
+	case len(segments) == 8 && segments[7] == "messages":
+		fs.getMessages(w, r, queueID)
+	case len(segments) == 10 && segments[9] == "response":
+		messageID := segments[8]
+		if fs.writeResponse != nil && !fs.writeResponse(w, messageID) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		fs.responses <- messageID
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fs *fakeServer) deleteQueue(w http.ResponseWriter, queueID string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.failDeletes {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"ResponseCode":500,"Message":"injected delete failure","ErrorType":"InternalServerError"}`))
+		return
+	}
+	fs.deletedQueues[queueID] = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (fs *fakeServer) listQueues(w http.ResponseWriter) {
+	fs.mu.Lock()
+	var resp p42.List[*p42.RunnerQueue]
+	queueIDs := slices.Collect(maps.Keys(fs.queueKeys))
+	for _, queueID := range append(queueIDs, fs.extraQueues...) {
+		if !fs.deletedQueues[queueID] {
+			resp.Items = append(resp.Items, &p42.RunnerQueue{TenantID: testTenantID, RunnerID: testRunnerID, QueueID: queueID, Version: 1})
+		}
+	}
+	fs.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (fs *fakeServer) registerQueue(w http.ResponseWriter, r *http.Request, queueID string) {
+	var body struct {
+		PublicKey string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pub, err := ecies.PemToPubKey(body.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fs.mu.Lock()
+	fs.registerCalls++
+	if fs.registerCalls <= fs.failRegisters {
+		fs.mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"ResponseCode":500,"Message":"injected register failure","ErrorType":"InternalServerError"}`))
+		return
+	}
+	fs.queueKeys[queueID] = pub.(*ecdsa.PublicKey)
+	fs.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(p42.RunnerQueue{TenantID: testTenantID, RunnerID: testRunnerID, QueueID: queueID, Version: 1})
+}
+
+func (fs *fakeServer) getMessages(w http.ResponseWriter, r *http.Request, queueID string) {
+	fs.mu.Lock()
+	pending, ids := fs.pending, fs.pendingID
+	fs.pending, fs.pendingID = nil, nil
+	queueKey := fs.queueKeys[queueID]
+	fs.pollTimes = append(fs.pollTimes, time.Now())
+	blockPolls := fs.blockPolls
+	undecryptable := fs.undecryptable
+	fs.mu.Unlock()
+
+	if blockPolls {
+		<-r.Context().Done()
+		return
+	}
+
+	if len(pending) == 0 {
+		// emulate a short long-poll so an idle poller doesn't spin.
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	callerPem, err := ecies.PubKeyToPem(&fs.callerKey.PublicKey)
+	require.NoError(fs.t, err)
+
+	var resp p42.GetMessagesBatchResponse
+	for i, msg := range pending {
+		payload, err := json.Marshal(msg)
+		require.NoError(fs.t, err)
+		key := queueKey
+		if undecryptable[ids[i]] {
+			key = &fs.callerKey.PublicKey
+		}
+		wrapped, err := ecies.Wrap(payload, key)
+		require.NoError(fs.t, err)
+		resp.Messages = append(resp.Messages, &p42.RunnerMessage{
+			TenantID:        testTenantID,
+			RunnerID:        testRunnerID,
+			QueueID:         queueID,
+			MessageID:       ids[i],
+			CallerID:        "caller-123",
+			CallerPublicKey: callerPem,
+			CreatedAt:       time.Now(),
+			Payload:         wrapped,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// waitForQueue blocks until the poller has registered at least one queue.
+func (fs *fakeServer) waitForQueue() {
+	require.Eventually(fs.t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return len(fs.queueKeys) > 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func (fs *fakeServer) waitForResponses(n int) []string {
+	var ids []string
+	timeout := time.After(10 * time.Second)
+	for len(ids) < n {
+		select {
+		case id := <-fs.responses:
+			ids = append(ids, id)
+		case <-timeout:
+			fs.t.Fatalf("timed out waiting for responses: got %d of %d", len(ids), n)
+		}
+	}
+	return ids
+}
+
+func TestMaxConcurrentMessages(t *testing.T) {
+	const (
+		limit     = 3
+		nMessages = 30
+	)
+
+	fs := newFakeServer(t)
+	var inFlight, maxInFlight atomic.Int64
+	fs.writeResponse = func(_ http.ResponseWriter, _ string) bool {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			current := maxInFlight.Load()
+			if n <= current || maxInFlight.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return true
+	}
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithMaxConcurrentMessages(limit))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	for i := 0; i < nMessages; i++ {
+		fs.enqueue(&messages.PingRequest{})
+	}
+
+	ids := fs.waitForResponses(nMessages)
+	require.Len(t, ids, nMessages)
+	require.LessOrEqual(t, maxInFlight.Load(), int64(limit))
+	require.Positive(t, maxInFlight.Load())
+}
+
+func TestSkippedMessagesLogged(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	writing := make(chan struct{}, 3)
+	release := make(chan struct{})
+	defer close(release)
+	fs.writeResponse = func(_ http.ResponseWriter, _ string) bool {
+		writing <- struct{}{}
+		<-release
+		return true
+	}
+	for range 3 {
+		fs.enqueue(&messages.PingRequest{})
+	}
+
+	// with one slot, the rest of the batch waits behind the first message until the queue is stopped.
+	p := New(fs.client(), testTenantID, testRunnerID, WithMaxConcurrentMessages(1))
+	<-writing
+	require.NoError(t, p.Close())
+
+	require.NotNil(t, handler.find("queue stopped before the batch was processed; skipping remaining messages", "skipped", "2"))
+}
+
+func writeAPIError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(p42.Error{ResponseCode: code, Message: http.StatusText(code)})
+}
+
+func TestWriteResponseRetriesTransientFailures(t *testing.T) {
+	fs := newFakeServer(t)
+	var attempts atomic.Int64
+	fs.writeResponse = func(w http.ResponseWriter, _ string) bool {
+		if attempts.Add(1) <= 2 {
+			writeAPIError(w, http.StatusServiceUnavailable)
+			return false
+		}
+		return true
+	}
+
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+	require.Equal(t, int64(3), attempts.Load())
+}
+
+func TestWriteResponseDoesNotRetryPermanentFailures(t *testing.T) {
+	fs := newFakeServer(t)
+	var attempts atomic.Int64
+	fs.writeResponse = func(w http.ResponseWriter, _ string) bool {
+		attempts.Add(1)
+		writeAPIError(w, http.StatusBadRequest)
+		return false
+	}
+
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueue(&messages.PingRequest{})
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int64(1), attempts.Load())
+}
+
+func TestWriteResponseCallerGone(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	var attempts atomic.Int64
+	fs.writeResponse = func(w http.ResponseWriter, _ string) bool {
+		attempts.Add(1)
+		writeAPIError(w, http.StatusGone)
+		return false
+	}
+
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Eventually(t, func() bool {
+		return p.MessageMetrics().Outcomes[messages.PingRequestMessage][OutcomeCallerGone] == 1
+	}, 5*time.Second, time.Millisecond)
+	require.Equal(t, int64(1), attempts.Load())
+
+	handler.logs.mu.Lock()
+	defer handler.logs.mu.Unlock()
+	var found bool
+	for _, r := range handler.logs.records {
+		require.NotEqual(t, "unable to write response", r.Message)
+		if r.Message == "caller no longer waiting for response; dropping it" {
+			found = true
+			require.Equal(t, slog.LevelInfo, r.Level)
+		}
+	}
+	require.True(t, found, "caller gone not logged for %s", id)
+}
+
+func TestDuplicateMessageProcessedOnce(t *testing.T) {
+	fs := newFakeServer(t)
+	var processed atomic.Int64
+	countProcessed := Option(func(p *Poller) {
+		p.process = func(ctx context.Context, msg pollerMessage) messages.Message {
+			processed.Add(1)
+			// give the concurrent duplicate time to arrive while the original is still processing.
+			time.Sleep(20 * time.Millisecond)
+			return msg.Process(ctx)
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, countProcessed)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	// deliver the same message twice in one batch, then redeliver it after it has been processed.
+	fs.enqueueWithID("message-dup", &messages.PingRequest{})
+	fs.enqueueWithID("message-dup", &messages.PingRequest{})
+	require.Equal(t, []string{"message-dup", "message-dup"}, fs.waitForResponses(2))
+
+	fs.enqueueWithID("message-dup", &messages.PingRequest{})
+	require.Equal(t, []string{"message-dup"}, fs.waitForResponses(1))
+
+	require.Equal(t, int64(1), processed.Load())
+}
+
+func TestFailedMessageProcessedOnRedelivery(t *testing.T) {
+	fs := newFakeServer(t)
+	var attempts atomic.Int64
+	failFirst := Option(func(p *Poller) {
+		p.process = func(ctx context.Context, msg pollerMessage) messages.Message {
+			if attempts.Add(1) == 1 {
+				panic("first attempt fails")
+			}
+			return msg.Process(ctx)
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, failFirst)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueueWithID("message-retry", &messages.PingRequest{})
+	require.Eventually(t, func() bool {
+		return p.MessageMetrics().Outcomes[messages.PingRequestMessage][OutcomeFailure] == 1
+	}, 5*time.Second, time.Millisecond)
+
+	// the failed delivery isn't remembered, so the redelivery is processed and answered.
+	fs.enqueueWithID("message-retry", &messages.PingRequest{})
+	require.Equal(t, []string{"message-retry"}, fs.waitForResponses(1))
+	require.Equal(t, int64(2), attempts.Load())
+	require.Zero(t, p.MessageMetrics().Duplicates)
+}
+
+func TestPanickingMessageRecovered(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	panicOnFake := Option(func(p *Poller) {
+		p.process = func(ctx context.Context, msg pollerMessage) messages.Message {
+			if _, ok := msg.(*fakeRequest); ok {
+				panic("bad message")
+			}
+			return msg.Process(ctx)
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, panicOnFake)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueueWithID("message-panic", &fakeRequest{Value: "boom"})
+	require.Eventually(t, func() bool {
+		return handler.find("recovered from panic in message", "panic", "bad message") != nil
+	}, 5*time.Second, time.Millisecond)
+	attrs := handler.find("recovered from panic in message", "panic", "bad message")
+	require.Equal(t, "message-panic", attrs["messageID"])
+	require.Contains(t, attrs, "queueID")
+	require.Contains(t, attrs, "stack")
+
+	// the runner keeps processing messages, and the panicking message is not answered.
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+}
+
+func TestKeyRotationRegistersNewKey(t *testing.T) {
+	fs := newFakeServer(t)
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithKeyRotationInterval(100*time.Millisecond))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	// rotation is checked on the scale ticker, which fires every second.
+	require.Eventually(t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return len(fs.queueKeys) >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	fs.mu.Lock()
+	var keys []*ecdsa.PublicKey
+	for _, key := range fs.queueKeys {
+		keys = append(keys, key)
+	}
+	fs.mu.Unlock()
+	require.False(t, keys[0].Equal(keys[1]), "rotated queue should have a new key")
+
+	// messages are still processed after rotation.
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+}
+
+func TestInitialQueueRetriesKeyGenerationFailure(t *testing.T) {
+	fs := newFakeServer(t)
+	var attempts atomic.Int64
+	failingKeyGen := Option(func(p *Poller) {
+		p.generateKey = func() (*ecdsa.PrivateKey, error) {
+			if attempts.Add(1) <= 3 {
+				return nil, errors.New("injected key generation failure")
+			}
+			return generateQueueKey()
+		}
+	})
+
+	var p *Poller
+	require.NotPanics(t, func() {
+		p = New(fs.client(), testTenantID, testRunnerID, failingKeyGen)
+	})
+	defer func() { _ = p.Close() }()
+
+	fs.waitForQueue()
+	require.Equal(t, int64(4), attempts.Load())
+
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+}
+
+func TestFailedQueueCreationIsRetried(t *testing.T) {
+	fs := newFakeServer(t)
+	// fail every attempt to register the first queue, so its goroutine gives up.
+	fs.failRegisters = maxRetries
+
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.mu.Lock()
+	require.Equal(t, maxRetries+1, fs.registerCalls)
+	require.Len(t, fs.queueKeys, 1)
+	fs.mu.Unlock()
+
+	id := fs.enqueue(&messages.PingRequest{})
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+
+	require.Eventually(t, func() bool {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+		return p.nExpectedQueueCount == 1 && p.nActualQueueCount == 1 && len(p.queues) == 1
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestCreateQueueReturnsLastError(t *testing.T) {
+	fs := newFakeServer(t)
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.mu.Lock()
+	fs.failRegisters = fs.registerCalls + maxRetries
+	fs.mu.Unlock()
+
+	qi := p.createQueueInfo(p.ctx)
+	require.NotNil(t, qi)
+	defer qi.cancel()
+	err := p.createQueue(qi)
+	require.ErrorContains(t, err, "injected register failure")
+}
+
+func TestPollBackoffBounds(t *testing.T) {
+	const (
+		minBackoff = 200 * time.Millisecond
+		maxBackoff = 400 * time.Millisecond
+		window     = 1500 * time.Millisecond
+	)
+
+	fs := newFakeServer(t)
+	p := New(fs.client(), testTenantID, testRunnerID, WithPollBackoff(minBackoff, maxBackoff))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	time.Sleep(window)
+
+	fs.mu.Lock()
+	pollTimes := slices.Clone(fs.pollTimes)
+	fs.mu.Unlock()
+
+	// With the default 1ms-50ms backoff, an idle queue polls dozens of times in this window. The jittered wait
+	// averages half the current backoff, so with these bounds expect well under window / (minBackoff / 2) polls.
+	require.GreaterOrEqual(t, len(pollTimes), 2)
+	require.Less(t, len(pollTimes), int(window/(minBackoff/2)))
+
+	for i := 1; i < len(pollTimes); i++ {
+		gap := pollTimes[i].Sub(pollTimes[i-1])
+		// Allow for the fake server's idle long-poll and scheduling slack.
+		require.Less(t, gap, maxBackoff+200*time.Millisecond, "poll %d waited longer than the max backoff", i)
+	}
+}
+
+func TestWithPollBackoffIgnoresInvalidBounds(t *testing.T) {
+	p := &Poller{pollBackoffMin: defaultPollBackoffMin, pollBackoffMax: defaultPollBackoffMax}
+	WithPollBackoff(0, time.Second)(p)
+	WithPollBackoff(time.Second, time.Millisecond)(p)
+	require.Equal(t, defaultPollBackoffMin, p.pollBackoffMin)
+	require.Equal(t, defaultPollBackoffMax, p.pollBackoffMax)
+
+	WithPollBackoff(time.Second, 2*time.Second)(p)
+	require.Equal(t, time.Second, p.pollBackoffMin)
+	require.Equal(t, 2*time.Second, p.pollBackoffMax)
+}
+
+func readState(t *testing.T, path string) runnerState {
+	t.Helper()
+	state, err := (&stateFile{path: path}).load()
+	require.NoError(t, err)
+	return state
+}
+
+func TestUndeletableQueueRecordedForCleanup(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.failDeletes = true
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithStateFile(statePath))
+	// wait until the queue is being polled, so the poller knows it was created.
+	require.Eventually(t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return len(fs.pollTimes) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, p.Close())
+
+	fs.mu.Lock()
+	queueIDs := slices.Collect(maps.Keys(fs.queueKeys))
+	fs.mu.Unlock()
+	require.Len(t, queueIDs, 1)
+	require.Equal(t, queueIDs, readState(t, statePath).OrphanedQueues)
+}
+
+func TestOrphanedQueuesCleanedUpOnStartup(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.extraQueues = []string{"orphaned-queue"}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	data, err := json.Marshal(runnerState{OrphanedQueues: []string{"orphaned-queue", "already-deleted-queue"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(statePath, data, 0o600))
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithStateFile(statePath))
+	defer func() { _ = p.Close() }()
+
+	require.Eventually(t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		return fs.deletedQueues["orphaned-queue"]
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return len(readState(t, statePath).OrphanedQueues) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOnceProcessesOneBatchAndStops(t *testing.T) {
+	fs := newFakeServer(t)
+	id := fs.enqueue(&messages.PingRequest{})
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithOnce())
+	defer func() { _ = p.Close() }()
+
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the poller to finish")
+	}
+
+	require.Equal(t, []string{id}, fs.waitForResponses(1))
+	fs.mu.Lock()
+	require.Len(t, fs.queueKeys, 1)
+	for queueID := range fs.queueKeys {
+		require.True(t, fs.deletedQueues[queueID])
+	}
+	fs.mu.Unlock()
+	require.NoError(t, p.ShutdownTimeout(5*time.Second))
+}
+
+func TestOnceWaitsForAgents(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.enqueue(&messages.PingRequest{})
+
+	release := make(chan struct{})
+	var agentDone atomic.Bool
+	startAgent := Option(func(p *Poller) {
+		p.process = func(ctx context.Context, msg pollerMessage) messages.Message {
+			p.startAgent(ctx, func(context.Context) {
+				<-release
+				agentDone.Store(true)
+			})
+			return msg.Process(ctx)
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, WithOnce(), startAgent)
+	defer func() { _ = p.Close() }()
+
+	fs.waitForResponses(1)
+	select {
+	case <-p.Done():
+		t.Fatal("poller finished before the agent it started")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-p.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the poller to finish")
+	}
+	require.True(t, agentDone.Load())
+}
+
+// logRecords collects the records handled by a recordingHandler and the handlers derived from it.
+type logRecords struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+// recordingHandler is a slog.Handler that records every log record it handles, along with any attrs added to it.
+type recordingHandler struct {
+	logs  *logRecords
+	attrs []slog.Attr
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{logs: &logRecords{}}
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+	h.logs.mu.Lock()
+	defer h.logs.mu.Unlock()
+	h.logs.records = append(h.logs.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{logs: h.logs, attrs: append(slices.Clone(h.attrs), attrs...)}
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// find returns the attrs of the first record with the given message whose attrs include key=value.
+func (h *recordingHandler) find(msg string, key string, value string) map[string]string {
+	h.logs.mu.Lock()
+	defer h.logs.mu.Unlock()
+	for _, r := range h.logs.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := make(map[string]string)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		if attrs[key] == value {
+			return attrs
+		}
+	}
+	return nil
+}
+
+func TestScaleDecisionLogged(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	require.Eventually(t, func() bool {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+		return p.nActualQueueCount == p.nExpectedQueueCount
+	}, 5*time.Second, time.Millisecond)
+
+	// force a scale up: a full minute of completely full batches since the last scale event.
+	p.mux.Lock()
+	p.measureStart = time.Now().Add(-2 * time.Minute)
+	p.lastScaleEvent = time.Now().Add(-2 * time.Minute)
+	p.sumBatchPct = 1000
+	p.nBatches = 1000
+	p.mux.Unlock()
+	p.doScale()
+
+	attrs := handler.find("scale decision", "decision", scaleDecisionUp)
+	require.NotNil(t, attrs)
+	require.Equal(t, testTenantID, attrs["tenantID"])
+	require.Equal(t, testRunnerID, attrs["runnerID"])
+	require.Equal(t, "1", attrs["queues"])
+	require.Contains(t, attrs, "avgFillRatio")
+	require.Contains(t, attrs, "batches")
+	require.Contains(t, attrs, "sinceLastScale")
+}
+
+func TestReadyAfterQueueRegistered(t *testing.T) {
+	handler := newRecordingHandler()
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewContextHandler(handler)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	fs := newFakeServer(t)
+	clock := newFakeClock()
+	// hold the server's lock, so no queue can be registered until the poller has reported that it isn't ready.
+	fs.mu.Lock()
+	locked := true
+	defer func() {
+		if locked {
+			fs.mu.Unlock()
+		}
+	}()
+	p := New(fs.client(), testTenantID, testRunnerID, WithClock(clock))
+	defer func() { _ = p.Close() }()
+
+	clock.Advance(notReadyWarnInterval)
+	require.Eventually(t, func() bool {
+		return handler.find("runner not yet connected; no queue has been registered with the server", "tenantID", testTenantID) != nil
+	}, 5*time.Second, time.Millisecond)
+	select {
+	case <-p.Ready():
+		t.Fatal("poller ready before a queue was registered")
+	default:
+	}
+
+	fs.mu.Unlock()
+	locked = false
+	select {
+	case <-p.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller not ready after a queue was registered")
+	}
+	require.NotNil(t, handler.find("runner ready", "tenantID", testTenantID))
+}
+
+func TestStartAgentOutlivesQueue(t *testing.T) {
+	fs := newFakeServer(t)
+	p := New(fs.client(), testTenantID, testRunnerID)
+	defer func() { _ = p.Close() }()
+
+	type ctxKey struct{}
+	queueCtx, cancelQueue := context.WithCancel(context.WithValue(t.Context(), ctxKey{}, "message"))
+	started := make(chan context.Context, 1)
+	p.startAgent(queueCtx, func(ctx context.Context) {
+		started <- ctx
+		<-ctx.Done()
+	})
+	agentCtx := <-started
+	require.Equal(t, "message", agentCtx.Value(ctxKey{}))
+
+	// cancelling the queue, e.g. on key rotation, leaves the agent running.
+	cancelQueue()
+	select {
+	case <-agentCtx.Done():
+		t.Fatal("agent cancelled with its queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// closing the poller stops it.
+	require.NoError(t, p.Close())
+	select {
+	case <-agentCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent not cancelled when the poller was closed")
+	}
+}
+
+func TestOversizedPayloadSkipped(t *testing.T) {
+	fs := newFakeServer(t)
+	var processed atomic.Int64
+	countProcessed := Option(func(p *Poller) {
+		p.process = func(ctx context.Context, msg pollerMessage) messages.Message {
+			processed.Add(1)
+			return msg.Process(ctx)
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, countProcessed, WithMaxPayloadSize(8))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueue(&messages.PingRequest{})
+	require.Eventually(t, func() bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		// the message is delivered by one poll, and the next poll shows it has been handled.
+		return len(fs.pollTimes) > 2 && len(fs.pending) == 0
+	}, 5*time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	require.Zero(t, processed.Load())
+	require.Empty(t, fs.responses)
+}
+
+func TestOversizedResponseNotSent(t *testing.T) {
+	request, err := json.Marshal(&messages.PingRequest{})
+	require.NoError(t, err)
+
+	fs := newFakeServer(t)
+	var processed atomic.Int64
+	largeResponse := Option(func(p *Poller) {
+		p.process = func(context.Context, pollerMessage) messages.Message {
+			processed.Add(1)
+			return &ListPullRequestsResponse{
+				Items: []PullRequest{{Title: strings.Repeat("x", len(request))}},
+			}
+		}
+	})
+
+	p := New(fs.client(), testTenantID, testRunnerID, largeResponse, WithMaxPayloadSize(len(request)))
+	defer func() { _ = p.Close() }()
+	fs.waitForQueue()
+
+	fs.enqueue(&messages.PingRequest{})
+	require.Eventually(t, func() bool { return processed.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	require.Empty(t, fs.responses)
+}
+
+func TestAutoscaler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		queues     int64
+		fillRatio  float64
+		elapsed    time.Duration
+		wantQueues int64
+	}{
+		{name: "scale up after one minute", queues: 1, fillRatio: 0.9, elapsed: time.Minute, wantQueues: 2},
+		{name: "no scale up before one minute", queues: 1, fillRatio: 0.9, elapsed: time.Minute - time.Second, wantQueues: 1},
+		{name: "scale up doubles queues", queues: 2, fillRatio: 0.85, elapsed: time.Minute, wantQueues: 4},
+		{name: "scale down after two minutes", queues: 2, fillRatio: 0.2, elapsed: 2 * time.Minute, wantQueues: 1},
+		{name: "no scale down before two minutes", queues: 2, fillRatio: 0.2, elapsed: 2*time.Minute - time.Second, wantQueues: 2},
+		{name: "never scale below one queue", queues: 1, fillRatio: 0, elapsed: 2 * time.Minute, wantQueues: 1},
+		{name: "steady between thresholds", queues: 2, fillRatio: 0.6, elapsed: 2 * time.Minute, wantQueues: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			fs.blockPolls = true
+			clock := newFakeClock()
+
+			p := New(fs.client(), testTenantID, testRunnerID, WithClock(clock))
+			defer func() { _ = p.Close() }()
+			// the test calls doScale itself rather than racing the scale goroutine.
+			p.scaleTicker.Stop()
+
+			waitForQueues := func(n int64) {
+				require.Eventually(t, func() bool {
+					p.mux.Lock()
+					defer p.mux.Unlock()
+					return p.nExpectedQueueCount == n && p.nActualQueueCount == n
+				}, 5*time.Second, time.Millisecond)
+			}
+			waitForQueues(1)
+
+			if tc.queues == 2 {
+				p.addStats(1)
+				clock.Advance(time.Minute)
+				p.doScale()
+				waitForQueues(2)
+			}
+
+			for range 10 {
+				p.addStats(tc.fillRatio)
+			}
+			clock.Advance(tc.elapsed)
+			p.doScale()
+
+			p.mux.Lock()
+			defer p.mux.Unlock()
+			require.Equal(t, tc.wantQueues, p.nExpectedQueueCount)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	ghapi "github.com/google/go-github/v81/github"
+	"github.com/plan42-ai/cli/internal/github"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/sdk-go/p42/messages"
+)
+
+// The pull request messages aren't part of the sdk yet, so they are defined here.
+const (
+	ListPullRequestsRequestMessage  messages.MessageType = "ListPullRequestsRequest"
+	ListPullRequestsResponseMessage messages.MessageType = "ListPullRequestsResponse"
+)
+
+func init() {
+	registerHandler(ListPullRequestsRequestMessage, func() pollerMessage { return &pollerListPullRequestsRequest{} })
+}
+
+type ListPullRequestsRequest struct {
+	TenantID     string
+	ConnectionID string
+	OrgName      string
+	RepoName     string
+	State        *string // "open", "closed", or "all". Defaults to "open".
+	MaxResults   *int
+	Token        *string
+}
+
+func (r *ListPullRequestsRequest) Type() messages.MessageType {
+	return ListPullRequestsRequestMessage
+}
+
+func (r ListPullRequestsRequest) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		TenantID     string
+		ConnectionID string
+		OrgName      string
+		RepoName     string
+		State        *string
+		MaxResults   *int
+		Token        *string
+	}
+
+	tmp.Type = ListPullRequestsRequestMessage
+	tmp.TenantID = r.TenantID
+	tmp.ConnectionID = r.ConnectionID
+	tmp.OrgName = r.OrgName
+	tmp.RepoName = r.RepoName
+	tmp.State = r.State
+	tmp.MaxResults = r.MaxResults
+	tmp.Token = r.Token
+
+	return json.Marshal(tmp)
+}
+
+type PullRequest struct {
+	Number  int
+	Title   string
+	Author  string
+	HeadRef string
+	BaseRef string
+	URL     string
+}
+
+type ListPullRequestsResponse struct {
+	Items        []PullRequest
+	NextToken    *string
+	ErrorMessage *string
+	ErrorCode    *ErrorCode
+}
+
+func (r *ListPullRequestsResponse) Type() messages.MessageType {
+	return ListPullRequestsResponseMessage
+}
+
+func (r ListPullRequestsResponse) MarshalJSON() ([]byte, error) {
+	var tmp struct {
+		Type         messages.MessageType
+		Items        []PullRequest
+		NextToken    *string
+		ErrorMessage *string
+		ErrorCode    *ErrorCode
+	}
+
+	tmp.Type = ListPullRequestsResponseMessage
+	tmp.Items = r.Items
+	tmp.NextToken = r.NextToken
+	tmp.ErrorMessage = r.ErrorMessage
+	tmp.ErrorCode = r.ErrorCode
+
+	return json.Marshal(tmp)
+}
+
+type pollerListPullRequestsRequest struct {
+	ListPullRequestsRequest
+	client  *github.Client
+	limiter *rateLimiter
+	err     error
+}
+
+func (req *pollerListPullRequestsRequest) Init(p *Poller) {
+	req.client, req.err = p.GetClientForConnectionID(req.ConnectionID)
+	req.limiter = p.githubRateLimiter(req.ConnectionID)
+}
+
+type ListPullRequestsPaginationKey struct {
+	Page int
+}
+
+func (req *pollerListPullRequestsRequest) Process(ctx context.Context) messages.Message {
+	slog.InfoContext(
+		ctx,
+		"received ListPullRequestsRequest message",
+		"connection_id",
+		req.ConnectionID,
+		"org_name",
+		req.OrgName,
+		"repo_name",
+		req.RepoName,
+		"pagination_token",
+		req.Token,
+	)
+	if req.err != nil {
+		slog.ErrorContext(ctx, "unable to initialize github client", "error", req.err, "connection_id", req.ConnectionID)
+		msg, code := githubErrorFields(req.err)
+		return &ListPullRequestsResponse{ErrorMessage: msg, ErrorCode: code}
+	}
+	if req.OrgName == "" {
+		slog.ErrorContext(ctx, "missing org name for pull request listing", "connection_id", req.ConnectionID)
+		return &ListPullRequestsResponse{
+			ErrorMessage: util.Pointer("org name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
+	}
+	if req.RepoName == "" {
+		slog.ErrorContext(ctx, "missing repo name for pull request listing", "connection_id", req.ConnectionID)
+		return &ListPullRequestsResponse{
+			ErrorMessage: util.Pointer("repo name is required"),
+			ErrorCode:    util.Pointer(ErrorCodeInvalidRequest),
+		}
+	}
+	items, nextToken, err := paginate(
+		ctx,
+		req.MaxResults,
+		req.Token,
+		ListPullRequestsPaginationKey{Page: 1},
+		func(key ListPullRequestsPaginationKey, limit int) ([]PullRequest, *ListPullRequestsPaginationKey, error) {
+			if err := req.limiter.Wait(ctx); err != nil {
+				return nil, nil, err
+			}
+			pulls, resp, err := req.client.ListPullRequests(
+				ctx,
+				req.OrgName,
+				req.RepoName,
+				&ghapi.PullRequestListOptions{
+					State:       util.Deref(req.State),
+					ListOptions: ghapi.ListOptions{Page: key.Page, PerPage: limit},
+				},
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "github pull request listing failed", "error", err)
+				return nil, nil, err
+			}
+			items := make([]PullRequest, 0, len(pulls))
+			for _, pull := range pulls {
+				items = append(items, PullRequest{
+					Number:  pull.GetNumber(),
+					Title:   pull.GetTitle(),
+					Author:  pull.GetUser().GetLogin(),
+					HeadRef: pull.GetHead().GetRef(),
+					BaseRef: pull.GetBase().GetRef(),
+					URL:     pull.GetHTMLURL(),
+				})
+			}
+			return items, nextPage(resp, func(page int) ListPullRequestsPaginationKey {
+				return ListPullRequestsPaginationKey{Page: page}
+			}), nil
+		},
+	)
+	if err != nil {
+		msg, code := githubErrorFields(err)
+		return &ListPullRequestsResponse{ErrorMessage: msg, ErrorCode: code}
+	}
+	return &ListPullRequestsResponse{Items: items, NextToken: nextToken}
+}
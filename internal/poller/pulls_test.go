@@ -0,0 +1,115 @@
+package poller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/plan42-ai/cli/internal/github"
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConnectionID = "connection-1"
+
+// newGithubTestPoller returns a poller whose test connection talks to server.
+func newGithubTestPoller(server *httptest.Server) *Poller {
+	p := &Poller{githubClients: make(map[string]*github.Client)}
+	WithConnectionIdx(map[string]*config.GithubInfo{
+		testConnectionID: {ConnectionID: testConnectionID, URL: server.URL, Token: "test-token"},
+	})(p)
+	return p
+}
+
+func TestListPullRequestsPagination(t *testing.T) {
+	const nPages = 2
+	var requestedPages []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		assert.NoError(t, err)
+		requestedPages = append(requestedPages, page)
+		if page < nPages {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `[{"number": %d, "title": "pr %d", "user": {"login": "octocat"}}]`, page, page)
+	}))
+	defer server.Close()
+	p := newGithubTestPoller(server)
+
+	var numbers []int
+	var token *string
+	for {
+		req := &pollerListPullRequestsRequest{
+			ListPullRequestsRequest: ListPullRequestsRequest{
+				ConnectionID: testConnectionID,
+				OrgName:      "plan42-ai",
+				RepoName:     "cli",
+				MaxResults:   util.Pointer(1),
+				Token:        token,
+			},
+		}
+		req.Init(p)
+		resp := req.Process(t.Context()).(*ListPullRequestsResponse)
+		require.Nil(t, resp.ErrorMessage)
+		for _, pull := range resp.Items {
+			numbers = append(numbers, pull.Number)
+			require.Equal(t, "octocat", pull.Author)
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		token = resp.NextToken
+	}
+
+	require.Equal(t, []int{1, 2}, numbers)
+	require.Equal(t, []int{1, 2}, requestedPages)
+}
+
+func TestListPullRequestsInvalidToken(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	req := &pollerListPullRequestsRequest{
+		ListPullRequestsRequest: ListPullRequestsRequest{
+			ConnectionID: testConnectionID,
+			OrgName:      "plan42-ai",
+			RepoName:     "cli",
+			Token:        util.Pointer("not-a-token!"),
+		},
+	}
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListPullRequestsResponse)
+	require.Equal(t, errInvalidPaginationToken.Error(), *resp.ErrorMessage)
+	require.Equal(t, ErrorCodeInvalidRequest, *resp.ErrorCode)
+}
+
+func TestListPullRequestsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`))
+	}))
+	defer server.Close()
+
+	req := &pollerListPullRequestsRequest{}
+	req.ConnectionID = testConnectionID
+	req.OrgName = "plan42-ai"
+	req.RepoName = "missing"
+	req.Init(newGithubTestPoller(server))
+	resp := req.Process(t.Context()).(*ListPullRequestsResponse)
+	require.Equal(t, ErrorCodeNotFound, *resp.ErrorCode)
+	require.NotContains(t, *resp.ErrorMessage, server.URL)
+}
+
+func TestListPullRequestsRequiresRepo(t *testing.T) {
+	req := &pollerListPullRequestsRequest{}
+	req.OrgName = "plan42-ai"
+	resp := req.Process(t.Context()).(*ListPullRequestsResponse)
+	require.Equal(t, "repo name is required", *resp.ErrorMessage)
+	require.Equal(t, ErrorCodeInvalidRequest, *resp.ErrorCode)
+}
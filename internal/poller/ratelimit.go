@@ -0,0 +1,86 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGithubRequestsPerSecond and DefaultGithubBurst bound the rate of GitHub API calls made for each github
+	// connection, to stay clear of GitHub's secondary rate limits.
+	DefaultGithubRequestsPerSecond = 10
+	DefaultGithubBurst             = 20
+)
+
+// rateLimiter is a token bucket. It refills one token every interval and holds at most burst tokens. A nil
+// *rateLimiter doesn't limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	// tat is the theoretical arrival time of the next request if requests were evenly spaced. Requests may run
+	// ahead of it by up to burst-1 intervals.
+	tat time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64, burst int) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+		burst:    max(burst, 1),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	allowAt := tat.Add(-time.Duration(l.burst-1) * l.interval)
+	l.tat = tat.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := allowAt.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// githubRateLimiter returns the rate limiter for a github connection. Each connection gets its own bucket, so a busy
+// connection doesn't slow down the others. Only configured connections get a bucket, so requests naming unknown
+// connections can't grow the set of buckets. They fail before calling GitHub anyway.
+func (p *Poller) githubRateLimiter(connectionID string) *rateLimiter {
+	p.githubClientMu.Lock()
+	defer p.githubClientMu.Unlock()
+
+	if p.connectionIdx[connectionID] == nil {
+		return nil
+	}
+	if p.githubLimiters == nil {
+		p.githubLimiters = make(map[string]*rateLimiter)
+	}
+	limiter, ok := p.githubLimiters[connectionID]
+	if !ok {
+		limiter = newRateLimiter(p.githubRequestsPerSecond, p.githubBurst)
+		p.githubLimiters[connectionID] = limiter
+	}
+	return limiter
+}
@@ -0,0 +1,80 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubRateLimiterPerConnection(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	p := &Poller{}
+	WithGithubRateLimit(float64(time.Second/interval), 1)(p)
+	WithConnectionIdx(map[string]*config.GithubInfo{
+		"busy-connection": {ConnectionID: "busy-connection"},
+		"idle-connection": {ConnectionID: "idle-connection"},
+	})(p)
+
+	busy := p.githubRateLimiter("busy-connection")
+	require.NotNil(t, busy)
+	require.Same(t, busy, p.githubRateLimiter("busy-connection"))
+	idle := p.githubRateLimiter("idle-connection")
+	require.NotSame(t, busy, idle)
+
+	const burst = 5
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range burst {
+		wg.Go(func() {
+			assert.NoError(t, busy.Wait(context.Background()))
+		})
+	}
+
+	// while the busy connection is throttled, the idle one proceeds immediately.
+	time.Sleep(interval / 2)
+	idleStart := time.Now()
+	require.NoError(t, idle.Wait(context.Background()))
+	require.Less(t, time.Since(idleStart), interval/2)
+
+	wg.Wait()
+	require.GreaterOrEqual(t, time.Since(start), (burst-1)*interval)
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := newRateLimiter(1, 3)
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, limiter.Wait(context.Background()))
+	}
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestGithubRateLimiterUnknownConnection(t *testing.T) {
+	p := &Poller{}
+	WithGithubRateLimit(1, 1)(p)
+	WithConnectionIdx(map[string]*config.GithubInfo{testConnectionID: {ConnectionID: testConnectionID}})(p)
+
+	for i := range 100 {
+		require.Nil(t, p.githubRateLimiter(fmt.Sprintf("unknown-%d", i)))
+	}
+	require.NotNil(t, p.githubRateLimiter(testConnectionID))
+	require.Len(t, p.githubLimiters, 1)
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	p := &Poller{}
+	WithGithubRateLimit(0, 0)(p)
+	WithConnectionIdx(map[string]*config.GithubInfo{"connection": {ConnectionID: "connection"}})(p)
+	require.Nil(t, p.githubRateLimiter("connection"))
+	require.NoError(t, p.githubRateLimiter("connection").Wait(context.Background()))
+}
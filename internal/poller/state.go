@@ -0,0 +1,106 @@
+package poller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// runnerState is the state the poller persists across restarts.
+type runnerState struct {
+	// OrphanedQueues lists queues that couldn't be deleted before the runner stopped polling them. They are cleaned
+	// up on the next startup.
+	OrphanedQueues []string `json:"orphaned_queues,omitempty"`
+}
+
+// stateFile serializes access to the runner state file. A nil *stateFile, or one with an empty path, discards all
+// updates.
+type stateFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *stateFile) enabled() bool {
+	return s != nil && s.path != ""
+}
+
+func (s *stateFile) load() (runnerState, error) {
+	var state runnerState
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return state, nil
+}
+
+func (s *stateFile) save(state runnerState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// update applies fn to the persisted state and saves the result.
+func (s *stateFile) update(fn func(state *runnerState)) error {
+	if !s.enabled() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+	fn(&state)
+	return s.save(state)
+}
+
+func (s *stateFile) orphanedQueues() ([]string, error) {
+	if !s.enabled() {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.load()
+	return state.OrphanedQueues, err
+}
+
+func (s *stateFile) addOrphanedQueue(queueID string) error {
+	return s.update(func(state *runnerState) {
+		if !slices.Contains(state.OrphanedQueues, queueID) {
+			state.OrphanedQueues = append(state.OrphanedQueues, queueID)
+		}
+	})
+}
+
+func (s *stateFile) removeOrphanedQueues(queueIDs []string) error {
+	return s.update(func(state *runnerState) {
+		state.OrphanedQueues = slices.DeleteFunc(state.OrphanedQueues, func(queueID string) bool {
+			return slices.Contains(queueIDs, queueID)
+		})
+	})
+}
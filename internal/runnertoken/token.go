@@ -0,0 +1,96 @@
+// Package runnertoken parses Plan42 runner tokens: the "p42r_" prefix followed by a JWT whose claims identify the
+// tenant and runner the token belongs to.
+package runnertoken
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/plan42-ai/openid/jwt"
+)
+
+// Prefix is the prefix of every runner token.
+const Prefix = "p42r_"
+
+// clockSkew is how far the local clock may be off from the issuer's before exp and nbf are enforced.
+const clockSkew = time.Minute
+
+var (
+	// ErrInvalid is returned for a token that isn't a well formed runner token.
+	ErrInvalid = errors.New("invalid runner token")
+	// ErrExpired is returned for a token whose exp claim has passed.
+	ErrExpired = errors.New("runner token expired")
+	// ErrNotYetValid is returned for a token whose nbf claim hasn't been reached.
+	ErrNotYetValid = errors.New("runner token not yet valid")
+)
+
+// Claims are the claims of a runner token. Time claims that the token doesn't set are zero.
+type Claims struct {
+	TenantID  string
+	RunnerID  string
+	Issuer    *url.URL
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// Parse parses token and checks that it is currently valid. Its signature isn't verified; that is left to the
+// server.
+func Parse(token string) (*Claims, error) {
+	claims, err := parseClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	err = claims.Validate(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func parseClaims(token string) (*Claims, error) {
+	raw, ok := strings.CutPrefix(token, Prefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s prefix", ErrInvalid, Prefix)
+	}
+	parsed, err := jwt.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, util.RedactError(err))
+	}
+
+	payload := parsed.Payload
+	ret := &Claims{
+		TenantID:  payload.Subject,
+		Issuer:    payload.Issuer,
+		IssuedAt:  claimTime(payload.IssuedAt),
+		NotBefore: claimTime(payload.NotBefore),
+		ExpiresAt: claimTime(payload.Expiration),
+	}
+	if payload.RunnerID != nil {
+		ret.RunnerID = *payload.RunnerID
+	}
+	return ret, nil
+}
+
+// claimTime maps the Unix epoch, which the jwt package returns for a missing time claim, to the zero time.
+func claimTime(t time.Time) time.Time {
+	if t.Unix() == 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+// Validate checks the exp and nbf claims against now, allowing for a small amount of clock skew.
+func (c *Claims) Validate(now time.Time) error {
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(clockSkew)) {
+		return fmt.Errorf("%w at %s", ErrExpired, c.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if !c.NotBefore.IsZero() && now.Add(clockSkew).Before(c.NotBefore) {
+		return fmt.Errorf("%w until %s", ErrNotYetValid, c.NotBefore.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
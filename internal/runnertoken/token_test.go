@@ -0,0 +1,76 @@
+package runnertoken
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{"alg": "ES256", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	enc := base64.RawURLEncoding
+	return Prefix + enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + "." + enc.EncodeToString([]byte("sig"))
+}
+
+func TestParse(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name    string
+		claims  map[string]any
+		wantErr error
+	}{
+		{
+			name: "valid",
+			claims: map[string]any{
+				"sub": "tenant-123", "runner_id": "runner-123", "iss": "https://api.plan42.ai",
+				"iat": now.Add(-time.Hour).Unix(), "nbf": now.Add(-time.Hour).Unix(), "exp": now.Add(time.Hour).Unix(),
+			},
+		},
+		{name: "no time claims", claims: map[string]any{"sub": "tenant-123", "runner_id": "runner-123"}},
+		{name: "expired", claims: map[string]any{"sub": "tenant-123", "exp": now.Add(-time.Hour).Unix()}, wantErr: ErrExpired},
+		{name: "within clock skew of expiry", claims: map[string]any{"sub": "tenant-123", "exp": now.Add(-30 * time.Second).Unix()}},
+		{name: "not yet valid", claims: map[string]any{"sub": "tenant-123", "nbf": now.Add(time.Hour).Unix()}, wantErr: ErrNotYetValid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := Parse(testToken(t, tc.claims))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "tenant-123", claims.TenantID)
+		})
+	}
+}
+
+func TestParseClaims(t *testing.T) {
+	exp := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	claims, err := Parse(testToken(t, map[string]any{
+		"sub":       "tenant-123",
+		"runner_id": "runner-123",
+		"iss":       "https://api.plan42.ai/v1",
+		"exp":       exp.Unix(),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "runner-123", claims.RunnerID)
+	require.Equal(t, "api.plan42.ai", claims.Issuer.Host)
+	require.True(t, exp.Equal(claims.ExpiresAt))
+	require.True(t, claims.NotBefore.IsZero())
+	require.True(t, claims.IssuedAt.IsZero())
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, token := range []string{"", "abc.def.ghi", "p42_abc.def.ghi", Prefix + "not-a-jwt"} {
+		_, err := Parse(token)
+		require.ErrorIs(t, err, ErrInvalid, token)
+	}
+}
@@ -1,7 +1,11 @@
 package dropdown
 
 import (
+	"fmt"
+	"io"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,6 +16,11 @@ import (
 const (
 	collapsed = "▶"
 	expanded  = "▼"
+	moreAbove = "↑"
+	moreBelow = "↓"
+
+	// typeAheadTimeout is how long type-ahead waits for the next key before starting a new prefix.
+	typeAheadTimeout = time.Second
 )
 
 type Item interface {
@@ -33,6 +42,43 @@ type Model struct {
 	selectedIndex int
 	list          list.Model
 	styles        Styles
+	delegate      list.ItemDelegate
+	multiSelect   bool
+	checked       *checkedItems
+	typeAhead     bool
+	typedPrefix   string
+	lastTypedAt   time.Time
+}
+
+// checkedItems tracks which items are checked in multi-select mode. It is shared by pointer between the model and
+// its checkbox delegate, so copies of the model made by Update see the same state.
+type checkedItems struct {
+	checked []bool
+}
+
+func (c *checkedItems) isChecked(index int) bool {
+	return index >= 0 && index < len(c.checked) && c.checked[index]
+}
+
+func (c *checkedItems) toggle(index int) {
+	if index >= 0 && index < len(c.checked) {
+		c.checked[index] = !c.checked[index]
+	}
+}
+
+// checkboxDelegate wraps an item delegate to render a [x] / [ ] prefix in front of each item.
+type checkboxDelegate struct {
+	list.ItemDelegate
+	checked *checkedItems
+}
+
+func (d checkboxDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	box := "[ ] "
+	if d.checked.isChecked(index) {
+		box = "[x] "
+	}
+	_, _ = fmt.Fprint(w, box)
+	d.ItemDelegate.Render(w, m, index, item)
 }
 
 func (m Model) View() string {
@@ -41,9 +87,14 @@ func (m Model) View() string {
 	ret.WriteString(chromeStyle.Render("["))
 	summaryStyle := m.summaryStyle()
 
-	selected := m.SelectedItem()
 	var summaryText string
-	if selected != nil {
+	if m.multiSelect {
+		var summaries []string
+		for _, item := range m.SelectedItems() {
+			summaries = append(summaries, item.Summary())
+		}
+		summaryText = " " + m.chevron() + " " + strings.Join(summaries, ", ")
+	} else if selected := m.SelectedItem(); selected != nil {
 		summaryText = " " + m.chevron() + " " + selected.Summary()
 	}
 	maxSummaryWidth := summaryStyle.GetMaxWidth()
@@ -58,8 +109,18 @@ func (m Model) View() string {
 
 	ret.WriteString(chromeStyle.Render("]"))
 	if m.expanded {
+		// The list pages rather than scrolls, so there are more items above or below the visible window whenever
+		// it isn't on the first or last page.
+		if !m.list.Paginator.OnFirstPage() {
+			ret.WriteString("\n")
+			ret.WriteString(chromeStyle.Render(moreAbove))
+		}
 		ret.WriteString("\n")
 		ret.WriteString(m.list.View())
+		if !m.list.Paginator.OnLastPage() {
+			ret.WriteString("\n")
+			ret.WriteString(chromeStyle.Render(moreBelow))
+		}
 	}
 	return ret.String()
 }
@@ -70,8 +131,19 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.expanded && m.typeAhead && keyMsg.Type == tea.KeyRunes {
+		m.jumpToPrefix(string(keyMsg.Runes))
+		return m, nil
+	}
+
 	switch keyMsg.String() {
-	case "enter", " ":
+	case " ":
+		if m.expanded && m.multiSelect {
+			m.checked.toggle(m.list.GlobalIndex())
+			return m, nil
+		}
+		fallthrough
+	case "enter":
 		if m.expanded {
 			m.selectedIndex = m.list.GlobalIndex()
 			m.Collapse()
@@ -85,7 +157,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		}
 	case "right":
 		if !m.expanded {
+			// Don't forward the key, or the list will treat it as "next page".
 			m.Expand()
+			return m, nil
 		}
 	}
 	var cmd1 tea.Cmd
@@ -97,6 +171,24 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	return m, tea.Batch(cmd1, cmd2)
 }
 
+// jumpToPrefix appends typed to the type-ahead prefix and highlights the first item whose filter value starts with
+// it. The prefix is reset if the previous key was typed more than typeAheadTimeout ago.
+func (m *Model) jumpToPrefix(typed string) {
+	now := time.Now()
+	if now.Sub(m.lastTypedAt) > typeAheadTimeout {
+		m.typedPrefix = ""
+	}
+	m.lastTypedAt = now
+	m.typedPrefix += strings.ToLower(typed)
+
+	for index, item := range m.list.Items() {
+		if strings.HasPrefix(strings.ToLower(item.FilterValue()), m.typedPrefix) {
+			m.list.Select(index)
+			return
+		}
+	}
+}
+
 func (m *Model) Focus() {
 	m.focused = true
 }
@@ -140,9 +232,30 @@ func (m *Model) SelectedItem() Item {
 	return ret
 }
 
+// SelectedItems returns the checked items in multi-select mode, or the selected item otherwise.
+func (m *Model) SelectedItems() []Item {
+	if !m.multiSelect {
+		if selected := m.SelectedItem(); selected != nil {
+			return []Item{selected}
+		}
+		return nil
+	}
+
+	var ret []Item
+	for index, item := range m.list.Items() {
+		if m.checked.isChecked(index) {
+			if i, ok := item.(Item); ok {
+				ret = append(ret, i)
+			}
+		}
+	}
+	return ret
+}
+
 func (m *Model) SetItems(items []Item) tea.Cmd {
 	items2 := narrow(items)
 	m.selectedIndex = 0
+	m.checked.checked = make([]bool, len(items2))
 	return m.list.SetItems(items2)
 }
 
@@ -160,6 +273,8 @@ func narrow(items []Item) []list.Item {
 }
 
 func (m *Model) InsertItem(index int, item Item) tea.Cmd {
+	index = min(max(index, 0), len(m.checked.checked))
+	m.checked.checked = slices.Insert(m.checked.checked, index, false)
 	return m.list.InsertItem(index, item)
 }
 
@@ -167,6 +282,9 @@ func (m *Model) RemoveItem(index int) {
 	if index <= m.selectedIndex {
 		m.selectedIndex = max(m.selectedIndex-1, 0)
 	}
+	if index >= 0 && index < len(m.checked.checked) {
+		m.checked.checked = slices.Delete(m.checked.checked, index, index+1)
+	}
 	m.list.RemoveItem(index)
 }
 
@@ -183,7 +301,36 @@ func (m *Model) SetSyles(styles Styles) {
 }
 
 func (m *Model) SetDelegate(d list.ItemDelegate) {
-	m.list.SetDelegate(d)
+	m.delegate = d
+	m.list.SetDelegate(m.wrapDelegate(d))
+}
+
+// SetMultiSelect enables or disables multi-select mode. In multi-select mode, space toggles the highlighted item,
+// enter confirms, and SelectedItems returns every checked item.
+func (m *Model) SetMultiSelect(v bool) {
+	m.multiSelect = v
+	m.list.SetDelegate(m.wrapDelegate(m.delegate))
+}
+
+func (m Model) MultiSelect() bool {
+	return m.multiSelect
+}
+
+// SetTypeAhead enables or disables type-ahead. When enabled, typing while the dropdown is expanded highlights the
+// first item whose filter value starts with the typed characters. This is lighter than the list's filter UI.
+func (m *Model) SetTypeAhead(v bool) {
+	m.typeAhead = v
+}
+
+func (m Model) TypeAhead() bool {
+	return m.typeAhead
+}
+
+func (m *Model) wrapDelegate(d list.ItemDelegate) list.ItemDelegate {
+	if m.multiSelect {
+		return checkboxDelegate{ItemDelegate: d, checked: m.checked}
+	}
+	return d
 }
 
 func (m *Model) SetShowTitle(v bool) {
@@ -251,5 +398,7 @@ func New(items []Item, delegate list.ItemDelegate, listWidth, listHeight int) Mo
 		focused:       false,
 		selectedIndex: 0,
 		list:          list.New(narrow(items), delegate, listWidth, listHeight),
+		delegate:      delegate,
+		checked:       &checkedItems{checked: make([]bool, len(items))},
 	}
 }
@@ -0,0 +1,165 @@
+package dropdown
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem string
+
+func (i testItem) FilterValue() string {
+	return string(i)
+}
+
+func (i testItem) Summary() string {
+	return string(i)
+}
+
+type testDelegate struct{}
+
+func (testDelegate) Render(w io.Writer, _ list.Model, _ int, item list.Item) {
+	_, _ = fmt.Fprint(w, item.FilterValue())
+}
+
+func (testDelegate) Height() int {
+	return 1
+}
+
+func (testDelegate) Spacing() int {
+	return 0
+}
+
+func (testDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd {
+	return nil
+}
+
+func newTestModel(height int, names ...string) Model {
+	items := make([]Item, len(names))
+	for i, name := range names {
+		items[i] = testItem(name)
+	}
+	m := New(items, testDelegate{}, 40, height)
+	m.SetShowStatusBar(false)
+	m.SetShowFilter(false)
+	m.SetShowTitle(false)
+	m.SetShowPagination(false)
+	m.SetShowHelp(false)
+	return m
+}
+
+func sendKeys(m Model, keys ...tea.KeyMsg) Model {
+	for _, key := range keys {
+		m, _ = m.Update(key)
+	}
+	return m
+}
+
+var (
+	keyDown  = tea.KeyMsg{Type: tea.KeyDown}
+	keyRight = tea.KeyMsg{Type: tea.KeyRight}
+	keyEnter = tea.KeyMsg{Type: tea.KeyEnter}
+	keySpace = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+)
+
+func typed(text string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+}
+
+func TestMultiSelect(t *testing.T) {
+	m := newTestModel(5, "alpha", "beta", "gamma")
+	m.SetMultiSelect(true)
+
+	m = sendKeys(m, keyRight, keySpace, keyDown, keyDown, keySpace)
+	require.True(t, m.IsExpanded())
+	require.Contains(t, m.View(), "[x] alpha")
+	require.Contains(t, m.View(), "[ ] beta")
+	require.Contains(t, m.View(), "[x] gamma")
+
+	m = sendKeys(m, keyEnter)
+	require.False(t, m.IsExpanded())
+	require.Equal(t, []Item{testItem("alpha"), testItem("gamma")}, m.SelectedItems())
+	require.Contains(t, m.View(), "alpha, gamma")
+
+	// Expanding again highlights the last highlighted item, and toggling it again unchecks it.
+	m = sendKeys(m, keyRight, keySpace, keyEnter)
+	require.Equal(t, []Item{testItem("alpha")}, m.SelectedItems())
+}
+
+func TestSingleSelectIsDefault(t *testing.T) {
+	m := newTestModel(5, "alpha", "beta", "gamma")
+	require.False(t, m.MultiSelect())
+
+	m = sendKeys(m, keySpace, keyDown, keySpace)
+	require.False(t, m.IsExpanded())
+	require.Equal(t, []Item{testItem("beta")}, m.SelectedItems())
+	require.NotContains(t, m.View(), "[x]")
+}
+
+func TestTypeAhead(t *testing.T) {
+	m := newTestModel(10, "alpha", "beta", "bravo", "gamma", "Golf")
+	m.SetTypeAhead(true)
+	m = sendKeys(m, keyRight)
+
+	m = sendKeys(m, typed("b"))
+	require.Equal(t, 1, m.HighlightedIndex())
+
+	m = sendKeys(m, typed("r"))
+	require.Equal(t, 2, m.HighlightedIndex())
+
+	// Matching is case-insensitive.
+	m.lastTypedAt = time.Now().Add(-2 * typeAheadTimeout)
+	m = sendKeys(m, typed("go"))
+	require.Equal(t, 4, m.HighlightedIndex())
+
+	// A prefix with no match leaves the highlight where it is.
+	m = sendKeys(m, typed("x"))
+	require.Equal(t, 4, m.HighlightedIndex())
+
+	// The prefix resets after the idle timeout.
+	m.lastTypedAt = time.Now().Add(-2 * typeAheadTimeout)
+	m = sendKeys(m, typed("a"))
+	require.Equal(t, 0, m.HighlightedIndex())
+
+	// Typing only moves the highlight. The selection changes once it is confirmed.
+	m.lastTypedAt = time.Time{}
+	m = sendKeys(m, typed("g"))
+	require.Equal(t, 0, m.SelectedIndex())
+	m = sendKeys(m, keyEnter)
+	require.Equal(t, 3, m.SelectedIndex())
+}
+
+func TestScrollIndicators(t *testing.T) {
+	m := newTestModel(2, "alpha", "beta", "gamma", "delta", "epsilon")
+	m = sendKeys(m, keyRight)
+
+	// First page: only more below.
+	require.NotContains(t, m.View(), moreAbove)
+	require.Contains(t, m.View(), moreBelow)
+
+	// Middle page: more in both directions.
+	m = sendKeys(m, keyDown, keyDown)
+	require.Contains(t, m.View(), "gamma")
+	require.Contains(t, m.View(), moreAbove)
+	require.Contains(t, m.View(), moreBelow)
+
+	// Last page: only more above.
+	m = sendKeys(m, keyDown, keyDown)
+	require.Contains(t, m.View(), "epsilon")
+	require.Contains(t, m.View(), moreAbove)
+	require.NotContains(t, m.View(), moreBelow)
+
+	// No indicators when everything fits or the dropdown is collapsed.
+	m = sendKeys(m, keyEnter)
+	require.NotContains(t, m.View(), moreAbove)
+	require.NotContains(t, m.View(), moreBelow)
+
+	small := sendKeys(newTestModel(5, "alpha", "beta"), keyRight)
+	require.NotContains(t, small.View(), moreAbove)
+	require.NotContains(t, small.View(), moreBelow)
+}
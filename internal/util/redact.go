@@ -0,0 +1,117 @@
+package util
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+)
+
+// tokenPattern matches secrets that may end up in logs or error messages: runner tokens (p42r_), github tokens
+// (ghp_, gho_, ghu_, ghs_, ghr_ and fine-grained github_pat_), and JWTs.
+var tokenPattern = regexp.MustCompile(
+	`p42r_[A-Za-z0-9._-]+|gh[pousr]_[A-Za-z0-9]+|github_pat_[A-Za-z0-9_]+|eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+)
+
+// redactedVisibleChars is the number of trailing characters of a token left visible by RedactToken, so a redacted
+// token can still be told apart from others.
+const redactedVisibleChars = 4
+
+// RedactToken masks every token in s, leaving only its last 4 characters visible. Short tokens are masked entirely.
+// Text that doesn't look like a token is left as is.
+func RedactToken(s string) string {
+	return tokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if len(token) <= 2*redactedVisibleChars {
+			return "****"
+		}
+		return "****" + token[len(token)-redactedVisibleChars:]
+	})
+}
+
+// redactedError is an error whose message has had tokens masked by RedactToken.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns err with any tokens in its message masked by RedactToken. The original error is still
+// available to errors.Is and errors.As. It returns nil if err is nil.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := RedactToken(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{err: err, msg: redacted}
+}
+
+// redactingHandler is a slog.Handler that masks tokens in log messages and attribute values before passing records
+// to the wrapped handler.
+type redactingHandler struct {
+	wrapped slog.Handler
+}
+
+// NewRedactingHandler returns a slog.Handler that masks tokens, as RedactToken does, in the message and attributes of
+// each record before passing it to wrapped.
+func NewRedactingHandler(wrapped slog.Handler) slog.Handler {
+	return &redactingHandler{wrapped: wrapped}
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.wrapped.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, RedactToken(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.wrapped.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		redacted = append(redacted, redactAttr(attr))
+	}
+	return &redactingHandler{wrapped: h.wrapped.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{wrapped: h.wrapped.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactToken(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, member := range group {
+			redacted = append(redacted, redactAttr(member))
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.Any(attr.Key, RedactError(err))
+		}
+		// only replace other values with their string form if they contain a token, to keep their structure.
+		if s := value.String(); RedactToken(s) != s {
+			return slog.String(attr.Key, RedactToken(s))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrForcedShutdown is returned by GracefulShutdown when a second stop signal interrupts draining.
+var ErrForcedShutdown = errors.New("shutdown forced by second stop signal")
+
+// Shutdowner is a service that can be drained gracefully, or stopped immediately by closing it.
+type Shutdowner interface {
+	ShutdownTimeout(timeout time.Duration) error
+	Close() error
+}
+
+// NotifyStopSignals returns a channel that receives SIGTERM and SIGINT. It is buffered so that a second signal sent
+// while the first is being handled isn't dropped.
+func NotifyStopSignals() chan os.Signal {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	return sigCh
+}
+
+// GracefulShutdown drains s, waiting up to timeout. If another signal arrives on sigCh before draining finishes, s is
+// closed immediately and ErrForcedShutdown is returned, so an impatient operator can hit ctrl+c twice to skip the drain.
+func GracefulShutdown(s Shutdowner, sigCh <-chan os.Signal, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ShutdownTimeout(timeout)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-sigCh:
+		_ = s.Close()
+		return ErrForcedShutdown
+	}
+}
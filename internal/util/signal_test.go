@@ -0,0 +1,69 @@
+package util_test
+
+import (
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingShutdowner drains until it is closed.
+type blockingShutdowner struct {
+	closed   chan struct{}
+	nCloses  atomic.Int64
+	timeouts chan time.Duration
+}
+
+func newBlockingShutdowner() *blockingShutdowner {
+	return &blockingShutdowner{
+		closed:   make(chan struct{}),
+		timeouts: make(chan time.Duration, 1),
+	}
+}
+
+func (b *blockingShutdowner) ShutdownTimeout(timeout time.Duration) error {
+	b.timeouts <- timeout
+	<-b.closed
+	return nil
+}
+
+func (b *blockingShutdowner) Close() error {
+	if b.nCloses.Add(1) == 1 {
+		close(b.closed)
+	}
+	return nil
+}
+
+func TestGracefulShutdownDrains(t *testing.T) {
+	t.Parallel()
+	s := newBlockingShutdowner()
+	sigCh := make(chan os.Signal, 1)
+
+	go func() {
+		<-s.timeouts
+		_ = s.Close()
+	}()
+
+	require.NoError(t, util.GracefulShutdown(s, sigCh, time.Minute))
+}
+
+func TestGracefulShutdownForcedBySecondSignal(t *testing.T) {
+	t.Parallel()
+	s := newBlockingShutdowner()
+	sigCh := make(chan os.Signal, 1)
+
+	go func() {
+		<-s.timeouts
+		sigCh <- syscall.SIGINT
+	}()
+
+	start := time.Now()
+	err := util.GracefulShutdown(s, sigCh, time.Hour)
+	require.ErrorIs(t, err, util.ErrForcedShutdown)
+	require.Less(t, time.Since(start), time.Minute)
+	require.Equal(t, int64(1), s.nCloses.Load())
+}
@@ -2,6 +2,7 @@
 package util
 
 import (
+	"errors"
 	"io"
 	"os"
 	"path"
@@ -13,6 +14,47 @@ func Pointer[T any](v T) *T {
 
 type ExitCode int
 
+// Exit codes used by the runner so that supervisors (launchctl, systemd) can tell fatal misconfiguration apart from
+// failures that may succeed on restart.
+const (
+	ExitCodeConfig              ExitCode = 1
+	ExitCodeToken               ExitCode = 2
+	ExitCodeRuntimeNotInstalled ExitCode = 3
+	ExitCodeStartup             ExitCode = 4
+	ExitCodeUnauthorized        ExitCode = 5
+	ExitCodeUnreachable         ExitCode = 6
+)
+
+// ExitError associates an error with the exit code the process should terminate with.
+type ExitError struct {
+	Code ExitCode
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func WithExitCode(code ExitCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCodeOf returns the exit code attached to err via WithExitCode, or fallback if there isn't one.
+func ExitCodeOf(err error, fallback ExitCode) ExitCode {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return fallback
+}
+
 func HandleExit() {
 	if r := recover(); r != nil {
 		if ec, ok := r.(ExitCode); ok {
@@ -35,7 +77,27 @@ func Deref[T any](p *T) T {
 	return *p
 }
 
+// RunnerConfigEnvVar names the environment variable that overrides the default runner config file path.
+const RunnerConfigEnvVar = "PLAN42_RUNNER_CONFIG"
+
+// ResolveRunnerConfigFileName returns the runner config file path to use. An explicit flag value takes precedence
+// over RunnerConfigEnvVar, which takes precedence over the default path.
+func ResolveRunnerConfigFileName(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if envValue := os.Getenv(RunnerConfigEnvVar); envValue != "" {
+		return envValue, nil
+	}
+	return DefaultRunnerConfigFileName()
+}
+
+// DefaultRunnerConfigFileName returns plan42-runner.toml in $XDG_CONFIG_HOME, or in ~/.config if XDG_CONFIG_HOME is
+// unset. As the XDG spec requires, a relative XDG_CONFIG_HOME is ignored.
 func DefaultRunnerConfigFileName() (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); path.IsAbs(configHome) {
+		return path.Join(configHome, "plan42-runner.toml"), nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -0,0 +1,102 @@
+package util_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/plan42-ai/cli/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCodeOf(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		err      error
+		expected util.ExitCode
+	}{
+		{
+			name:     "plain error uses fallback",
+			err:      errors.New("failed to parse config file"),
+			expected: util.ExitCodeConfig,
+		},
+		{
+			name:     "token error",
+			err:      util.WithExitCode(util.ExitCodeToken, errors.New("invalid api token")),
+			expected: util.ExitCodeToken,
+		},
+		{
+			name:     "runtime not installed",
+			err:      util.WithExitCode(util.ExitCodeRuntimeNotInstalled, errors.New("podman is not installed")),
+			expected: util.ExitCodeRuntimeNotInstalled,
+		},
+		{
+			name: "wrapped startup error",
+			err: fmt.Errorf(
+				"failed to start platform services: %w",
+				util.WithExitCode(util.ExitCodeStartup, errors.New("container system start failed")),
+			),
+			expected: util.ExitCodeStartup,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				t.Parallel()
+				require.Equal(t, tc.expected, util.ExitCodeOf(tc.err, util.ExitCodeConfig))
+			},
+		)
+	}
+}
+
+func TestWithExitCodePreservesMessage(t *testing.T) {
+	t.Parallel()
+	inner := errors.New("container system start failed")
+	err := util.WithExitCode(util.ExitCodeStartup, inner)
+	require.Equal(t, inner.Error(), err.Error())
+	require.ErrorIs(t, err, inner)
+	require.NoError(t, util.WithExitCode(util.ExitCodeStartup, nil))
+}
+
+func TestDefaultRunnerConfigFileName(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	resolved, err := util.DefaultRunnerConfigFileName()
+	require.NoError(t, err)
+	require.Equal(t, path.Join(home, ".config", "plan42-runner.toml"), resolved, "unset")
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	resolved, err = util.DefaultRunnerConfigFileName()
+	require.NoError(t, err)
+	require.Equal(t, "/xdg/config/plan42-runner.toml", resolved, "set")
+
+	t.Setenv("XDG_CONFIG_HOME", "relative/config")
+	resolved, err = util.DefaultRunnerConfigFileName()
+	require.NoError(t, err)
+	require.Equal(t, path.Join(home, ".config", "plan42-runner.toml"), resolved, "relative is ignored")
+}
+
+func TestResolveRunnerConfigFileName(t *testing.T) {
+	defaultPath, err := util.DefaultRunnerConfigFileName()
+	require.NoError(t, err)
+
+	t.Setenv(util.RunnerConfigEnvVar, "")
+	resolved, err := util.ResolveRunnerConfigFileName("")
+	require.NoError(t, err)
+	require.Equal(t, defaultPath, resolved, "default")
+
+	t.Setenv(util.RunnerConfigEnvVar, "/env/runner.toml")
+	resolved, err = util.ResolveRunnerConfigFileName("")
+	require.NoError(t, err)
+	require.Equal(t, "/env/runner.toml", resolved, "env overrides default")
+
+	resolved, err = util.ResolveRunnerConfigFileName("/flag/runner.toml")
+	require.NoError(t, err)
+	require.Equal(t, "/flag/runner.toml", resolved, "flag overrides env")
+}
@@ -0,0 +1,29 @@
+// Package version reports the build metadata of the plan42 binaries. The variables are set at build time with
+// -ldflags "-X github.com/plan42-ai/cli/internal/version.Version=...".
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// String returns the version, commit, and build date in the form printed by --version.
+func String() string {
+	return format(Version, Commit, Date)
+}
+
+func format(version string, commit string, date string) string {
+	return fmt.Sprintf("%s (commit %s, built %s)", version, commit, date)
+}
+
+// UserAgent returns the User-Agent sent on requests to the Plan42 API and GitHub, e.g.
+// "plan42-runner/1.0.42 (darwin/arm64)".
+func UserAgent() string {
+	return fmt.Sprintf("plan42-runner/%s (%s/%s)", Version, runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,24 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	require.Equal(t, "dev (commit unknown, built unknown)", String())
+
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	t.Cleanup(func() { Version, Commit, Date = oldVersion, oldCommit, oldDate })
+	Version, Commit, Date = "1.0.42", "abc1234", "2026-01-02T03:04:05Z"
+	require.Equal(t, "1.0.42 (commit abc1234, built 2026-01-02T03:04:05Z)", String())
+}
+
+func TestUserAgent(t *testing.T) {
+	oldVersion := Version
+	t.Cleanup(func() { Version = oldVersion })
+	Version = "1.0.42"
+	require.Equal(t, "plan42-runner/1.0.42 ("+runtime.GOOS+"/"+runtime.GOARCH+")", UserAgent())
+}